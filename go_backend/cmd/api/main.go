@@ -4,12 +4,16 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
 	"github.com/Seyamalam/bun_backend/go_backend/internal/handlers"
 	"github.com/Seyamalam/bun_backend/go_backend/internal/middleware"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,53 +29,184 @@ func main() {
 		nodeEnv = "development"
 	}
 
+	// Token signing secret. Without one, development runs get a random
+	// secret, which signs everyone out on every restart.
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		utils.SetJWTSecret(secret)
+	} else if nodeEnv == "production" {
+		log.Fatal("JWT_SECRET must be set in production")
+	} else {
+		log.Println("⚠️ JWT_SECRET is not set, using a random secret: tokens won't survive a restart")
+	}
+
 	enableRateLimit := os.Getenv("ENABLE_RATE_LIMIT")
 	if enableRateLimit == "" {
 		enableRateLimit = "true"
 	}
 
+	if rate, err := strconv.ParseFloat(os.Getenv("TAX_RATE"), 64); err == nil {
+		handlers.SetTaxRate(rate)
+	}
+
+	if rpID := os.Getenv("WEBAUTHN_RP_ID"); rpID != "" {
+		rpName := os.Getenv("WEBAUTHN_RP_NAME")
+		if rpName == "" {
+			rpName = "E-commerce API"
+		}
+		origins := strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",")
+		if origins[0] == "" {
+			origins = []string{"https://" + rpID}
+		}
+		handlers.SetWebAuthnRelyingParty(rpID, rpName, origins)
+	}
+
+	if currency := os.Getenv("CURRENCY"); currency != "" {
+		if err := models.SetCurrency(currency); err != nil {
+			log.Fatal("Invalid CURRENCY:", err)
+		}
+	}
+
+	if threshold := os.Getenv("FREE_SHIPPING_THRESHOLD"); threshold != "" {
+		amount, err := models.ParseMoney(threshold)
+		if err == nil {
+			err = handlers.SetFreeShippingThreshold(amount)
+		}
+		if err != nil {
+			log.Fatal("Invalid FREE_SHIPPING_THRESHOLD:", err)
+		}
+	}
+
+	if methods := os.Getenv("PAYMENT_METHODS"); methods != "" {
+		if err := handlers.SetPaymentMethods(strings.Split(methods, ",")); err != nil {
+			log.Fatal("Invalid PAYMENT_METHODS:", err)
+		}
+	}
+
+	if pattern := os.Getenv("PRODUCT_SKU_PATTERN"); pattern != "" {
+		if err := utils.SetSKUPattern(pattern); err != nil {
+			log.Fatal("Invalid PRODUCT_SKU_PATTERN:", err)
+		}
+	}
+
+	if mode := os.Getenv("PRODUCT_SLUG_ON_RENAME"); mode != "" {
+		if err := handlers.SetProductSlugOnRename(mode); err != nil {
+			log.Fatal("Invalid PRODUCT_SLUG_ON_RENAME:", err)
+		}
+	}
+
 	// Set Gin mode
 	if nodeEnv == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Slow query log
+	if threshold, err := time.ParseDuration(os.Getenv("SLOW_QUERY_THRESHOLD")); err == nil {
+		database.SetSlowQueryThreshold(threshold)
+	}
+
 	// Initialize database
-	_ = database.GetDB()
+	if _, err := database.InitDB(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
 	log.Println("🗄️ Database: Connected")
+	database.StartHealthMonitor(5 * time.Second)
+
+	// Saved search alerts
+	alertInterval := 15 * time.Minute
+	if interval, err := time.ParseDuration(os.Getenv("SAVED_SEARCH_ALERT_INTERVAL")); err == nil {
+		alertInterval = interval
+	}
+	if alertInterval > 0 {
+		handlers.StartSavedSearchAlerts(alertInterval)
+		log.Printf("🔔 Saved search alerts: every %s\n", alertInterval)
+	}
+
+	// Revoked tokens are kept until they expire
+	handlers.StartRevokedTokenPurge(time.Hour)
+
+	// Concurrent report limit, shared by all report endpoints
+	reportConcurrency := 4
+	if limit := os.Getenv("REPORT_CONCURRENCY"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			log.Fatal("Invalid REPORT_CONCURRENCY:", err)
+		}
+		reportConcurrency = n
+	}
+	reportLimit := middleware.ConcurrencyLimitMiddleware(reportConcurrency)
 
 	// Create router
 	r := gin.New()
 
 	// Add middleware
+	r.Use(middleware.RequestIDMiddleware())
 	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
-
-	// CORS middleware
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
+	r.Use(middleware.RecoveryMiddleware())
+
+	// CORS: public endpoints and admin endpoints each have their own policy
+	corsConfig := middleware.DefaultCORSConfig()
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		corsConfig.AllowedOrigins = middleware.ParseCORSOrigins(origins)
+	}
+	adminCORSConfig := corsConfig
+	if origins := os.Getenv("CORS_ADMIN_ALLOWED_ORIGINS"); origins != "" {
+		adminCORSConfig.AllowedOrigins = middleware.ParseCORSOrigins(origins)
+	}
+	r.Use(middleware.CORSMiddleware(corsConfig, map[string]middleware.CORSConfig{
+		"/api/v1/admin": adminCORSConfig,
+	}))
 
 	// Security headers middleware
-	r.Use(func(c *gin.Context) {
-		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		c.Header("Content-Security-Policy", "default-src 'self'")
-		c.Next()
-	})
+	securityHeaders := middleware.DefaultSecurityHeadersConfig()
+	if csp, ok := os.LookupEnv("SECURITY_CSP"); ok {
+		securityHeaders.ContentSecurityPolicy = csp
+	}
+	if frameOptions, ok := os.LookupEnv("SECURITY_FRAME_OPTIONS"); ok {
+		securityHeaders.FrameOptions = frameOptions
+	}
+	if maxAge, err := strconv.Atoi(os.Getenv("SECURITY_HSTS_MAX_AGE")); err == nil && maxAge >= 0 {
+		securityHeaders.HSTSMaxAge = maxAge
+	}
+	if os.Getenv("SECURITY_HSTS") == "false" {
+		securityHeaders.HSTSEnabled = false
+	}
+	r.Use(middleware.SecurityHeadersMiddleware(securityHeaders))
+
+	// Debug body logging (never enable in production: bodies may contain personal data)
+	if os.Getenv("DEBUG_BODY_LOGGING") == "true" {
+		bodyLog := middleware.DefaultBodyLogConfig()
+		if fields := os.Getenv("DEBUG_BODY_REDACT_FIELDS"); fields != "" {
+			bodyLog.RedactFields = append(bodyLog.RedactFields, strings.Split(fields, ",")...)
+		}
+		if maxBytes, err := strconv.Atoi(os.Getenv("DEBUG_BODY_MAX_BYTES")); err == nil && maxBytes > 0 {
+			bodyLog.MaxBytes = maxBytes
+		}
+		r.Use(middleware.BodyLogMiddleware(bodyLog))
+		log.Println("🐛 Body logging: Enabled")
+	}
+
+	// Fail fast while the database is unreachable
+	r.Use(middleware.DatabaseBreakerMiddleware("/health", "/api/v1/status"))
+
+	// Store resolution (multi-tenant)
+	r.Use(middleware.StoreMiddleware())
 
-	// Rate limiting
+	// Rate limiting (the limiter needs the caller's role, so auth is resolved first)
 	if enableRateLimit == "true" {
+		if multiplier, err := strconv.Atoi(os.Getenv("RATE_LIMIT_ADMIN_MULTIPLIER")); err == nil {
+			middleware.SetAdminRateLimitMultiplier(multiplier)
+		}
+		if os.Getenv("RATE_LIMIT_STORE") == "redis" {
+			store, err := middleware.NewRedisLimiterStore(os.Getenv("REDIS_URL"))
+			if err != nil {
+				log.Fatal("Failed to connect rate limiter to Redis:", err)
+			}
+			middleware.SetLimiterStore(store)
+		}
+		middleware.SetRateLimitMode(os.Getenv("RATE_LIMIT_MODE"))
+		r.Use(middleware.OptionalAuthMiddleware())
 		r.Use(middleware.RateLimitMiddleware(100, 60*time.Second))
-		log.Println("⏱️ Rate limiting: Enabled")
+		log.Printf("⏱️ Rate limiting: Enabled (%s)\n", middleware.RateLimitStats()["mode"])
 	} else {
 		log.Println("⏱️ Rate limiting: Disabled")
 	}
@@ -80,24 +215,85 @@ func main() {
 	r.GET("/health", handlers.HealthCheck)
 	r.GET("/api/v1/status", handlers.APIStatus)
 
+	// API documentation (off by default in production)
+	enableAPIDocs := os.Getenv("ENABLE_API_DOCS")
+	if enableAPIDocs == "" {
+		enableAPIDocs = strconv.FormatBool(nodeEnv != "production")
+	}
+	if enableAPIDocs == "true" {
+		r.GET("/openapi.json", handlers.OpenAPISpec)
+		r.GET("/docs", handlers.SwaggerUI)
+		log.Println("📖 API docs: Enabled at /docs")
+	}
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	{
 		// Auth routes (public)
+		passkeys := middleware.RequireFeature("passkeys")
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", handlers.Register)
 			auth.POST("/login", handlers.Login)
-			auth.POST("/logout", handlers.Logout)
+			auth.POST("/refresh", handlers.RefreshToken)
+			auth.POST("/logout", middleware.OptionalAuthMiddleware(), handlers.Logout)
+			auth.POST("/introspect", handlers.IntrospectToken)
 			auth.GET("/me", middleware.AuthMiddleware(), handlers.GetCurrentUser)
+			auth.GET("/me/stats", middleware.AuthMiddleware(), handlers.GetCurrentUserStats)
+			auth.GET("/me/invoices.zip", middleware.AuthMiddleware(), handlers.DownloadInvoices)
+			auth.GET("/sessions", middleware.AuthMiddleware(), handlers.ListSessions)
+			auth.DELETE("/sessions/:id", middleware.AuthMiddleware(), handlers.RevokeSession)
+			auth.POST("/webauthn/login/begin", passkeys, handlers.BeginWebAuthnLogin)
+			auth.POST("/webauthn/login/finish", passkeys, handlers.FinishWebAuthnLogin)
+			auth.POST("/webauthn/register/begin", middleware.AuthMiddleware(), passkeys, handlers.BeginWebAuthnRegistration)
+			auth.POST("/webauthn/register/finish", middleware.AuthMiddleware(), passkeys, handlers.FinishWebAuthnRegistration)
+			auth.GET("/webauthn/credentials", middleware.AuthMiddleware(), passkeys, handlers.ListWebAuthnCredentials)
+			auth.DELETE("/webauthn/credentials/:id", middleware.AuthMiddleware(), passkeys, handlers.DeleteWebAuthnCredential)
+		}
+
+		// Address routes (protected)
+		addresses := v1.Group("/addresses")
+		addresses.Use(middleware.AuthMiddleware())
+		{
+			addresses.GET("", handlers.ListAddresses)
+			addresses.POST("", handlers.CreateAddress)
+			addresses.POST("/import", handlers.ImportAddresses)
+			addresses.PUT("/:id", handlers.UpdateAddress)
+			addresses.DELETE("/:id", handlers.DeleteAddress)
 		}
 
 		// Product routes (public for reading)
 		products := v1.Group("/products")
 		{
 			products.GET("", handlers.ListProducts)
-			products.GET("/:id", handlers.GetProduct)
+			products.GET("/popular", handlers.ListPopularProducts)
+			products.GET("/changes", handlers.ListProductChanges)
+			products.POST("/compare", handlers.CompareProducts)
+			products.GET("/:id", middleware.OptionalAuthMiddleware(), handlers.GetProduct)
+			products.GET("/slug/:slug", middleware.OptionalAuthMiddleware(), handlers.GetProductBySlug)
 			products.POST("", middleware.AuthMiddleware(), handlers.CreateProduct)
+			products.PUT("/:id", middleware.AuthMiddleware(), handlers.UpdateProduct)
+			products.DELETE("/:id", middleware.AuthMiddleware(), handlers.DeleteProduct)
+			products.POST("/:id/duplicate", middleware.AuthMiddleware(), handlers.DuplicateProduct)
+			products.POST("/:id/variants/transfer", middleware.AuthMiddleware(), middleware.RequireRole("vendor"), handlers.TransferVariantStock)
+			products.POST("/:id/tags", middleware.AuthMiddleware(), handlers.AddProductTags)
+			products.DELETE("/:id/tags/:tag", middleware.AuthMiddleware(), handlers.RemoveProductTag)
+			products.GET("/:id/stock", handlers.GetProductStock)
+			products.POST("/:id/stock", middleware.AuthMiddleware(), handlers.AdjustStock)
+			products.GET("/:id/inventory-history", middleware.AuthMiddleware(), middleware.RequireRole("vendor"), handlers.GetInventoryHistory)
+			products.GET("/:id/effective-price", handlers.GetEffectivePrice)
+			products.GET("/:id/price-history", handlers.GetPriceHistory)
+			products.GET("/:id/review-keywords", handlers.GetReviewKeywords)
+			products.POST("/:id/notify-me", middleware.AuthMiddleware(), handlers.SubscribeBackInStock)
+			products.DELETE("/:id/notify-me", middleware.AuthMiddleware(), handlers.UnsubscribeBackInStock)
+			products.GET("/:id/delivery-estimate", handlers.GetDeliveryEstimate)
+			products.GET("/:id/questions", handlers.ListProductQuestions)
+			products.POST("/:id/questions", middleware.AuthMiddleware(), handlers.AskProductQuestion)
+			products.PUT("/:id/questions/:questionId", middleware.AuthMiddleware(), handlers.ModerateProductQuestion)
+			products.DELETE("/:id/questions/:questionId", middleware.AuthMiddleware(), handlers.DeleteProductQuestion)
+			products.POST("/:id/questions/:questionId/answers", middleware.AuthMiddleware(), handlers.AnswerProductQuestion)
+			products.PUT("/:id/questions/:questionId/answers/:answerId", middleware.AuthMiddleware(), handlers.ModerateProductQuestion)
+			products.DELETE("/:id/questions/:questionId/answers/:answerId", middleware.AuthMiddleware(), handlers.DeleteProductQuestion)
 		}
 
 		// Category routes
@@ -107,24 +303,114 @@ func main() {
 			categories.POST("", middleware.AuthMiddleware(), handlers.CreateCategory)
 		}
 
+		// Cart estimates need no account
+		v1.POST("/cart/estimate", handlers.EstimateCart)
+
 		// Cart routes (protected)
 		cart := v1.Group("/cart")
 		cart.Use(middleware.AuthMiddleware())
 		{
 			cart.GET("", handlers.GetCart)
 			cart.DELETE("", handlers.ClearCart)
+			cart.POST("/validate", handlers.ValidateCart)
+			cart.POST("/share", handlers.ShareCart)
+			cart.POST("/claim", handlers.ClaimCart)
 			cart.POST("/items", handlers.AddToCart)
+			cart.DELETE("/items", handlers.RemoveProductFromCart)
 			cart.DELETE("/items/:itemId", handlers.RemoveFromCart)
 		}
 
+		// Shipping routes (protected)
+		shipping := v1.Group("/shipping")
+		shipping.Use(middleware.AuthMiddleware())
+		{
+			shipping.POST("/quote", handlers.QuoteShipping)
+		}
+
+		// Checkout routes (protected)
+		checkout := v1.Group("/checkout")
+		checkout.Use(middleware.AuthMiddleware())
+		{
+			checkout.POST("/preview", handlers.PreviewCheckout)
+		}
+
+		// Storefront routes (public)
+		v1.GET("/storefront/featured", handlers.ListFeaturedProducts)
+
+		// Payment routes (public)
+		v1.GET("/payment-methods", handlers.ListPaymentMethods)
+
 		// Order routes (protected)
 		orders := v1.Group("/orders")
 		orders.Use(middleware.AuthMiddleware())
 		{
 			orders.GET("", handlers.GetUserOrders)
 			orders.POST("", handlers.CreateOrder)
+			orders.POST("/status", handlers.GetOrderStatuses)
 			orders.GET("/:id", handlers.GetOrder)
 			orders.DELETE("/:id", handlers.CancelOrder)
+			orders.POST("/:id/pay", handlers.PayOrder)
+			orders.POST("/:id/resend-confirmation", handlers.ResendOrderConfirmation)
+		}
+
+		// Saved search routes (protected)
+		savedSearches := v1.Group("/saved-searches")
+		savedSearches.Use(middleware.AuthMiddleware())
+		{
+			savedSearches.GET("", handlers.ListSavedSearches)
+			savedSearches.POST("", handlers.CreateSavedSearch)
+			savedSearches.DELETE("/:id", handlers.DeleteSavedSearch)
+		}
+
+		// Notification routes (protected)
+		notifications := v1.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware())
+		{
+			notifications.GET("", handlers.ListNotifications)
+			notifications.POST("/read", handlers.MarkNotificationsRead)
+			notifications.DELETE("", handlers.ClearReadNotifications)
+			notifications.DELETE("/:id", handlers.DeleteNotification)
+		}
+
+		// Vendor dashboard routes (protected, vendors and admins)
+		vendor := v1.Group("/vendor")
+		vendor.Use(middleware.AuthMiddleware(), middleware.RequireRole("vendor"))
+		{
+			vendor.GET("/questions/unanswered", handlers.UnansweredQuestionCounts)
+			vendor.GET("/analytics", reportLimit, handlers.VendorAnalytics)
+			vendor.GET("/reviews", handlers.ListVendorReviews)
+		}
+
+		// Admin routes (protected, admin only)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(), middleware.RequireRole("admin"))
+		{
+			admin.POST("/users", handlers.CreateUser)
+			admin.GET("/users/inactive", handlers.ListInactiveUsers)
+			admin.POST("/categories/reparent", handlers.ReparentCategories)
+			admin.POST("/coupons/generate", handlers.GenerateCoupons)
+			admin.POST("/emails/preview", handlers.PreviewEmail)
+			admin.POST("/reviews/import", handlers.ImportReviews)
+			admin.POST("/reviews/moderate", handlers.ModerateReviews)
+			admin.POST("/notifications/broadcast", middleware.RequireFeature("notification_broadcast"), handlers.BroadcastNotification)
+			admin.GET("/feature-flags", handlers.ListFeatureFlags)
+			admin.PUT("/feature-flags/:name", handlers.UpdateFeatureFlag)
+			admin.GET("/stores", handlers.ListStores)
+			admin.POST("/stores", handlers.CreateStore)
+			admin.GET("/shipping-methods", handlers.ListShippingMethods)
+			admin.POST("/products/prices", handlers.BulkUpdatePrices)
+			admin.POST("/products/merge", handlers.MergeProducts)
+			admin.DELETE("/products/:id/cart-references", handlers.RemoveProductFromCarts)
+			admin.GET("/products/:id/price-rules", handlers.ListPriceRules)
+			admin.POST("/products/:id/price-rules", handlers.CreatePriceRule)
+			admin.DELETE("/price-rules/:ruleId", handlers.CancelPriceRule)
+			admin.GET("/orders", handlers.ListAllOrders)
+			admin.DELETE("/orders/:id", handlers.DeleteOrder)
+			admin.PUT("/orders/:id/status", handlers.UpdateOrderStatus)
+			admin.POST("/orders/:id/fulfillments", handlers.FulfillOrderItems)
+			admin.POST("/orders/:id/discount", handlers.ApplyOrderDiscount)
+			admin.GET("/reports/categories", reportLimit, handlers.CategorySalesReport)
+			admin.GET("/carts/abandoned", handlers.ListAbandonedCarts)
 		}
 	}
 
@@ -134,7 +420,7 @@ func main() {
 			"success":   false,
 			"error":     "Not found",
 			"code":      "NOT_FOUND",
-			"timestamp": time.Now().Format(time.RFC3339),
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
 		})
 	})
 
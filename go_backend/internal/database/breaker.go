@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed = "closed"
+	BreakerOpen   = "open"
+)
+
+const (
+	// breakerThreshold is how many consecutive failed pings open the breaker
+	breakerThreshold = 2
+	// pingTimeout bounds each health ping so a hung database can't stall the monitor
+	pingTimeout = 2 * time.Second
+	// maxRetryBackoff caps the delay between reconnect attempts while open
+	maxRetryBackoff = 30 * time.Second
+)
+
+// BreakerStatus describes the database circuit breaker
+type BreakerStatus struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	NextRetryAt         *time.Time `json:"next_retry_at,omitempty"`
+}
+
+var breaker = struct {
+	sync.RWMutex
+	status BreakerStatus
+}{status: BreakerStatus{State: BreakerClosed}}
+
+// Available reports whether the circuit breaker lets database work through.
+// While it is open, requests should fail fast instead of waiting on queries.
+func Available() bool {
+	breaker.RLock()
+	defer breaker.RUnlock()
+	return breaker.status.State == BreakerClosed
+}
+
+// Breaker returns a snapshot of the circuit breaker
+func Breaker() BreakerStatus {
+	breaker.RLock()
+	defer breaker.RUnlock()
+	return breaker.status
+}
+
+// StartHealthMonitor pings the database every interval. After
+// breakerThreshold consecutive failures the breaker opens and the database
+// is retried with exponential backoff until a ping succeeds, which closes it.
+func StartHealthMonitor(interval time.Duration) {
+	go func() {
+		backoff := interval
+		for {
+			err := ping()
+
+			breaker.Lock()
+			if err == nil {
+				if breaker.status.State == BreakerOpen {
+					log.Println("Database reachable again, closing circuit breaker")
+				}
+				breaker.status = BreakerStatus{State: BreakerClosed}
+				backoff = interval
+			} else {
+				breaker.status.ConsecutiveFailures++
+				breaker.status.LastError = err.Error()
+				if breaker.status.State == BreakerClosed && breaker.status.ConsecutiveFailures >= breakerThreshold {
+					now := time.Now()
+					breaker.status.State = BreakerOpen
+					breaker.status.OpenedAt = &now
+					log.Println("Database unreachable, opening circuit breaker:", err)
+				}
+				if breaker.status.State == BreakerOpen {
+					backoff *= 2
+					if backoff > maxRetryBackoff {
+						backoff = maxRetryBackoff
+					}
+					next := time.Now().Add(backoff)
+					breaker.status.NextRetryAt = &next
+				}
+			}
+			wait := interval
+			if breaker.status.State == BreakerOpen {
+				wait = backoff
+			}
+			breaker.Unlock()
+
+			time.Sleep(wait)
+		}
+	}()
+}
+
+func ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+
+	conn := GetDB()
+	if conn == nil {
+		return errors.New("database is not initialized")
+	}
+	return conn.PingContext(ctx)
+}
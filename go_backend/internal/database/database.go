@@ -10,46 +10,75 @@ import (
 )
 
 var (
-	db   *sql.DB
-	once sync.Once
+	db *sql.DB
+	mu sync.Mutex
 )
 
-// GetDB returns a singleton database connection
-func GetDB() *sql.DB {
-	once.Do(func() {
-		var err error
-		db, err = sql.Open("sqlite3", "./ecommerce.db?_journal_mode=WAL&_foreign_keys=ON")
-		if err != nil {
-			log.Fatal("Failed to connect to database:", err)
-		}
+// InitDB opens the database connection and brings its schema up to date. It
+// is called once at startup; later calls return the open connection. A
+// failed attempt leaves nothing behind, so it can be retried.
+func InitDB() (*sql.DB, error) {
+	mu.Lock()
+	defer mu.Unlock()
 
-		if err = db.Ping(); err != nil {
-			log.Fatal("Failed to ping database:", err)
-		}
+	if db != nil {
+		return db, nil
+	}
 
-		// Set connection pool settings
-		db.SetMaxOpenConns(25)
-		db.SetMaxIdleConns(5)
+	conn, err := sql.Open(driverName, "./ecommerce.db?_journal_mode=WAL&_foreign_keys=ON")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
 
-		// Initialize schema
-		if err = initSchema(); err != nil {
-			log.Fatal("Failed to initialize schema:", err)
-		}
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Set connection pool settings
+	conn.SetMaxOpenConns(25)
+	conn.SetMaxIdleConns(5)
+
+	// Initialize schema
+	if err = initSchema(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	}
+
+	if err = runMigrations(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	db = conn
+	log.Println("Database connected and initialized")
+	return db, nil
+}
 
-		log.Println("Database connected and initialized")
-	})
+// GetDB returns the connection opened by InitDB, or nil before InitDB has
+// succeeded
+func GetDB() *sql.DB {
+	mu.Lock()
+	defer mu.Unlock()
 	return db
 }
 
 // Close closes the database connection
 func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
 	if db != nil {
-		return db.Close()
+		err := db.Close()
+		db = nil
+		return err
 	}
 	return nil
 }
 
-func initSchema() error {
+// initSchema creates the base tables in one transaction, so a failure part
+// way through leaves the database as it was
+func initSchema(conn *sql.DB) error {
 	schemas := []string{
 		createUserTables(),
 		createProductTables(),
@@ -66,13 +95,19 @@ func initSchema() error {
 		createVerificationTokenTables(),
 	}
 
+	tx, err := conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start schema transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	for _, schema := range schemas {
-		if _, err := db.Exec(schema); err != nil {
+		if _, err := tx.Exec(schema); err != nil {
 			return fmt.Errorf("failed to execute schema: %w", err)
 		}
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 func createUserTables() string {
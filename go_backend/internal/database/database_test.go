@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestInitDBRetriesAfterPartialFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// A leftover table without the columns the schema indexes makes the last
+	// schema statement fail, after every other table was created
+	blocker, err := sql.Open(driverName, "./ecommerce.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blocker.Close()
+	if _, err := blocker.Exec("CREATE TABLE verification_tokens (id TEXT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := InitDB(); err == nil {
+		Close()
+		t.Fatal("expected InitDB to fail")
+	}
+	if GetDB() != nil {
+		t.Fatal("a failed InitDB left a connection behind")
+	}
+	var tables int
+	if err := blocker.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('users', 'products', 'schema_migrations')").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Fatalf("%d tables left behind by the failed schema", tables)
+	}
+
+	if _, err := blocker.Exec("DROP TABLE verification_tokens"); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := InitDB()
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	defer Close()
+
+	var version int
+	if err := conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if want := migrations[len(migrations)-1].version; version != want {
+		t.Fatalf("schema at version %d, want %d", version, want)
+	}
+}
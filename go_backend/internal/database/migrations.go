@@ -0,0 +1,946 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+)
+
+// migration is a versioned schema change applied on top of the base schema
+type migration struct {
+	version    int
+	name       string
+	statements string
+	// run performs changes that cannot be expressed as plain SQL
+	run func(tx *sql.Tx) error
+	// rebuildsTables disables foreign key enforcement while the migration
+	// runs, so tables can be dropped and recreated without cascading deletes
+	rebuildsTables bool
+}
+
+// migrations are applied in order and recorded in schema_migrations so each
+// one only runs once per database
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "add_product_dimensions",
+		statements: `
+ALTER TABLE products ADD COLUMN weight REAL CHECK(weight IS NULL OR weight >= 0);
+ALTER TABLE products ADD COLUMN length REAL CHECK(length IS NULL OR length >= 0);
+ALTER TABLE products ADD COLUMN width REAL CHECK(width IS NULL OR width >= 0);
+ALTER TABLE products ADD COLUMN height REAL CHECK(height IS NULL OR height >= 0);
+ALTER TABLE shipping_methods ADD COLUMN cost_per_kg REAL NOT NULL DEFAULT 0 CHECK(cost_per_kg >= 0);
+`,
+	},
+	{
+		version: 2,
+		name:    "add_split_shipments",
+		statements: `
+ALTER TABLE order_items ADD COLUMN shipping_address_id TEXT REFERENCES addresses(id) ON DELETE RESTRICT;
+
+CREATE TABLE order_shipping_new (
+	id TEXT PRIMARY KEY,
+	order_id TEXT NOT NULL,
+	shipping_address_id TEXT,
+	shipping_method_id TEXT,
+	tracking_number TEXT,
+	status TEXT NOT NULL DEFAULT 'pending',
+	estimated_delivery TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	UNIQUE(order_id, shipping_address_id),
+	FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+	FOREIGN KEY (shipping_address_id) REFERENCES addresses(id) ON DELETE RESTRICT,
+	FOREIGN KEY (shipping_method_id) REFERENCES shipping_methods(id) ON DELETE RESTRICT
+);
+
+INSERT INTO order_shipping_new (id, order_id, shipping_address_id, shipping_method_id, tracking_number, status, estimated_delivery, created_at, updated_at)
+SELECT os.id, os.order_id, o.shipping_address_id, os.shipping_method_id, os.tracking_number, os.status, os.estimated_delivery, os.created_at, os.updated_at
+FROM order_shipping os JOIN orders o ON o.id = os.order_id;
+
+DROP TABLE order_shipping;
+ALTER TABLE order_shipping_new RENAME TO order_shipping;
+
+CREATE INDEX IF NOT EXISTS idx_order_shipping_order_id ON order_shipping(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_shipping_address_id ON order_items(shipping_address_id);
+`,
+	},
+	{
+		version: 3,
+		name:    "sync_product_stock_with_variants",
+		statements: `
+ALTER TABLE inventory_history ADD COLUMN variant_id TEXT REFERENCES product_variants(id) ON DELETE SET NULL;
+
+-- A product with variants holds the sum of its variants' stock
+UPDATE products SET stock_quantity = (
+	SELECT SUM(v.stock_quantity) FROM product_variants v WHERE v.product_id = products.id
+) WHERE EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id);
+
+CREATE TRIGGER IF NOT EXISTS trg_product_variants_stock_insert
+AFTER INSERT ON product_variants
+BEGIN
+	UPDATE products SET stock_quantity = (
+		SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants WHERE product_id = NEW.product_id
+	) WHERE id = NEW.product_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_product_variants_stock_update
+AFTER UPDATE OF stock_quantity, product_id ON product_variants
+BEGIN
+	UPDATE products SET stock_quantity = (
+		SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants WHERE product_id = NEW.product_id
+	) WHERE id = NEW.product_id;
+	UPDATE products SET stock_quantity = (
+		SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants WHERE product_id = OLD.product_id
+	) WHERE id = OLD.product_id AND OLD.product_id != NEW.product_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_product_variants_stock_delete
+AFTER DELETE ON product_variants
+BEGIN
+	UPDATE products SET stock_quantity = (
+		SELECT COALESCE(SUM(stock_quantity), 0) FROM product_variants WHERE product_id = OLD.product_id
+	) WHERE id = OLD.product_id;
+END;
+`,
+	},
+	{
+		version: 4,
+		name:    "create_price_rules",
+		statements: `
+CREATE TABLE IF NOT EXISTS price_rules (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	price REAL NOT NULL CHECK(price >= 0),
+	starts_at TEXT NOT NULL,
+	ends_at TEXT,
+	cancelled_at TEXT,
+	created_at TEXT NOT NULL,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_rules_product_id ON price_rules(product_id, starts_at);
+`,
+	},
+	{
+		version: 5,
+		name:    "add_product_compare_at_price",
+		statements: `
+ALTER TABLE products ADD COLUMN compare_at_price REAL CHECK(compare_at_price IS NULL OR compare_at_price >= 0);
+`,
+	},
+	{
+		version: 6,
+		name:    "add_stores",
+		statements: `
+CREATE TABLE IF NOT EXISTS stores (
+	id TEXT PRIMARY KEY,
+	slug TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+INSERT OR IGNORE INTO stores (id, slug, name, created_at, updated_at)
+VALUES ('default', 'default', 'Default Store', strftime('%Y-%m-%dT%H:%M:%SZ', 'now'), strftime('%Y-%m-%dT%H:%M:%SZ', 'now'));
+
+-- Existing rows belong to the default store
+ALTER TABLE products ADD COLUMN store_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE categories ADD COLUMN store_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_products_store_id ON products(store_id);
+CREATE INDEX IF NOT EXISTS idx_categories_store_id ON categories(store_id);
+`,
+	},
+	{
+		version: 7,
+		name:    "store_money_as_cents",
+		statements: `
+-- Money columns hold integer cents from here on
+UPDATE products SET price = CAST(ROUND(price * 100) AS INTEGER),
+	compare_at_price = CAST(ROUND(compare_at_price * 100) AS INTEGER);
+UPDATE product_variants SET price_modifier = CAST(ROUND(price_modifier * 100) AS INTEGER);
+UPDATE price_rules SET price = CAST(ROUND(price * 100) AS INTEGER);
+UPDATE orders SET total_amount = CAST(ROUND(total_amount * 100) AS INTEGER);
+UPDATE order_items SET unit_price = CAST(ROUND(unit_price * 100) AS INTEGER),
+	total_price = CAST(ROUND(total_price * 100) AS INTEGER);
+UPDATE payments SET amount = CAST(ROUND(amount * 100) AS INTEGER);
+UPDATE coupons SET discount_value = CAST(ROUND(discount_value * 100) AS INTEGER) WHERE discount_type = 'fixed_amount';
+UPDATE coupons SET min_purchase_amount = CAST(ROUND(min_purchase_amount * 100) AS INTEGER);
+UPDATE coupon_usage SET discount_amount = CAST(ROUND(discount_amount * 100) AS INTEGER);
+UPDATE shipping_methods SET base_cost = CAST(ROUND(base_cost * 100) AS INTEGER),
+	cost_per_kg = CAST(ROUND(cost_per_kg * 100) AS INTEGER);
+UPDATE vendor_payouts SET amount = CAST(ROUND(amount * 100) AS INTEGER);
+`,
+	},
+	{
+		version:        8,
+		name:           "default_timestamps",
+		run:            addTimestampDefaults,
+		rebuildsTables: true,
+	},
+	{
+		version: 9,
+		name:    "maintain_updated_at",
+		run:     addUpdatedAtTriggers,
+	},
+	{
+		version: 10,
+		name:    "add_order_soft_delete",
+		statements: `
+ALTER TABLE orders ADD COLUMN deleted_at TEXT;
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders(deleted_at);
+`,
+	},
+	{
+		version: 11,
+		name:    "create_product_questions",
+		statements: `
+CREATE TABLE IF NOT EXISTS product_questions (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	question TEXT NOT NULL,
+	is_hidden BOOLEAN NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS product_answers (
+	id TEXT PRIMARY KEY,
+	question_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	answer TEXT NOT NULL,
+	is_hidden BOOLEAN NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (question_id) REFERENCES product_questions(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_questions_product_id ON product_questions(product_id);
+CREATE INDEX IF NOT EXISTS idx_product_questions_user_id ON product_questions(user_id);
+CREATE INDEX IF NOT EXISTS idx_product_answers_question_id ON product_answers(question_id);
+
+CREATE TRIGGER IF NOT EXISTS trg_product_questions_updated_at
+AFTER UPDATE ON product_questions
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE product_questions SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_product_answers_updated_at
+AFTER UPDATE ON product_answers
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE product_answers SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = NEW.id;
+END;
+`,
+	},
+	{
+		version: 12,
+		name:    "create_tags",
+		statements: `
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	store_id TEXT NOT NULL DEFAULT 'default',
+	name TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	UNIQUE(store_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS product_tags (
+	product_id TEXT NOT NULL,
+	tag_id TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	PRIMARY KEY (product_id, tag_id),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_tags_tag_id ON product_tags(tag_id);
+`,
+	},
+	{
+		version: 13,
+		name:    "create_saved_searches",
+		statements: `
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	store_id TEXT NOT NULL DEFAULT 'default',
+	name TEXT NOT NULL,
+	filters TEXT NOT NULL,
+	last_checked_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON saved_searches(user_id);
+
+CREATE TRIGGER IF NOT EXISTS trg_saved_searches_updated_at
+AFTER UPDATE ON saved_searches
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE saved_searches SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = NEW.id;
+END;
+`,
+	},
+	{
+		version: 14,
+		name:    "add_product_restock_date",
+		statements: `
+ALTER TABLE products ADD COLUMN restock_date TEXT;
+`,
+	},
+	{
+		version: 15,
+		name:    "add_partial_fulfillment",
+		statements: `
+ALTER TABLE order_items ADD COLUMN fulfilled_quantity INTEGER NOT NULL DEFAULT 0 CHECK(fulfilled_quantity >= 0 AND fulfilled_quantity <= quantity);
+UPDATE order_items SET fulfilled_quantity = quantity
+WHERE order_id IN (SELECT id FROM orders WHERE status IN ('shipped', 'delivered', 'returned'));
+`,
+		run:            addPartiallyShippedStatus,
+		rebuildsTables: true,
+	},
+	{
+		version: 16,
+		name:    "add_order_adjustments",
+		statements: `
+ALTER TABLE orders ADD COLUMN adjustment_amount INTEGER NOT NULL DEFAULT 0 CHECK(adjustment_amount >= 0);
+`,
+	},
+	{
+		version: 17,
+		name:    "create_cart_idempotency_keys",
+		statements: `
+CREATE TABLE IF NOT EXISTS cart_idempotency_keys (
+	user_id TEXT NOT NULL,
+	idempotency_key TEXT NOT NULL,
+	product_id TEXT NOT NULL,
+	variant_id TEXT,
+	quantity INTEGER NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	PRIMARY KEY (user_id, idempotency_key),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_idempotency_keys_created_at ON cart_idempotency_keys(created_at);
+`,
+	},
+	{
+		version: 18,
+		name:    "create_webauthn_credentials",
+		statements: `
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	credential_id TEXT NOT NULL UNIQUE,
+	public_key BLOB NOT NULL,
+	name TEXT NOT NULL,
+	sign_count INTEGER NOT NULL DEFAULT 0,
+	last_used_at TEXT,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+CREATE TRIGGER IF NOT EXISTS trg_webauthn_credentials_updated_at
+AFTER UPDATE ON webauthn_credentials
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE webauthn_credentials SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = NEW.id;
+END;
+`,
+	},
+	{
+		version: 19,
+		name:    "create_feature_flags",
+		statements: `
+CREATE TABLE IF NOT EXISTS feature_flags (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT 0,
+	rollout_percentage INTEGER NOT NULL DEFAULT 100 CHECK(rollout_percentage >= 0 AND rollout_percentage <= 100),
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE TRIGGER IF NOT EXISTS trg_feature_flags_updated_at
+AFTER UPDATE ON feature_flags
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE feature_flags SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE name = NEW.name;
+END;
+
+-- Features that were already live when they were put behind a flag start on
+INSERT OR IGNORE INTO feature_flags (name, description, enabled) VALUES
+	('passkeys', 'WebAuthn passkey registration and login', 1),
+	('notification_broadcast', 'Admin notification broadcasts to user segments', 1);
+`,
+	},
+	{
+		version: 20,
+		name:    "add_product_slugs",
+		statements: `
+ALTER TABLE products ADD COLUMN slug TEXT;
+`,
+		run: backfillProductSlugs,
+	},
+	{
+		version: 21,
+		name:    "add_user_last_login",
+		statements: `
+ALTER TABLE users ADD COLUMN last_login_at TEXT;
+CREATE INDEX IF NOT EXISTS idx_users_last_login_at ON users(last_login_at);
+
+-- Logging in isn't a change to the account, so it leaves updated_at alone
+DROP TRIGGER IF EXISTS trg_users_updated_at;
+CREATE TRIGGER trg_users_updated_at
+AFTER UPDATE ON users
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at AND NEW.last_login_at IS OLD.last_login_at
+BEGIN
+	UPDATE users SET updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = NEW.id;
+END;
+`,
+	},
+	{
+		version: 22,
+		name:    "create_payment_idempotency_keys",
+		statements: `
+CREATE TABLE IF NOT EXISTS payment_idempotency_keys (
+	user_id TEXT NOT NULL,
+	idempotency_key TEXT NOT NULL,
+	order_id TEXT NOT NULL,
+	payment_id TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	PRIMARY KEY (user_id, idempotency_key),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE
+);
+`,
+	},
+	{
+		version: 23,
+		name:    "create_product_view_counts",
+		statements: `
+CREATE TABLE IF NOT EXISTS product_view_counts (
+	product_id TEXT PRIMARY KEY,
+	views INTEGER NOT NULL DEFAULT 0,
+	last_viewed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_view_counts_views ON product_view_counts(views);
+`,
+	},
+	{
+		version: 24,
+		name:    "add_review_moderation",
+		statements: `
+ALTER TABLE reviews ADD COLUMN moderated_by TEXT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE reviews ADD COLUMN moderated_at TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_reviews_moderated_at ON reviews(moderated_at);
+`,
+	},
+	{
+		version: 25,
+		name:    "create_sessions",
+		statements: `
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	ip_address TEXT NOT NULL DEFAULT '',
+	issued_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	last_seen_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	expires_at TEXT NOT NULL,
+	revoked_at TEXT,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+`,
+	},
+	{
+		version: 26,
+		name:    "create_price_history",
+		statements: `
+CREATE TABLE IF NOT EXISTS price_history (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	old_price INTEGER NOT NULL,
+	new_price INTEGER NOT NULL,
+	changed_by TEXT,
+	changed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+	FOREIGN KEY (changed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product_id ON price_history(product_id, changed_at);
+`,
+	},
+	{
+		version: 27,
+		name:    "create_stock_subscriptions",
+		statements: `
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	product_id TEXT NOT NULL,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	UNIQUE (user_id, product_id),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_subscriptions_product_id ON stock_subscriptions(product_id);
+`,
+	},
+	{
+		version: 28,
+		name:    "create_refresh_tokens",
+		statements: `
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	session_id TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	revoked_at TEXT,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+`,
+	},
+	{
+		version: 29,
+		name:    "create_revoked_tokens",
+		statements: `
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	revoked_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+`,
+	},
+	{
+		version: 30,
+		name:    "create_cart_shares",
+		statements: `
+CREATE TABLE IF NOT EXISTS cart_shares (
+	token TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	items TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	claimed_by TEXT,
+	claimed_at TEXT,
+	created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (claimed_by) REFERENCES users(id) ON DELETE SET NULL
+);
+`,
+	},
+	{
+		version: 31,
+		name:    "add_product_reserve_stock",
+		statements: `
+ALTER TABLE products ADD COLUMN reserve_stock INTEGER NOT NULL DEFAULT 0 CHECK(reserve_stock >= 0);
+`,
+	},
+	{
+		version: 32,
+		name:    "normalize_skus",
+		run:     normalizeSKUs,
+	},
+	{
+		version:        33,
+		name:           "store_scoped_uniqueness",
+		run:            scopeUniquenessToStores,
+		rebuildsTables: true,
+	},
+}
+
+// updatedAtTables are the tables whose updated_at is maintained by triggers
+var updatedAtTables = []string{
+	"users", "addresses", "stores", "categories", "products", "product_variants",
+	"carts", "cart_items", "orders", "order_shipping", "payments", "payment_methods",
+	"coupons", "reviews", "shipping_methods", "vendors", "vendor_payouts", "notifications",
+}
+
+// addUpdatedAtTriggers bumps updated_at after every update that doesn't set
+// it explicitly, so it stays correct regardless of handler code
+func addUpdatedAtTriggers(tx *sql.Tx) error {
+	for _, table := range updatedAtTables {
+		_, err := tx.Exec(fmt.Sprintf(`
+CREATE TRIGGER IF NOT EXISTS trg_%[1]s_updated_at
+AFTER UPDATE ON %[1]s
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+	UPDATE %[1]s SET updated_at = strftime('%%Y-%%m-%%dT%%H:%%M:%%SZ', 'now') WHERE id = NEW.id;
+END;
+`, table))
+		if err != nil {
+			return fmt.Errorf("creating updated_at trigger on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// timestampColumn matches created_at/updated_at definitions without a default
+var timestampColumn = regexp.MustCompile(`\b(created_at|updated_at) TEXT NOT NULL([,)\s])`)
+
+// timestampDefault is the default for timestamp columns, in the same RFC 3339
+// UTC format the handlers have always written
+const timestampDefault = `DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))`
+
+// addTimestampDefaults gives every created_at/updated_at column a database
+// default. SQLite cannot change a column default in place, so each affected
+// table is rebuilt and its indexes and triggers recreated.
+func addTimestampDefaults(tx *sql.Tx) error {
+	type tableDef struct {
+		name string
+		sql  string
+	}
+
+	rows, err := tx.Query("SELECT name, sql FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'")
+	if err != nil {
+		return err
+	}
+	var tables []tableDef
+	for rows.Next() {
+		var t tableDef
+		if err := rows.Scan(&t.name, &t.sql); err != nil {
+			rows.Close()
+			return err
+		}
+		if timestampColumn.MatchString(t.sql) {
+			tables = append(tables, t)
+		}
+	}
+	rows.Close()
+
+	return withoutTriggers(tx, func() error {
+		for _, t := range tables {
+			definition := timestampColumn.ReplaceAllString(t.sql, "$1 TEXT NOT NULL "+timestampDefault+"$2")
+			if err := rebuildTable(tx, t.name, definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// addPartiallyShippedStatus allows orders to be partially_shipped while only
+// some of their items have been fulfilled
+func addPartiallyShippedStatus(tx *sql.Tx) error {
+	var definition string
+	if err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'orders'").Scan(&definition); err != nil {
+		return err
+	}
+	if !strings.Contains(definition, "'shipped', ") {
+		return fmt.Errorf("unexpected orders status constraint")
+	}
+	definition = strings.Replace(definition, "'shipped', ", "'partially_shipped', 'shipped', ", 1)
+
+	return withoutTriggers(tx, func() error {
+		return rebuildTable(tx, "orders", definition)
+	})
+}
+
+// normalizeSKUs puts existing product and variant SKUs in the form
+// utils.NormalizeSKU gives new ones, so the UNIQUE constraints catch case and
+// whitespace variants. SKUs that would then collide with another one are left
+// as they are, to be renamed by hand.
+func normalizeSKUs(tx *sql.Tx) error {
+	for _, table := range []string{"products", "product_variants"} {
+		type item struct{ id, sku string }
+
+		rows, err := tx.Query("SELECT id, sku FROM " + table)
+		if err != nil {
+			return err
+		}
+		var items []item
+		normalized := map[string]int{}
+		for rows.Next() {
+			var i item
+			if err := rows.Scan(&i.id, &i.sku); err != nil {
+				rows.Close()
+				return err
+			}
+			items = append(items, i)
+			normalized[utils.NormalizeSKU(i.sku)]++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, i := range items {
+			sku := utils.NormalizeSKU(i.sku)
+			if sku == i.sku || normalized[sku] > 1 {
+				continue
+			}
+			if _, err := tx.Exec("UPDATE "+table+" SET sku = ? WHERE id = ?", sku, i.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// storeScopedColumns are the columns that were unique across all stores and
+// are now only unique within a store, by table
+var storeScopedColumns = map[string]string{
+	"categories": "name",
+	"products":   "sku",
+}
+
+// scopeUniquenessToStores replaces the UNIQUE constraints on category names
+// and product SKUs with UNIQUE(store_id, ...), so stores don't collide with
+// or learn about each other's catalogues
+func scopeUniquenessToStores(tx *sql.Tx) error {
+	return withoutTriggers(tx, func() error {
+		for table, column := range storeScopedColumns {
+			var definition string
+			if err := tx.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&definition); err != nil {
+				return err
+			}
+			unique := column + " TEXT NOT NULL UNIQUE,"
+			end := strings.LastIndex(definition, ")")
+			if !strings.Contains(definition, unique) || end < 0 {
+				return fmt.Errorf("unexpected %s.%s constraint", table, column)
+			}
+			definition = strings.TrimRight(definition[:end], " \t\n") + ",\n\tUNIQUE(store_id, " + column + ")\n)"
+			definition = strings.Replace(definition, unique, column+" TEXT NOT NULL,", 1)
+			if err := rebuildTable(tx, table, definition); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillProductSlugs gives every existing product a slug derived from its
+// name, unique within its store. Older products claim a name's plain slug
+// first. updated_at is left alone, as the products themselves don't change.
+func backfillProductSlugs(tx *sql.Tx) error {
+	type product struct{ id, storeID, name string }
+
+	rows, err := tx.Query("SELECT id, store_id, name FROM products ORDER BY created_at, id")
+	if err != nil {
+		return err
+	}
+	var products []product
+	for rows.Next() {
+		var p product
+		if err := rows.Scan(&p.id, &p.storeID, &p.name); err != nil {
+			rows.Close()
+			return err
+		}
+		products = append(products, p)
+	}
+	rows.Close()
+
+	err = withoutTriggers(tx, func() error {
+		taken := map[string]map[string]bool{}
+		for _, p := range products {
+			if taken[p.storeID] == nil {
+				taken[p.storeID] = map[string]bool{}
+			}
+			slug := utils.NextSlug(utils.Slugify(p.name), taken[p.storeID])
+			taken[p.storeID][slug] = true
+			if _, err := tx.Exec("UPDATE products SET slug = ? WHERE id = ?", slug, p.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_products_store_slug ON products(store_id, slug)")
+	return err
+}
+
+// withoutTriggers runs fn with every trigger dropped, recreating them
+// afterwards. Triggers may reference any table, so they must not exist while
+// tables are being rebuilt.
+func withoutTriggers(tx *sql.Tx, fn func() error) error {
+	triggers, err := schemaObjects(tx, "SELECT name, sql FROM sqlite_master WHERE type = 'trigger'")
+	if err != nil {
+		return err
+	}
+	for name := range triggers {
+		if _, err := tx.Exec(`DROP TRIGGER "` + name + `"`); err != nil {
+			return err
+		}
+	}
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	for _, trigger := range triggers {
+		if _, err := tx.Exec(trigger); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebuildTable recreates a table from a new CREATE TABLE statement, copying
+// its rows and recreating its indexes. SQLite cannot alter constraints or
+// defaults in place, so this is how such changes are made.
+func rebuildTable(tx *sql.Tx, name, definition string) error {
+	indexes, err := schemaObjects(tx, "SELECT name, sql FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND tbl_name = ?", name)
+	if err != nil {
+		return err
+	}
+
+	tmp := name + "__rebuild"
+	definition = `CREATE TABLE "` + tmp + `" ` + definition[strings.Index(definition, "("):]
+
+	statements := []string{
+		definition,
+		`INSERT INTO "` + tmp + `" SELECT * FROM "` + name + `"`,
+		`DROP TABLE "` + name + `"`,
+		`ALTER TABLE "` + tmp + `" RENAME TO "` + name + `"`,
+	}
+	for _, index := range indexes {
+		statements = append(statements, index)
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("rebuilding %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// schemaObjects returns the CREATE statements of sqlite_master rows by name
+func schemaObjects(tx *sql.Tx, query string, args ...interface{}) (map[string]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := map[string]string{}
+	for rows.Next() {
+		var name, stmt string
+		if err := rows.Scan(&name, &stmt); err != nil {
+			return nil, err
+		}
+		objects[name] = stmt
+	}
+	return objects, rows.Err()
+}
+
+func runMigrations(db *sql.DB) error {
+	// Migrations run on a single connection so per-connection pragmas apply
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migrations: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(context.Background(), `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+);
+`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		var applied int
+		if err := conn.QueryRowContext(context.Background(), "SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.version).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check migration %d: %w", m.version, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		if err := applyMigration(conn, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyMigration(conn *sql.Conn, m migration) error {
+	ctx := context.Background()
+
+	// foreign_keys cannot be changed inside a transaction
+	if m.rebuildsTables {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return fmt.Errorf("failed to disable foreign keys for migration %d: %w", m.version, err)
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if m.statements != "" {
+		if _, err := tx.Exec(m.statements); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	if m.run != nil {
+		if err := m.run(tx); err != nil {
+			return fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+		}
+	}
+
+	if m.rebuildsTables {
+		var violations int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM pragma_foreign_key_check").Scan(&violations); err != nil {
+			return fmt.Errorf("failed to check foreign keys for migration %d: %w", m.version, err)
+		}
+		if violations > 0 {
+			return fmt.Errorf("migration %d (%s) left %d foreign key violations", m.version, m.name, violations)
+		}
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		m.version, m.name, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a database with the full schema in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open(driverName, filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=ON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := initSchema(conn); err != nil {
+		t.Fatal(err)
+	}
+	if err := runMigrations(conn); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestNormalizeSKUsMigration(t *testing.T) {
+	conn := openTestDB(t)
+
+	exec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := conn.Exec(query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	exec("INSERT INTO categories (id, name) VALUES ('c1', 'Category')")
+	for id, sku := range map[string]string{"p1": "abc-1", "p2": " xyz-2 ", "p3": "dup", "p4": "DUP ", "p5": "ab  1"} {
+		exec("INSERT INTO products (id, name, slug, description, price, category_id, sku) VALUES (?, 'Product', ?, 'A product', 100, 'c1', ?)",
+			id, id, sku)
+	}
+	exec("INSERT INTO product_variants (id, product_id, name, value, sku) VALUES ('v1', 'p1', 'Size', 'S', ' abc-1-s')")
+	exec("INSERT INTO product_variants (id, product_id, name, value, sku) VALUES ('v2', 'p5', 'Size', 'M', 'ab \t 1-m')")
+
+	// Apply the migration again to the rows written before it
+	exec("DELETE FROM schema_migrations WHERE version = 32")
+	if err := runMigrations(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"p1": "ABC-1", "p2": "XYZ-2", "p3": "dup", "p4": "DUP ", "p5": "AB 1", "v1": "ABC-1-S", "v2": "AB 1-M"}
+	for id, sku := range want {
+		var got string
+		if err := conn.QueryRow("SELECT sku FROM products WHERE id = ? UNION ALL SELECT sku FROM product_variants WHERE id = ?", id, id).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", id, err)
+		}
+		if got != sku {
+			t.Errorf("%s: sku = %q, want %q", id, got, sku)
+		}
+	}
+}
+
+func TestUpdatedAtTriggers(t *testing.T) {
+	conn := openTestDB(t)
+
+	stale := "2020-01-01T00:00:00Z"
+	if _, err := conn.Exec("INSERT INTO categories (id, name, created_at, updated_at) VALUES ('c1', 'Category', ?, ?)", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	updatedAt := func() string {
+		t.Helper()
+		var value string
+		if err := conn.QueryRow("SELECT updated_at FROM categories WHERE id = 'c1'").Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	// An update that doesn't set updated_at bumps it
+	if _, err := conn.Exec("UPDATE categories SET name = 'Renamed' WHERE id = 'c1'"); err != nil {
+		t.Fatal(err)
+	}
+	bumped, err := time.Parse(time.RFC3339, updatedAt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(bumped) > time.Minute {
+		t.Fatalf("updated_at = %s, want about now", bumped)
+	}
+
+	// An explicit updated_at is kept
+	if _, err := conn.Exec("UPDATE categories SET name = 'Again', updated_at = ? WHERE id = 'c1'", stale); err != nil {
+		t.Fatal(err)
+	}
+	if got := updatedAt(); got != stale {
+		t.Fatalf("updated_at = %s, want the explicit %s", got, stale)
+	}
+}
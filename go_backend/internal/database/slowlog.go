@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// driverName is the SQLite driver wrapped with query timing
+const driverName = "sqlite3_timed"
+
+// maxSlowQueries is how many distinct slow statements are kept for reporting
+const maxSlowQueries = 20
+
+// slowQueryThreshold is the duration, in nanoseconds, from which a query is
+// logged as slow; 0 disables the slow log
+var slowQueryThreshold atomic.Int64
+
+func init() {
+	sql.Register(driverName, &timedDriver{&sqlite3.SQLiteDriver{}})
+	slowQueryThreshold.Store(int64(200 * time.Millisecond))
+}
+
+// SetSlowQueryThreshold sets the duration from which queries are logged and
+// reported as slow. 0 disables the slow log.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	if threshold < 0 {
+		threshold = 0
+	}
+	slowQueryThreshold.Store(int64(threshold))
+}
+
+// SlowQuery aggregates the slow executions of one SQL statement
+type SlowQuery struct {
+	SQL         string    `json:"sql"`
+	Count       int       `json:"count"`
+	MaxMillis   float64   `json:"max_ms"`
+	TotalMillis float64   `json:"total_ms"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+var slowQueries = struct {
+	sync.Mutex
+	byStatement map[string]*SlowQuery
+}{byStatement: map[string]*SlowQuery{}}
+
+// SlowQueries returns the slowest statements seen since startup, slowest first
+func SlowQueries() []SlowQuery {
+	slowQueries.Lock()
+	defer slowQueries.Unlock()
+
+	queries := make([]SlowQuery, 0, len(slowQueries.byStatement))
+	for _, q := range slowQueries.byStatement {
+		queries = append(queries, *q)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].MaxMillis > queries[j].MaxMillis })
+	return queries
+}
+
+// observeQuery logs and records a query that took at least the threshold.
+// The fast path is a single comparison.
+func observeQuery(query string, elapsed time.Duration) {
+	threshold := time.Duration(slowQueryThreshold.Load())
+	if threshold == 0 || elapsed < threshold {
+		return
+	}
+
+	statement := strings.Join(strings.Fields(query), " ")
+	log.Printf("slow query (%s): %s", elapsed, statement)
+
+	slowQueries.Lock()
+	defer slowQueries.Unlock()
+
+	q, ok := slowQueries.byStatement[statement]
+	if !ok {
+		if len(slowQueries.byStatement) >= maxSlowQueries && !evictFastestSlowQuery(elapsed) {
+			return
+		}
+		q = &SlowQuery{SQL: statement}
+		slowQueries.byStatement[statement] = q
+	}
+
+	millis := float64(elapsed) / float64(time.Millisecond)
+	q.Count++
+	q.TotalMillis += millis
+	if millis > q.MaxMillis {
+		q.MaxMillis = millis
+	}
+	q.LastSeenAt = time.Now().UTC()
+}
+
+// evictFastestSlowQuery makes room for a query taking elapsed by dropping the
+// recorded statement with the lowest maximum, if that is lower. Callers hold
+// the slowQueries lock.
+func evictFastestSlowQuery(elapsed time.Duration) bool {
+	var fastest *SlowQuery
+	for _, q := range slowQueries.byStatement {
+		if fastest == nil || q.MaxMillis < fastest.MaxMillis {
+			fastest = q
+		}
+	}
+	if fastest == nil || fastest.MaxMillis >= float64(elapsed)/float64(time.Millisecond) {
+		return false
+	}
+	delete(slowQueries.byStatement, fastest.SQL)
+	return true
+}
+
+// timedDriver opens SQLite connections that time every query and exec
+type timedDriver struct {
+	*sqlite3.SQLiteDriver
+}
+
+func (d *timedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// timedConn is a SQLite connection whose queries and execs are timed
+type timedConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	result, err := c.SQLiteConn.ExecContext(ctx, query, args)
+	observeQuery(query, time.Since(start))
+	return result, err
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := c.SQLiteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		observeQuery(query, time.Since(start))
+		return nil, err
+	}
+	return &timedRows{Rows: rows, query: query, elapsed: time.Since(start)}, nil
+}
+
+// timedRows adds the time spent stepping through results to the query's
+// duration, excluding the time the caller spends between rows
+type timedRows struct {
+	driver.Rows
+	query   string
+	elapsed time.Duration
+}
+
+func (r *timedRows) Next(dest []driver.Value) error {
+	start := time.Now()
+	err := r.Rows.Next(dest)
+	r.elapsed += time.Since(start)
+	return err
+}
+
+func (r *timedRows) Close() error {
+	observeQuery(r.query, r.elapsed)
+	return r.Rows.Close()
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Timestamps are stored as RFC 3339 text in UTC, e.g. "2024-05-01T12:00:00Z".
+// Columns are declared TEXT, so the driver hands them back as strings; the
+// scanners below parse them into time.Time. Older rows written by SQLite
+// itself ("2024-05-01 12:00:00") or without an offset are read as UTC too.
+var timestampFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// UTCTime returns a Scanner that reads a stored timestamp into t, in UTC
+func UTCTime(t *time.Time) sql.Scanner {
+	return utcTime{t}
+}
+
+// NullUTCTime returns a Scanner that reads a nullable stored timestamp into
+// t, in UTC. NULL leaves t nil.
+func NullUTCTime(t **time.Time) sql.Scanner {
+	return nullUTCTime{t}
+}
+
+type utcTime struct{ t *time.Time }
+
+func (s utcTime) Scan(value interface{}) error {
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	*s.t = t
+	return nil
+}
+
+type nullUTCTime struct{ t **time.Time }
+
+func (s nullUTCTime) Scan(value interface{}) error {
+	if value == nil {
+		*s.t = nil
+		return nil
+	}
+	t, err := parseTimestamp(value)
+	if err != nil {
+		return err
+	}
+	*s.t = &t
+	return nil
+}
+
+func parseTimestamp(value interface{}) (time.Time, error) {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return time.Time{}, fmt.Errorf("cannot read %T as a timestamp", value)
+	}
+
+	for _, format := range timestampFormats {
+		if t, err := time.ParseInLocation(format, text, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUTCTimeScansStoredFormats(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, value := range []interface{}{
+		"2024-05-01T12:00:00Z",
+		"2024-05-01T14:00:00+02:00",
+		"2024-05-01 12:00:00",
+		"2024-05-01T12:00:00",
+		[]byte("2024-05-01T12:00:00Z"),
+		want.In(time.FixedZone("UTC-7", -7*3600)),
+	} {
+		var got time.Time
+		if err := UTCTime(&got).Scan(value); err != nil {
+			t.Errorf("%v: %v", value, err)
+			continue
+		}
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("%v: got %v, want %v", value, got, want)
+		}
+	}
+
+	var got time.Time
+	if err := UTCTime(&got).Scan("yesterday"); err == nil {
+		t.Error("expected an error for an invalid timestamp")
+	}
+}
+
+func TestNullUTCTimeScansNull(t *testing.T) {
+	got := &time.Time{}
+	if err := NullUTCTime(&got).Scan(nil); err != nil || got != nil {
+		t.Fatalf("got %v, %v; want nil", got, err)
+	}
+	if err := NullUTCTime(&got).Scan("2024-05-01T14:00:00+02:00"); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.Location() != time.UTC || got.Hour() != 12 {
+		t.Fatalf("got %v, want 12:00 UTC", got)
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"time"
+)
+
+// TokenRevoked reports whether an access token was revoked, either on its
+// own at logout (by its jti) or along with its session. Tokens without a jti,
+// or without a recorded session, have nothing to revoke. Every check of an
+// access token goes through it, so a revoked token is rejected everywhere.
+// touchSession keeps the session's last_seen_at roughly current, for checks
+// made because the token is being used rather than inspected.
+func TokenRevoked(tokenID, sessionID string, touchSession bool) (bool, error) {
+	if tokenID != "" {
+		var exists int
+		err := GetDB().QueryRow("SELECT 1 FROM revoked_tokens WHERE jti = ?", tokenID).Scan(&exists)
+		if err == nil {
+			return true, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+	}
+	return sessionRevoked(sessionID, touchSession)
+}
+
+// sessionTouchInterval is how out of date a session's last_seen_at may get
+// before a check of one of its tokens updates it
+const sessionTouchInterval = time.Minute
+
+// sessionRevoked reports whether a token's session was revoked, and with
+// touch keeps the session's last_seen_at roughly current
+func sessionRevoked(sessionID string, touch bool) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	db := GetDB()
+	var revokedAt sql.NullString
+	var lastSeenAt string
+	err := db.QueryRow("SELECT revoked_at, last_seen_at FROM sessions WHERE id = ?", sessionID).Scan(&revokedAt, &lastSeenAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if revokedAt.Valid || !touch {
+		return revokedAt.Valid, nil
+	}
+
+	now := time.Now().UTC()
+	if seen, err := time.Parse(time.RFC3339, lastSeenAt); err != nil || now.Sub(seen) >= sessionTouchInterval {
+		go func() {
+			if _, err := db.Exec("UPDATE sessions SET last_seen_at = ? WHERE id = ?", now.Format(time.RFC3339), sessionID); err != nil {
+				log.Printf("Failed to update session %s: %v", sessionID, err)
+			}
+		}()
+	}
+	return false, nil
+}
@@ -0,0 +1,135 @@
+// Package features gates functionality behind feature flags stored in the
+// feature_flags table. A flag is either off, on for everyone, or rolled out
+// to a stable percentage of users.
+package features
+
+import (
+	"database/sql"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+)
+
+// cacheTTL is how long flags are cached before being re-read, which bounds
+// how long other instances take to see a toggle
+const cacheTTL = 30 * time.Second
+
+var cache = struct {
+	sync.Mutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+}{}
+
+// Enabled reports whether the named feature is on for a user. Unknown flags
+// are off, so code can ship before its flag exists. During a percentage
+// rollout each user is consistently in or out, decided by hashing the flag
+// name with their id; anonymous callers only get fully rolled out features.
+func Enabled(name, userID string) bool {
+	flag, ok := lookup(name)
+	if !ok || !flag.Enabled {
+		return false
+	}
+	if flag.RolloutPercentage >= 100 {
+		return true
+	}
+	if userID == "" {
+		return false
+	}
+	return bucket(name, userID) < flag.RolloutPercentage
+}
+
+// bucket places a user in one of 100 buckets for a flag. Including the flag
+// name means the same users aren't always the first to get every feature.
+func bucket(name, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + userID))
+	return int(h.Sum32() % 100)
+}
+
+func lookup(name string) (models.FeatureFlag, bool) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if cache.flags == nil || time.Since(cache.loadedAt) > cacheTTL {
+		flags, err := load()
+		if err != nil {
+			log.Println("Feature flags:", err)
+			// Keep serving the previous flags rather than switching everything off
+			if cache.flags == nil {
+				return models.FeatureFlag{}, false
+			}
+		} else {
+			cache.flags = flags
+		}
+		cache.loadedAt = time.Now()
+	}
+
+	flag, ok := cache.flags[name]
+	return flag, ok
+}
+
+// load reads the state of every flag for Enabled
+func load() (map[string]models.FeatureFlag, error) {
+	rows, err := database.GetDB().Query("SELECT name, enabled, rollout_percentage FROM feature_flags")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := map[string]models.FeatureFlag{}
+	for rows.Next() {
+		var flag models.FeatureFlag
+		if err := rows.Scan(&flag.Name, &flag.Enabled, &flag.RolloutPercentage); err != nil {
+			return nil, err
+		}
+		flags[flag.Name] = flag
+	}
+	return flags, rows.Err()
+}
+
+// List returns every feature flag, by name
+func List() ([]models.FeatureFlag, error) {
+	rows, err := database.GetDB().Query(`
+		SELECT name, description, enabled, rollout_percentage, created_at, updated_at
+		FROM feature_flags ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []models.FeatureFlag{}
+	for rows.Next() {
+		var flag models.FeatureFlag
+		if err := rows.Scan(&flag.Name, &flag.Description, &flag.Enabled, &flag.RolloutPercentage, database.UTCTime(&flag.CreatedAt), database.UTCTime(&flag.UpdatedAt)); err != nil {
+			continue
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// Set creates or updates a flag. Call Invalidate once the transaction is
+// committed for the change to take effect immediately on this instance.
+func Set(tx *sql.Tx, flag models.FeatureFlag) error {
+	_, err := tx.Exec(`
+		INSERT INTO feature_flags (name, description, enabled, rollout_percentage)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description,
+			enabled = excluded.enabled,
+			rollout_percentage = excluded.rollout_percentage
+	`, flag.Name, flag.Description, flag.Enabled, flag.RolloutPercentage)
+	return err
+}
+
+// Invalidate drops the cached flags so the next check re-reads them
+func Invalidate() {
+	cache.Lock()
+	cache.flags = nil
+	cache.Unlock()
+}
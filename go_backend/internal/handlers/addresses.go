@@ -0,0 +1,492 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// addressValidator is applied to every address before it is stored
+var addressValidator utils.AddressValidator = utils.PostalCodeValidator{}
+
+// SetAddressValidator replaces the validator used by the address endpoints,
+// e.g. with one backed by an external validation provider
+func SetAddressValidator(v utils.AddressValidator) {
+	if v == nil {
+		v = utils.PassThroughAddressValidator{}
+	}
+	addressValidator = v
+}
+
+// ListAddresses lists the current user's addresses
+func ListAddresses(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT id, user_id, street_address, city, state, postal_code, country, is_default, created_at, updated_at
+		FROM addresses WHERE user_id = ?
+		ORDER BY is_default DESC, created_at DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	addresses := []models.Address{}
+	for rows.Next() {
+		var a models.Address
+		err := rows.Scan(&a.ID, &a.UserID, &a.StreetAddress, &a.City, &a.State,
+			&a.PostalCode, &a.Country, &a.IsDefault, database.UTCTime(&a.CreatedAt), database.UTCTime(&a.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, a)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(addresses, len(addresses)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CreateAddress adds an address for the current user
+func CreateAddress(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		StreetAddress string `json:"street_address" binding:"required"`
+		City          string `json:"city" binding:"required"`
+		State         string `json:"state"`
+		PostalCode    string `json:"postal_code" binding:"required"`
+		Country       string `json:"country" binding:"required"`
+		IsDefault     bool   `json:"is_default"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	address := models.Address{
+		ID:            utils.GenerateID(),
+		UserID:        userID.(string),
+		StreetAddress: req.StreetAddress,
+		City:          req.City,
+		State:         req.State,
+		PostalCode:    req.PostalCode,
+		Country:       req.Country,
+		IsDefault:     req.IsDefault,
+	}
+
+	if fieldErrors := addressValidator.Validate(&address); len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid address",
+			Code:      "INVALID_ADDRESS",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// A user's first address becomes the default
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM addresses WHERE user_id = ?", userID).Scan(&count); err == nil && count == 0 {
+		address.IsDefault = true
+	}
+
+	if address.IsDefault {
+		if _, err := tx.Exec("UPDATE addresses SET is_default = 0 WHERE user_id = ? AND is_default = 1", userID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to create address",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO addresses (id, user_id, street_address, city, state, postal_code, country, is_default)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, address.ID, address.UserID, address.StreetAddress, address.City, address.State,
+		address.PostalCode, address.Country, address.IsDefault)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create address",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"address": address},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// maxAddressImport caps the addresses one import can add
+const maxAddressImport = 100
+
+// ImportAddresses adds several addresses for the current user at once, e.g.
+// a business's shipping locations. Each address is validated as on create.
+// The import is all or nothing: any invalid address rejects the whole batch,
+// with an error for every invalid one. At most one address may be marked
+// default; it replaces the current default.
+func ImportAddresses(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Addresses []struct {
+			StreetAddress string `json:"street_address"`
+			City          string `json:"city"`
+			State         string `json:"state"`
+			PostalCode    string `json:"postal_code"`
+			Country       string `json:"country"`
+			IsDefault     bool   `json:"is_default"`
+		} `json:"addresses" binding:"required,min=1,max=100"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("addresses must list 1 to %d addresses", maxAddressImport),
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	addresses := make([]models.Address, 0, len(req.Addresses))
+	defaultIndex := -1
+	for i, row := range req.Addresses {
+		field := fmt.Sprintf("addresses[%d]", i)
+
+		address := models.Address{
+			ID:            utils.GenerateID(),
+			UserID:        userID.(string),
+			StreetAddress: row.StreetAddress,
+			City:          row.City,
+			State:         row.State,
+			PostalCode:    row.PostalCode,
+			Country:       row.Country,
+			IsDefault:     row.IsDefault,
+		}
+
+		// The fields CreateAddress requires
+		missing := false
+		for _, required := range []struct{ name, value string }{
+			{"street_address", address.StreetAddress},
+			{"city", address.City},
+			{"postal_code", address.PostalCode},
+			{"country", address.Country},
+		} {
+			if strings.TrimSpace(required.value) == "" {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + "." + required.name, Message: "is required"})
+				missing = true
+			}
+		}
+		if !missing {
+			for _, fieldError := range addressValidator.Validate(&address) {
+				fieldError.Field = field + "." + fieldError.Field
+				fieldErrors = append(fieldErrors, fieldError)
+			}
+		}
+
+		if address.IsDefault {
+			if defaultIndex >= 0 {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".is_default", Message: "only one address can be the default"})
+			}
+			defaultIndex = i
+		}
+		addresses = append(addresses, address)
+	}
+
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid addresses, nothing was imported",
+			Code:      "INVALID_ADDRESS",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// As on create, a user's first address becomes the default
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM addresses WHERE user_id = ?", userID).Scan(&count); err == nil && count == 0 && defaultIndex < 0 {
+		addresses[0].IsDefault = true
+	}
+
+	if defaultIndex >= 0 {
+		_, err = tx.Exec("UPDATE addresses SET is_default = 0 WHERE user_id = ? AND is_default = 1", userID)
+	}
+	ids := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`
+			INSERT INTO addresses (id, user_id, street_address, city, state, postal_code, country, is_default)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, address.ID, address.UserID, address.StreetAddress, address.City, address.State,
+			address.PostalCode, address.Country, address.IsDefault)
+		ids = append(ids, address.ID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to import addresses",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"imported": len(ids),
+			"ids":      ids,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// UpdateAddress updates one of the current user's addresses
+func UpdateAddress(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	addressID := c.Param("id")
+
+	var req struct {
+		StreetAddress *string `json:"street_address"`
+		City          *string `json:"city"`
+		State         *string `json:"state"`
+		PostalCode    *string `json:"postal_code"`
+		Country       *string `json:"country"`
+		IsDefault     *bool   `json:"is_default"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var address models.Address
+	err := db.QueryRow(`
+		SELECT id, user_id, street_address, city, state, postal_code, country, is_default
+		FROM addresses WHERE id = ? AND user_id = ?
+	`, addressID, userID).Scan(&address.ID, &address.UserID, &address.StreetAddress, &address.City,
+		&address.State, &address.PostalCode, &address.Country, &address.IsDefault)
+	if err == sql.ErrNoRows {
+		notFound(c, "Address")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.StreetAddress != nil {
+		address.StreetAddress = *req.StreetAddress
+	}
+	if req.City != nil {
+		address.City = *req.City
+	}
+	if req.State != nil {
+		address.State = *req.State
+	}
+	if req.PostalCode != nil {
+		address.PostalCode = *req.PostalCode
+	}
+	if req.Country != nil {
+		address.Country = *req.Country
+	}
+	if req.IsDefault != nil && *req.IsDefault {
+		address.IsDefault = true
+	}
+
+	if fieldErrors := addressValidator.Validate(&address); len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid address",
+			Code:      "INVALID_ADDRESS",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	if address.IsDefault {
+		if _, err := tx.Exec("UPDATE addresses SET is_default = 0 WHERE user_id = ? AND id != ? AND is_default = 1", userID, addressID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update address",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE addresses SET street_address = ?, city = ?, state = ?, postal_code = ?, country = ?, is_default = ?
+		WHERE id = ? AND user_id = ?
+	`, address.StreetAddress, address.City, address.State, address.PostalCode, address.Country,
+		address.IsDefault, addressID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update address",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"address": address},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteAddress deletes one of the current user's addresses
+func DeleteAddress(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	addressID := c.Param("id")
+
+	db := database.GetDB()
+
+	result, err := db.Exec("DELETE FROM addresses WHERE id = ? AND user_id = ?", addressID, userID)
+	if err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+		// Addresses referenced by orders are protected by ON DELETE RESTRICT
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Address is in use by an order",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete address",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		notFound(c, "Address")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Address deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
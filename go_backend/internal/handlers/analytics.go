@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultReportDays is the period reports cover when no from date is given
+const defaultReportDays = 30
+
+// reportPeriod reads the from/to query parameters of a report as the UTC
+// timestamps orders are stored with, defaulting to the last
+// defaultReportDays days. It writes the error response when they are invalid.
+func reportPeriod(c *gin.Context) (from, to string, ok bool) {
+	now := time.Now().UTC()
+	from = now.AddDate(0, 0, -defaultReportDays).Format(time.RFC3339)
+	to = now.Add(time.Second).Format(time.RFC3339)
+
+	for _, bound := range []struct {
+		param string
+		value *string
+	}{{"from", &from}, {"to", &to}} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		timestamp, err := parseDateParam(raw, bound.param == "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid " + bound.param + " date, expected YYYY-MM-DD or RFC 3339",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return "", "", false
+		}
+		*bound.value = timestamp
+	}
+
+	if from >= to {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "from must be before to",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return "", "", false
+	}
+
+	return from, to, true
+}
+
+// salesOrders restricts order_items (oi) joined to orders (o) to the sales
+// that count in reports: neither cancelled nor deleted, created in [from, to)
+const salesOrders = "o.status != 'cancelled' AND o.deleted_at IS NULL AND o.created_at >= ? AND o.created_at < ?"
+
+// VendorAnalytics reports units sold and revenue for the current vendor's
+// products over a period, in total, per product and per day. Admins may pass
+// ?vendor_id= to view any vendor.
+func VendorAnalytics(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var vendorID string
+	var err error
+	if role == "admin" && c.Query("vendor_id") != "" {
+		err = db.QueryRow("SELECT id FROM vendors WHERE id = ?", c.Query("vendor_id")).Scan(&vendorID)
+	} else {
+		err = db.QueryRow("SELECT id FROM vendors WHERE user_id = ?", userID).Scan(&vendorID)
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Vendor not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	args := []interface{}{vendorID, from, to}
+	base := `
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		JOIN products p ON oi.product_id = p.id
+		WHERE p.vendor_id = ? AND ` + salesOrders
+
+	var unitsSold int
+	var revenue models.Money
+	err = db.QueryRow("SELECT COALESCE(SUM(oi.quantity), 0), COALESCE(SUM(oi.total_price), 0)"+base, args...).
+		Scan(&unitsSold, &revenue)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	products := []gin.H{}
+	rows, err := db.Query(`
+		SELECT p.id, p.name, SUM(oi.quantity), SUM(oi.total_price) AS revenue`+base+`
+		GROUP BY p.id, p.name
+		ORDER BY revenue DESC
+		LIMIT 10
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	for rows.Next() {
+		var productID, name string
+		var units int
+		var productRevenue models.Money
+		if err := rows.Scan(&productID, &name, &units, &productRevenue); err != nil {
+			continue
+		}
+		products = append(products, gin.H{
+			"product_id": productID,
+			"name":       name,
+			"units_sold": units,
+			"revenue":    productRevenue,
+		})
+	}
+	rows.Close()
+
+	daily := []gin.H{}
+	rows, err = db.Query(`
+		SELECT substr(o.created_at, 1, 10) AS day, SUM(oi.quantity), SUM(oi.total_price)`+base+`
+		GROUP BY day
+		ORDER BY day
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	for rows.Next() {
+		var day string
+		var units int
+		var dayRevenue models.Money
+		if err := rows.Scan(&day, &units, &dayRevenue); err != nil {
+			continue
+		}
+		daily = append(daily, gin.H{
+			"date":       day,
+			"units_sold": units,
+			"revenue":    dayRevenue,
+		})
+	}
+	rows.Close()
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"vendor_id":    vendorID,
+			"from":         from,
+			"to":           to,
+			"units_sold":   unitsSold,
+			"revenue":      revenue,
+			"top_products": singlePage(products, len(products)),
+			"daily":        singlePage(daily, len(daily)),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CategorySalesReport reports revenue and units sold per product category
+// over a period, with each category's share of the total revenue, highest
+// revenue first. Order items don't record the category at the time of sale,
+// so sales are attributed to each product's current category.
+func CategorySalesReport(c *gin.Context) {
+	from, to, ok := reportPeriod(c)
+	if !ok {
+		return
+	}
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	args := []interface{}{currentStoreID(c), from, to}
+	base := `
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		JOIN products p ON oi.product_id = p.id
+		JOIN categories cat ON p.category_id = cat.id
+		WHERE p.store_id = ? AND ` + salesOrders
+
+	var totalRevenue models.Money
+	var categories int
+	err := db.QueryRow("SELECT COUNT(DISTINCT cat.id), COALESCE(SUM(oi.total_price), 0)"+base, args...).
+		Scan(&categories, &totalRevenue)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	page, limit, offset, ok := listWindow(c, params, categories)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT cat.id, cat.name, SUM(oi.quantity), SUM(oi.total_price) AS revenue`+base+`
+		GROUP BY cat.id, cat.name
+		ORDER BY revenue DESC, cat.name
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	report := []gin.H{}
+	for rows.Next() {
+		var categoryID, name string
+		var units int
+		var revenue models.Money
+		if err := rows.Scan(&categoryID, &name, &units, &revenue); err != nil {
+			continue
+		}
+
+		share := 0.0
+		if totalRevenue > 0 {
+			share = math.Round(float64(revenue)/float64(totalRevenue)*10000) / 100
+		}
+		report = append(report, gin.H{
+			"category_id":        categoryID,
+			"name":               name,
+			"units_sold":         units,
+			"revenue":            revenue,
+			"revenue_percentage": share,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"from":          from,
+			"to":            to,
+			"total_revenue": totalRevenue,
+			"categories":    paginated(c, report, page, limit, categories),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordAudit writes an audit_logs entry for an action performed by the
+// current user, serializing changes as JSON
+func recordAudit(ex execer, c *gin.Context, action, entityType, entityID string, changes interface{}) error {
+	userID, _ := c.Get("userID")
+
+	var changesJSON *string
+	if changes != nil {
+		b, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		s := string(b)
+		changesJSON = &s
+	}
+
+	_, err := ex.Exec(`
+		INSERT INTO audit_logs (id, user_id, action, entity_type, entity_id, changes, ip_address)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, utils.GenerateID(), userID, action, entityType, entityID, changesJSON, c.ClientIP())
+	return err
+}
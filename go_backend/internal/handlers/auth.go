@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
@@ -19,7 +21,7 @@ func Register(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid request body",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -30,7 +32,7 @@ func Register(c *gin.Context) {
 			Success:   false,
 			Error:     "Passwords do not match",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -41,7 +43,7 @@ func Register(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid email format",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -52,7 +54,7 @@ func Register(c *gin.Context) {
 			Success:   false,
 			Error:     "Password must be at least 8 characters with uppercase, lowercase, and numbers",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -60,82 +62,95 @@ func Register(c *gin.Context) {
 	db := database.GetDB()
 
 	// Check if email already exists
-	var existingID string
-	err := db.QueryRow("SELECT id FROM users WHERE email = ?", req.Email).Scan(&existingID)
-	if err == nil {
-		c.JSON(http.StatusConflict, models.APIResponse{
+	taken, err := emailRegistered(db, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Error:     "Email already registered",
-			Code:      "CONFLICT",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
-
-	// Hash password
-	passwordHash, err := utils.HashPassword(req.Password)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	if taken {
+		c.JSON(http.StatusConflict, models.APIResponse{
 			Success:   false,
-			Error:     "Failed to hash password",
-			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Error:     "Email already registered",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
-	// Create user
-	userID := utils.GenerateID()
-	now := time.Now().Format(time.RFC3339)
-
-	_, err = db.Exec(`
-		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, role, is_active, email_verified, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, userID, req.Email, passwordHash, req.FirstName, req.LastName, req.Phone, "customer", true, false, now, now)
-
-	if err != nil {
+	user := models.User{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Phone:     req.Phone,
+		Role:      "customer",
+	}
+	if err := createUser(db, &user, req.Password); err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to create user",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	// Generate token
-	token, err := utils.GenerateToken(userID, "customer")
+	tokens, err := issueToken(c, db, user.ID, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to generate token",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
-	user := models.User{
-		ID:            userID,
-		Email:         req.Email,
-		FirstName:     req.FirstName,
-		LastName:      req.LastName,
-		Phone:         req.Phone,
-		Role:          "customer",
-		IsActive:      true,
-		EmailVerified: false,
-	}
-
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"token": token,
-			"user":  user,
+			"token":         tokens.Token,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          user,
 		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// emailRegistered reports whether an account already uses the email
+func emailRegistered(db *sql.DB, email string) (bool, error) {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = ?", email).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// createUser hashes the password and inserts an active, unverified user,
+// filling in the user's ID
+func createUser(ex execer, user *models.User, password string) error {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.ID = utils.GenerateID()
+	user.IsActive = true
+	user.EmailVerified = false
+
+	_, err = ex.Exec(`
+		INSERT INTO users (id, email, password_hash, first_name, last_name, phone, role, is_active, email_verified)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, user.ID, user.Email, passwordHash, user.FirstName, user.LastName, user.Phone, user.Role, user.IsActive, user.EmailVerified)
+	return err
+}
+
 // Login handles user login
 func Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -144,7 +159,7 @@ func Login(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid request body",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -160,7 +175,7 @@ func Login(c *gin.Context) {
 	`, req.Email).Scan(
 		&user.ID, &user.Email, &passwordHash, &user.FirstName, &user.LastName,
 		&user.Phone, &user.Role, &user.IsActive, &user.EmailVerified,
-		&user.CreatedAt, &user.UpdatedAt,
+		database.UTCTime(&user.CreatedAt), database.UTCTime(&user.UpdatedAt),
 	)
 
 	if err == sql.ErrNoRows {
@@ -168,7 +183,7 @@ func Login(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid credentials",
 			Code:      "UNAUTHORIZED",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -178,7 +193,7 @@ func Login(c *gin.Context) {
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -189,7 +204,7 @@ func Login(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid credentials",
 			Code:      "UNAUTHORIZED",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -200,33 +215,47 @@ func Login(c *gin.Context) {
 			Success:   false,
 			Error:     "Account is inactive",
 			Code:      "FORBIDDEN",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	// Generate token
-	token, err := utils.GenerateToken(user.ID, user.Role)
+	tokens, err := issueToken(c, db, user.ID, user.Role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to generate token",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
+	recordLogin(db, user.ID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"token": token,
-			"user":  user,
+			"token":         tokens.Token,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          user,
 		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// recordLogin sets the user's last_login_at in the background, so logging in
+// doesn't wait on the write
+func recordLogin(db *sql.DB, userID string) {
+	loggedInAt := time.Now().UTC().Format(time.RFC3339)
+	go func() {
+		if _, err := db.Exec("UPDATE users SET last_login_at = ? WHERE id = ?", loggedInAt, userID); err != nil {
+			log.Printf("Failed to record login for user %s: %v", userID, err)
+		}
+	}()
+}
+
 // GetCurrentUser returns the current authenticated user
 func GetCurrentUser(c *gin.Context) {
 	userID, _ := c.Get("userID")
@@ -239,7 +268,7 @@ func GetCurrentUser(c *gin.Context) {
 	`, userID).Scan(
 		&user.ID, &user.Email, &user.FirstName, &user.LastName,
 		&user.Phone, &user.Role, &user.IsActive, &user.EmailVerified,
-		&user.CreatedAt, &user.UpdatedAt,
+		database.UTCTime(&user.CreatedAt), database.UTCTime(&user.UpdatedAt),
 	)
 
 	if err != nil {
@@ -247,23 +276,255 @@ func GetCurrentUser(c *gin.Context) {
 			Success:   false,
 			Error:     "User not found",
 			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"user": user},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// userStatsTTL is how long a user's order statistics are cached
+const userStatsTTL = time.Minute
+
+// userStats are the lifetime order statistics shown on account pages
+type userStats struct {
+	TotalOrders       int               `json:"total_orders"`
+	TotalSpent        models.Money      `json:"total_spent"`
+	AverageOrderValue models.Money      `json:"average_order_value"`
+	FavoriteCategory  *favoriteCategory `json:"favorite_category"`
+}
+
+// favoriteCategory is the category a user has bought the most units from
+type favoriteCategory struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Units int    `json:"units"`
+}
+
+var userStatsCache = struct {
+	sync.Mutex
+	entries map[string]userStatsEntry
+}{entries: map[string]userStatsEntry{}}
+
+type userStatsEntry struct {
+	stats   userStats
+	expires time.Time
+}
+
+// GetCurrentUserStats returns the current user's lifetime order statistics.
+// Cancelled and deleted orders are excluded; only delivered or paid orders
+// count towards the amount spent.
+func GetCurrentUserStats(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	id := userID.(string)
+
+	userStatsCache.Lock()
+	entry, ok := userStatsCache.entries[id]
+	userStatsCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success:   true,
+			Data:      gin.H{"stats": entry.stats},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var stats userStats
+	var paidOrders int
+	err := db.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(CASE WHEN paid THEN total_amount END), 0),
+		       COALESCE(SUM(paid), 0)
+		FROM (
+			SELECT o.total_amount,
+			       o.status = 'delivered' OR EXISTS (
+			           SELECT 1 FROM payments p WHERE p.order_id = o.id AND p.status = 'completed'
+			       ) AS paid
+			FROM orders o
+			WHERE o.user_id = ? AND o.status != 'cancelled' AND o.deleted_at IS NULL
+		)
+	`, id).Scan(&stats.TotalOrders, &stats.TotalSpent, &paidOrders)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if paidOrders > 0 {
+		stats.AverageOrderValue = stats.TotalSpent / models.Money(paidOrders)
+	}
+
+	var favorite favoriteCategory
+	err = db.QueryRow(`
+		SELECT cat.id, cat.name, SUM(oi.quantity) AS units
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		JOIN products p ON oi.product_id = p.id
+		JOIN categories cat ON p.category_id = cat.id
+		WHERE o.user_id = ? AND o.status != 'cancelled' AND o.deleted_at IS NULL
+		GROUP BY cat.id, cat.name
+		ORDER BY units DESC, cat.name
+		LIMIT 1
+	`, id).Scan(&favorite.ID, &favorite.Name, &favorite.Units)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
+	if err == nil {
+		stats.FavoriteCategory = &favorite
+	}
+
+	userStatsCache.Lock()
+	now := time.Now()
+	for cachedID, cached := range userStatsCache.entries {
+		if now.After(cached.expires) {
+			delete(userStatsCache.entries, cachedID)
+		}
+	}
+	userStatsCache.entries[id] = userStatsEntry{stats: stats, expires: now.Add(userStatsTTL)}
+	userStatsCache.Unlock()
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Data:      user,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      gin.H{"stats": stats},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
-// Logout handles user logout (client-side token removal)
+// Logout ends the session of the calling access token, or of the
+// refresh_token in the body, so neither its access tokens nor its refresh
+// tokens are accepted any more. The calling token itself is also recorded as
+// revoked by jti, which covers tokens without a recorded session. Logging out
+// of an already ended session succeeds too.
 func Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	c.ShouldBindJSON(&req)
+
+	db := database.GetDB()
+
+	var sessionIDs []string
+	if sessionID, ok := c.Get("sessionID"); ok && sessionID != "" {
+		sessionIDs = append(sessionIDs, sessionID.(string))
+	}
+	if claims, err := utils.ParseRefreshToken(req.RefreshToken); err == nil {
+		var sessionID string
+		err := db.QueryRow("SELECT session_id FROM refresh_tokens WHERE id = ? AND user_id = ?", claims.ID, claims.UserID).
+			Scan(&sessionID)
+		if err == nil {
+			sessionIDs = append(sessionIDs, sessionID)
+		} else if err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if tokenID, ok := c.Get("tokenID"); ok && tokenID != "" {
+		userID, _ := c.Get("userID")
+		expiresAt, _ := c.Get("tokenExpiresAt")
+		if err := revokeToken(db, tokenID.(string), userID.(string), expiresAt.(time.Time)); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to log out",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := revokeSessionTokens(db, sessionID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to log out",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Data:      gin.H{"message": "Logged out successfully"},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// IntrospectToken reports whether a token is currently valid, and its claims
+// if so, in the style of RFC 7662. Any token can be checked, not only the
+// caller's own, and an invalid, expired or revoked token is a normal
+// {"active": false} response rather than an error.
+func IntrospectToken(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" form:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	claims, err := utils.ParseToken(req.Token)
+	revoked := false
+	if err == nil {
+		revoked, err = database.TokenRevoked(claims.ID, claims.SessionID, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+	if err != nil || revoked {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success:   true,
+			Data:      gin.H{"active": false},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"active":     true,
+			"user_id":    claims.UserID,
+			"role":       claims.Role,
+			"exp":        claims.ExpiresAt.Unix(),
+			"expires_at": claims.ExpiresAt.UTC().Format(time.RFC3339),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/middleware"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginRecordsLastLogin(t *testing.T) {
+	userID := createTestUser(t, "customer")
+	hash, err := utils.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, "UPDATE users SET password_hash = ? WHERE id = ?", hash, userID)
+
+	lastLogin := func() sql.NullString {
+		var value sql.NullString
+		if err := database.GetDB().QueryRow("SELECT last_login_at FROM users WHERE id = ?", userID).Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+
+	login := func(password string) testResponse {
+		return serve(t, Login, http.MethodPost, "/auth/login", "/auth/login", "", "", map[string]string{
+			"email":    userID + "@example.com",
+			"password": password,
+		})
+	}
+
+	expectStatus(t, login("wrong horse"), http.StatusUnauthorized, "UNAUTHORIZED")
+	if lastLogin().Valid {
+		t.Fatal("a failed login set last_login_at")
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	expectStatus(t, login("correct horse"), http.StatusOK, "")
+
+	// last_login_at is written in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for !lastLogin().Valid && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	value := lastLogin()
+	if !value.Valid {
+		t.Fatal("last_login_at was not set")
+	}
+	loggedInAt, err := time.Parse(time.RFC3339, value.String)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loggedInAt.Before(before) || loggedInAt.After(time.Now().UTC()) {
+		t.Fatalf("last_login_at = %s, want the time of the login", value.String)
+	}
+}
+
+func TestIntrospectTokenAfterLogout(t *testing.T) {
+	userID := createTestUser(t, "customer")
+	hash, err := utils.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, "UPDATE users SET password_hash = ? WHERE id = ?", hash, userID)
+
+	res := serve(t, Login, http.MethodPost, "/auth/login", "/auth/login", "", "", map[string]string{
+		"email":    userID + "@example.com",
+		"password": "correct horse",
+	})
+	expectStatus(t, res, http.StatusOK, "")
+	token := res.Data["token"].(string)
+	refreshToken := res.Data["refresh_token"].(string)
+
+	introspect := func() testResponse {
+		t.Helper()
+		res := serve(t, IntrospectToken, http.MethodPost, "/auth/introspect", "/auth/introspect", "", "", map[string]string{"token": token})
+		expectStatus(t, res, http.StatusOK, "")
+		return res
+	}
+	if res := introspect(); res.Data["active"] != true || res.Data["user_id"] != userID {
+		t.Fatalf("before logout: %v, want the active token", res.Data)
+	}
+
+	// Logout runs behind OptionalAuthMiddleware, as routed
+	r := gin.New()
+	r.POST("/auth/logout", middleware.OptionalAuthMiddleware(), Logout)
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout: got %d %s", w.Code, w.Body.String())
+	}
+
+	if res := introspect(); res.Data["active"] != false || len(res.Data) != 1 {
+		t.Fatalf("after logout: %v, want only active false", res.Data)
+	}
+}
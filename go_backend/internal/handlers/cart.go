@@ -2,7 +2,11 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
@@ -23,26 +27,28 @@ func GetCart(c *gin.Context) {
 	if err == sql.ErrNoRows {
 		// Create new cart
 		cartID = utils.GenerateID()
-		now := time.Now().Format(time.RFC3339)
-		_, err = db.Exec("INSERT INTO carts (id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)",
-			cartID, userID, now, now)
+		_, err = db.Exec("INSERT INTO carts (id, user_id) VALUES (?, ?)", cartID, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
 				Error:     "Failed to create cart",
 				Code:      "INTERNAL_ERROR",
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
 			})
 			return
 		}
 	}
 
-	// Get cart items
+	// Get cart items. Items with a variant are priced with its price
+	// modifier and checked against its stock.
 	rows, err := db.Query(`
-		SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, ci.quantity, 
-		       p.name, p.price, p.stock_quantity
+		SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, ci.quantity,
+		       p.name, `+effectivePrice("p")+`, p.stock_quantity,
+		       v.id IS NOT NULL, COALESCE(v.name, ''), COALESCE(v.value, ''),
+		       COALESCE(v.price_modifier, 0), COALESCE(v.stock_quantity, 0)
 		FROM cart_items ci
 		JOIN products p ON ci.product_id = p.id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
 		WHERE ci.cart_id = ?
 	`, cartID)
 	if err != nil {
@@ -50,59 +56,363 @@ func GetCart(c *gin.Context) {
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 	defer rows.Close()
 
 	items := []gin.H{}
-	var total float64
+	var total models.Money
 	for rows.Next() {
 		var item models.CartItem
 		var productName string
-		var productPrice float64
+		var productPrice models.Money
 		var stockQuantity int
+		var variant models.ProductVariant
+		var hasVariant bool
 		err := rows.Scan(&item.ID, &item.CartID, &item.ProductID, &item.VariantID,
-			&item.Quantity, &productName, &productPrice, &stockQuantity)
+			&item.Quantity, &productName, &productPrice, &stockQuantity,
+			&hasVariant, &variant.Name, &variant.Value, &variant.PriceModifier, &variant.StockQuantity)
 		if err != nil {
 			continue
 		}
 
-		itemTotal := float64(item.Quantity) * productPrice
+		price := productPrice
+		if hasVariant {
+			price = variantPrice(productPrice, variant.PriceModifier)
+			stockQuantity = variant.StockQuantity
+		}
+		itemTotal := price * models.Money(item.Quantity)
 		total += itemTotal
 
+		cartItem := gin.H{
+			"id":         item.ID,
+			"product_id": item.ProductID,
+			"variant_id": item.VariantID,
+			"quantity":   item.Quantity,
+			"name":       productName,
+			"price":      price,
+			"item_total": itemTotal,
+			"in_stock":   stockQuantity >= item.Quantity,
+		}
+		if hasVariant {
+			cartItem["variant"] = gin.H{
+				"name":           variant.Name,
+				"value":          variant.Value,
+				"price_modifier": variant.PriceModifier,
+			}
+		}
+		items = append(items, cartItem)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"cart_id":       cartID,
+			"items":         items,
+			"total":         total,
+			"free_shipping": freeShipping(total),
+			"currency":      currencyInfo(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// variantPrice is the price of a product variant: the product's price plus
+// the variant's modifier, never below zero
+func variantPrice(productPrice, modifier models.Money) models.Money {
+	return max(productPrice+modifier, 0)
+}
+
+// Cart item availability, as reported by ValidateCart. reserved_stock is only
+// reported when claiming a shared cart, see stockReserved.
+const (
+	cartItemAvailable         = "available"
+	cartItemInsufficientStock = "insufficient_stock"
+	cartItemOutOfStock        = "out_of_stock"
+	cartItemUnavailable       = "unavailable"
+	cartItemReserved          = "reserved_stock"
+)
+
+// ValidateCart checks every item in the current user's cart against current
+// stock and product status without changing anything, so clients can catch
+// what would make CreateOrder fail before showing checkout. Items with a
+// variant are checked against the variant's stock.
+func ValidateCart(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+
+	var cartID string
+	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT ci.id, ci.product_id, ci.variant_id, ci.quantity, p.name, p.status, p.stock_quantity,
+		       v.id IS NOT NULL, COALESCE(v.stock_quantity, 0)
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
+		WHERE ci.cart_id = ?
+		ORDER BY ci.created_at, ci.id
+	`, cartID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := []gin.H{}
+	canCheckout := true
+	for rows.Next() {
+		var itemID, productID, name, productStatus string
+		var variantID *string
+		var quantity, productStock, variantStock int
+		var variantExists bool
+		err := rows.Scan(&itemID, &productID, &variantID, &quantity, &name, &productStatus, &productStock,
+			&variantExists, &variantStock)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		available := productStock
+		if variantID != nil {
+			available = variantStock
+		}
+
+		status := cartItemAvailable
+		switch {
+		case productStatus != "active" || (variantID != nil && !variantExists):
+			status = cartItemUnavailable
+			available = 0
+		case available <= 0:
+			status = cartItemOutOfStock
+			available = 0
+		case available < quantity:
+			status = cartItemInsufficientStock
+		}
+		if status != cartItemAvailable {
+			canCheckout = false
+		}
+
 		items = append(items, gin.H{
-			"id":          item.ID,
-			"product_id":  item.ProductID,
-			"variant_id":  item.VariantID,
-			"quantity":    item.Quantity,
-			"name":        productName,
-			"price":       productPrice,
-			"item_total":  itemTotal,
-			"in_stock":    stockQuantity >= item.Quantity,
+			"cart_item_id":       itemID,
+			"product_id":         productID,
+			"variant_id":         variantID,
+			"name":               name,
+			"quantity":           quantity,
+			"available_quantity": available,
+			"status":             status,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// An empty cart can't be checked out either
+	if len(items) == 0 {
+		canCheckout = false
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"cart_id":      nullableString(cartID),
+			"can_checkout": canCheckout,
+			"items":        items,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// EstimateCart prices a list of items the way GetCart prices a cart, at
+// current prices and with variant price modifiers, without needing an
+// account or storing anything. Each item reports its availability as in
+// ValidateCart; items that can't be bought as asked aren't counted in the
+// subtotal.
+func EstimateCart(c *gin.Context) {
+	var req struct {
+		Items []struct {
+			ProductID string  `json:"product_id" binding:"required"`
+			VariantID *string `json:"variant_id"`
+			Quantity  int     `json:"quantity" binding:"required,gt=0"`
+		} `json:"items" binding:"required,min=1,max=50,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Provide 1 to 50 items, each with a product_id and a positive quantity",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	items := []gin.H{}
+	var subtotal models.Money
+	allAvailable := true
+	for _, reqItem := range req.Items {
+		var name, productStatus string
+		var productPrice, modifier models.Money
+		var productStock, variantStock int
+		var variantExists bool
+		err := db.QueryRow(`
+			SELECT p.name, p.status, `+effectivePrice("p")+`, p.stock_quantity,
+			       v.id IS NOT NULL, COALESCE(v.price_modifier, 0), COALESCE(v.stock_quantity, 0)
+			FROM products p
+			LEFT JOIN product_variants v ON v.id = ? AND v.product_id = p.id
+			WHERE p.id = ? AND p.store_id = ?
+		`, reqItem.VariantID, reqItem.ProductID, storeID).Scan(&name, &productStatus, &productPrice, &productStock,
+			&variantExists, &modifier, &variantStock)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		item := gin.H{
+			"product_id": reqItem.ProductID,
+			"variant_id": reqItem.VariantID,
+			"quantity":   reqItem.Quantity,
+		}
+		if err == sql.ErrNoRows || productStatus != "active" || (reqItem.VariantID != nil && !variantExists) {
+			item["status"] = cartItemUnavailable
+			item["available_quantity"] = 0
+			items = append(items, item)
+			allAvailable = false
+			continue
+		}
+
+		price, available := productPrice, productStock
+		if reqItem.VariantID != nil {
+			price, available = variantPrice(productPrice, modifier), variantStock
+		}
+		available = max(available, 0)
+
+		status := cartItemAvailable
+		switch {
+		case available == 0:
+			status = cartItemOutOfStock
+		case available < reqItem.Quantity:
+			status = cartItemInsufficientStock
+		}
+
+		itemTotal := price * models.Money(reqItem.Quantity)
+		if status == cartItemAvailable {
+			subtotal += itemTotal
+		} else {
+			allAvailable = false
+		}
+
+		item["name"] = name
+		item["price"] = price
+		item["item_total"] = itemTotal
+		item["available_quantity"] = available
+		item["status"] = status
+		items = append(items, item)
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"cart_id": cartID,
-			"items":   items,
-			"total":   total,
+			"items":         items,
+			"subtotal":      subtotal,
+			"all_available": allAvailable,
+			"free_shipping": freeShipping(subtotal),
+			"currency":      currencyInfo(),
 		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
-// AddToCart adds an item to the cart
+// cartIdempotencyTTL is how long an AddToCart idempotency key is remembered
+const cartIdempotencyTTL = 24 * time.Hour
+
+// errIdempotencyKeyReused means an idempotency key was sent again with a
+// different request
+var errIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// claimCartIdempotencyKey records the key for an add to the cart. It reports
+// whether the key was already used for the identical add, which makes the
+// request a retry that must not be applied again. Expired keys are purged.
+func claimCartIdempotencyKey(tx *sql.Tx, userID, key, productID string, variantID *string, quantity int) (bool, error) {
+	cutoff := time.Now().UTC().Add(-cartIdempotencyTTL).Format(time.RFC3339)
+	if _, err := tx.Exec("DELETE FROM cart_idempotency_keys WHERE created_at < ?", cutoff); err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT OR IGNORE INTO cart_idempotency_keys (user_id, idempotency_key, product_id, variant_id, quantity)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, key, productID, variantID, quantity)
+	if err != nil {
+		return false, err
+	}
+	if inserted, _ := result.RowsAffected(); inserted > 0 {
+		return false, nil
+	}
+
+	var storedProductID string
+	var storedVariantID *string
+	var storedQuantity int
+	err = tx.QueryRow("SELECT product_id, variant_id, quantity FROM cart_idempotency_keys WHERE user_id = ? AND idempotency_key = ?",
+		userID, key).Scan(&storedProductID, &storedVariantID, &storedQuantity)
+	if err != nil {
+		return false, err
+	}
+
+	sameVariant := (storedVariantID == nil && variantID == nil) ||
+		(storedVariantID != nil && variantID != nil && *storedVariantID == *variantID)
+	if storedProductID != productID || !sameVariant || storedQuantity != quantity {
+		return false, errIdempotencyKeyReused
+	}
+	return true, nil
+}
+
+// AddToCart adds an item to the cart. An optional idempotency_key makes
+// retries of the same add no-ops for cartIdempotencyTTL.
 func AddToCart(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
 	var req struct {
-		ProductID string  `json:"product_id" binding:"required"`
-		VariantID *string `json:"variant_id"`
-		Quantity  int     `json:"quantity" binding:"required,gt=0"`
+		ProductID      string  `json:"product_id" binding:"required"`
+		VariantID      *string `json:"variant_id"`
+		Quantity       int     `json:"quantity" binding:"required,gt=0"`
+		IdempotencyKey string  `json:"idempotency_key" binding:"max=255"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -110,61 +420,119 @@ func AddToCart(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid request body",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	db := database.GetDB()
 
+	// Only active products of the current store can be added
+	var productCount int
+	err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND store_id = ? AND status = 'active'",
+		req.ProductID, currentStoreID(c)).Scan(&productCount)
+	if err != nil || productCount == 0 {
+		notFound(c, "Product")
+		return
+	}
+
 	// Get or create cart
 	var cartID string
-	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+	err = db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
 	if err == sql.ErrNoRows {
 		cartID = utils.GenerateID()
-		now := time.Now().Format(time.RFC3339)
-		_, err = db.Exec("INSERT INTO carts (id, user_id, created_at, updated_at) VALUES (?, ?, ?, ?)",
-			cartID, userID, now, now)
+		_, err = db.Exec("INSERT INTO carts (id, user_id) VALUES (?, ?)", cartID, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
 				Error:     "Failed to create cart",
 				Code:      "INTERNAL_ERROR",
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
 			})
 			return
 		}
 	}
 
-	// Check if item already exists
-	var existingItemID string
-	err = db.QueryRow(`
-		SELECT id FROM cart_items 
-		WHERE cart_id = ? AND product_id = ? AND (variant_id = ? OR (variant_id IS NULL AND ? IS NULL))
-	`, cartID, req.ProductID, req.VariantID, req.VariantID).Scan(&existingItemID)
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
 
-	now := time.Now().Format(time.RFC3339)
-	if err == sql.ErrNoRows {
-		// Add new item
-		itemID := utils.GenerateID()
-		_, err = db.Exec(`
-			INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
-		`, itemID, cartID, req.ProductID, req.VariantID, req.Quantity, now, now)
-	} else {
-		// Update quantity
-		_, err = db.Exec(`
-			UPDATE cart_items SET quantity = quantity + ?, updated_at = ?
-			WHERE id = ?
-		`, req.Quantity, now, existingItemID)
+	if req.IdempotencyKey != "" {
+		duplicate, err := claimCartIdempotencyKey(tx, userID.(string), req.IdempotencyKey, req.ProductID, req.VariantID, req.Quantity)
+		if err == errIdempotencyKeyReused {
+			c.JSON(http.StatusConflict, models.APIResponse{
+				Success:   false,
+				Error:     "Idempotency key was already used for a different item or quantity",
+				Code:      "IDEMPOTENCY_KEY_REUSED",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to add item to cart",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if duplicate {
+			c.JSON(http.StatusOK, models.APIResponse{
+				Success:   true,
+				Data:      gin.H{"message": "Item added to cart", "duplicate": true},
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
 	}
 
+	// Checked after the idempotency key, so a retried add that went through
+	// isn't refused
+	reserved, err := stockReserved(tx, req.ProductID, req.VariantID, req.Quantity)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to add item to cart",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if reserved {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Remaining stock is reserved",
+			Code:      "RESERVED_STOCK",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := addCartItem(tx, cartID, req.ProductID, req.VariantID, req.Quantity); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to add item to cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -172,10 +540,58 @@ func AddToCart(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Data:      gin.H{"message": "Item added to cart"},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// stockReserved reports whether adding quantity of a product, or of one of
+// its variants, to a cart would take from the product's reserve_stock. The
+// last reserve_stock units of the product's stock, or of the variant's, are
+// kept for checkouts already under way: carts holding them and orders can
+// still use them, but no more can be added to a cart.
+func stockReserved(tx *sql.Tx, productID string, variantID *string, quantity int) (bool, error) {
+	var stock, reserve int
+	err := tx.QueryRow(`
+		SELECT COALESCE(v.stock_quantity, p.stock_quantity), p.reserve_stock
+		FROM products p
+		LEFT JOIN product_variants v ON v.id = ? AND v.product_id = p.id
+		WHERE p.id = ?
+	`, variantID, productID).Scan(&stock, &reserve)
+	if err != nil {
+		return false, err
+	}
+	return reserve > 0 && stock-reserve < quantity, nil
+}
+
+// addCartItem adds a quantity of a product, or of one of its variants, to a
+// cart, increasing the line that already holds it if there is one
+func addCartItem(tx *sql.Tx, cartID, productID string, variantID *string, quantity int) error {
+	var existingItemID string
+	err := tx.QueryRow(`
+		SELECT id FROM cart_items 
+		WHERE cart_id = ? AND product_id = ? AND (variant_id = ? OR (variant_id IS NULL AND ? IS NULL))
+	`, cartID, productID, variantID, variantID).Scan(&existingItemID)
+
+	if err == sql.ErrNoRows {
+		// Add new item
+		_, err = tx.Exec(`
+			INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity)
+			VALUES (?, ?, ?, ?, ?)
+		`, utils.GenerateID(), cartID, productID, variantID, quantity)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// Update quantity
+	_, err = tx.Exec(`
+		UPDATE cart_items SET quantity = quantity + ?
+		WHERE id = ?
+	`, quantity, existingItemID)
+	return err
+}
+
 // RemoveFromCart removes an item from cart
 func RemoveFromCart(c *gin.Context) {
 	userID, _ := c.Get("userID")
@@ -183,16 +599,11 @@ func RemoveFromCart(c *gin.Context) {
 
 	db := database.GetDB()
 
-	// Verify item belongs to user's cart
+	// Verify item belongs to user's cart; without a cart no item can
 	var cartID string
 	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success:   false,
-			Error:     "Cart not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+		notFound(c, "Item")
 		return
 	}
 
@@ -202,46 +613,221 @@ func RemoveFromCart(c *gin.Context) {
 			Success:   false,
 			Error:     "Failed to remove item",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success:   false,
-			Error:     "Item not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+		notFound(c, "Item")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Data:      gin.H{"message": "Item removed from cart"},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
-// ClearCart clears all items from cart
-func ClearCart(c *gin.Context) {
+// RemoveProductFromCart removes every line of a product, whatever its
+// variant, from the current user's cart
+func RemoveProductFromCart(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
+	productID := c.Query("product_id")
+	if productID == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "product_id is required",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
 	db := database.GetDB()
 
 	var cartID string
 	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+		notFound(c, "Cart")
+		return
+	}
+
+	result, err := db.Exec("DELETE FROM cart_items WHERE cart_id = ? AND product_id = ?", cartID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Error:     "Cart not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Error:     "Failed to remove items",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
+	removed, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id": productID,
+			"removed":    removed,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// cartCleanupBatchSize is how many carts RemoveProductFromCarts loads at a time
+const cartCleanupBatchSize = 100
+
+// RemoveProductFromCarts removes a product from every cart, e.g. once it is
+// no longer sold. Each cart is cleaned in its own transaction, together with
+// the notification to its owner when ?notify=true.
+func RemoveProductFromCarts(c *gin.Context) {
+	productID := c.Param("id")
+	notify := c.Query("notify") == "true"
+
+	db := database.GetDB()
+
+	var productName string
+	err := db.QueryRow("SELECT name FROM products WHERE id = ?", productID).Scan(&productName)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var removed, carts, notified int64
+	lastCartID := ""
+	for {
+		rows, err := db.Query(`
+			SELECT DISTINCT c.id, c.user_id
+			FROM cart_items ci JOIN carts c ON c.id = ci.cart_id
+			WHERE ci.product_id = ? AND c.id > ?
+			ORDER BY c.id
+			LIMIT ?
+		`, productID, lastCartID, cartCleanupBatchSize)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		type cartOwner struct{ cartID, userID string }
+		var batch []cartOwner
+		for rows.Next() {
+			var owner cartOwner
+			if err := rows.Scan(&owner.cartID, &owner.userID); err != nil {
+				continue
+			}
+			batch = append(batch, owner)
+		}
+		rows.Close()
+
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, owner := range batch {
+			n, err := removeProductFromUserCart(db, owner.cartID, owner.userID, productID, productName, notify)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Failed to remove items",
+					Code:      "INTERNAL_ERROR",
+					Details:   gin.H{"removed": removed, "carts": carts},
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			if n > 0 {
+				removed += n
+				carts++
+				if notify {
+					notified++
+				}
+			}
+		}
+		lastCartID = batch[len(batch)-1].cartID
+	}
+
+	if err := recordAudit(db, c, "cart_references_delete", "product", productID, gin.H{
+		"removed":  removed,
+		"carts":    carts,
+		"notified": notified,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to record audit log",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id": productID,
+			"removed":    removed,
+			"carts":      carts,
+			"notified":   notified,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// removeProductFromUserCart deletes a product's lines from one cart and,
+// when notify is set, tells its owner, returning how many lines were removed
+func removeProductFromUserCart(db *sql.DB, cartID, userID, productID, productName string, notify bool) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM cart_items WHERE cart_id = ? AND product_id = ?", cartID, productID)
+	if err != nil {
+		return 0, err
+	}
+	removed, _ := result.RowsAffected()
+
+	if removed > 0 && notify {
+		tmpl := notificationTemplates["cart_item_removed"]
+		err := NotifyMany(tx, []string{userID}, tmpl.Type, tmpl.Title, tmpl.Message,
+			map[string]interface{}{"ProductName": productName})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return removed, tx.Commit()
+}
+
+// ClearCart clears all items from cart
+func ClearCart(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+
+	var cartID string
+	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+	if err != nil {
+		notFound(c, "Cart")
+		return
+	}
 
 	_, err = db.Exec("DELETE FROM cart_items WHERE cart_id = ?", cartID)
 	if err != nil {
@@ -249,7 +835,7 @@ func ClearCart(c *gin.Context) {
 			Success:   false,
 			Error:     "Failed to clear cart",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -257,6 +843,117 @@ func ClearCart(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Data:      gin.H{"message": "Cart cleared"},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// parseAge parses an age such as "7d", "12h" or "90m". Days are accepted in
+// addition to Go durations.
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// ListAbandonedCarts lists carts whose items were last changed more than
+// older_than ago (default 7d) and whose owner hasn't ordered since, most
+// valuable first
+func ListAbandonedCarts(c *gin.Context) {
+	olderThan := 7 * 24 * time.Hour
+	if value := c.Query("older_than"); value != "" {
+		age, err := parseAge(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid older_than, expected e.g. 7d or 12h",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		olderThan = age
+	}
+	cutoff := time.Now().UTC().Add(-olderThan).Format(time.RFC3339)
+
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	abandoned := `
+		SELECT c.id AS cart_id, c.user_id, u.email, u.first_name, u.last_name,
+		       SUM(ci.quantity) AS units, SUM(ci.quantity * ` + effectiveVariantPrice("p", "v") + `) AS value,
+		       MAX(ci.updated_at) AS last_activity
+		FROM carts c
+		JOIN users u ON c.user_id = u.id
+		JOIN cart_items ci ON ci.cart_id = c.id
+		JOIN products p ON ci.product_id = p.id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
+		WHERE NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = c.user_id AND o.created_at >= ?)
+		GROUP BY c.id
+		HAVING MAX(ci.updated_at) < ?
+	`
+	args := []interface{}{cutoff, cutoff}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM ("+abandoned+")", args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query("SELECT * FROM ("+abandoned+") ORDER BY value DESC, last_activity LIMIT ? OFFSET ?",
+		append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	carts := []gin.H{}
+	for rows.Next() {
+		var cartID, userID, email, firstName, lastName, lastActivity string
+		var units int
+		var value models.Money
+		err := rows.Scan(&cartID, &userID, &email, &firstName, &lastName, &units, &value, &lastActivity)
+		if err != nil {
+			continue
+		}
+		carts = append(carts, gin.H{
+			"cart_id":       cartID,
+			"user_id":       userID,
+			"email":         email,
+			"first_name":    firstName,
+			"last_name":     lastName,
+			"units":         units,
+			"value":         value,
+			"last_activity": lastActivity,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, carts, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
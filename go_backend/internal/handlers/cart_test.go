@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetCartPricesVariants(t *testing.T) {
+	user := createTestUser(t, "customer")
+	productID := createTestProduct(t, 2000, 0)
+	variantID := createTestVariant(t, productID, "CART-VAR-"+productID, 500, 5)
+	plainID := createTestProduct(t, 2000, 5)
+	addTestCartItem(t, user, productID, &variantID, 2)
+	addTestCartItem(t, user, plainID, nil, 1)
+
+	res := serve(t, GetCart, http.MethodGet, "/cart", "/cart", user, "customer", nil)
+	expectStatus(t, res, http.StatusOK, "")
+
+	prices := map[string]float64{}
+	for _, item := range res.Data["items"].([]interface{}) {
+		item := item.(map[string]interface{})
+		prices[item["product_id"].(string)] = item["price"].(float64)
+	}
+	if prices[productID] != 25 || prices[plainID] != 20 {
+		t.Fatalf("prices = %v, want 25 for the variant and 20 for the plain product", prices)
+	}
+	if res.Data["total"] != 70.0 {
+		t.Fatalf("total = %v, want 70", res.Data["total"])
+	}
+}
+
+func TestAddToCartIgnoresRetries(t *testing.T) {
+	user := createTestUser(t, "customer")
+	productID := createTestProduct(t, 1000, 20)
+
+	add := func(quantity int, key string) testResponse {
+		return serve(t, AddToCart, http.MethodPost, "/cart/items", "/cart/items", user, "customer", map[string]interface{}{
+			"product_id":      productID,
+			"quantity":        quantity,
+			"idempotency_key": key,
+		})
+	}
+	quantity := func() int {
+		return queryInt(t, `
+			SELECT ci.quantity FROM cart_items ci JOIN carts c ON c.id = ci.cart_id
+			WHERE c.user_id = ? AND ci.product_id = ?
+		`, user, productID)
+	}
+
+	res := add(2, "add-1")
+	expectStatus(t, res, http.StatusOK, "")
+	if res.Data["duplicate"] != nil {
+		t.Fatalf("first add: duplicate = %v", res.Data["duplicate"])
+	}
+
+	// A retry of the same add is a no-op
+	res = add(2, "add-1")
+	expectStatus(t, res, http.StatusOK, "")
+	if res.Data["duplicate"] != true || quantity() != 2 {
+		t.Fatalf("retry: duplicate = %v, quantity = %d; want true, 2", res.Data["duplicate"], quantity())
+	}
+
+	// The same key for a different add is rejected
+	expectStatus(t, add(3, "add-1"), http.StatusConflict, "IDEMPOTENCY_KEY_REUSED")
+
+	// A new key, or no key, adds again
+	expectStatus(t, add(1, "add-2"), http.StatusOK, "")
+	expectStatus(t, add(1, ""), http.StatusOK, "")
+	if quantity() != 4 {
+		t.Fatalf("quantity = %d, want 4", quantity())
+	}
+
+	// Keys are forgotten after cartIdempotencyTTL
+	mustExec(t, "UPDATE cart_idempotency_keys SET created_at = ? WHERE user_id = ?",
+		time.Now().UTC().Add(-cartIdempotencyTTL-time.Minute).Format(time.RFC3339), user)
+	res = add(2, "add-1")
+	expectStatus(t, res, http.StatusOK, "")
+	if res.Data["duplicate"] != nil || quantity() != 6 {
+		t.Fatalf("after the TTL: duplicate = %v, quantity = %d; want a new add, 6", res.Data["duplicate"], quantity())
+	}
+}
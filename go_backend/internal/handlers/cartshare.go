@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// cartShareTTL is how long a shared cart can be claimed
+const cartShareTTL = 7 * 24 * time.Hour
+
+// sharedCartItem is an item of a shared cart, as it was when shared
+type sharedCartItem struct {
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id"`
+	Quantity  int     `json:"quantity"`
+}
+
+// ShareCart snapshots the current user's cart behind a token, e.g. to send
+// as a gift link. Another user claims it with ClaimCart before it expires;
+// later changes to the cart don't affect the share.
+func ShareCart(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+
+	rows, err := db.Query(`
+		SELECT ci.product_id, ci.variant_id, ci.quantity
+		FROM cart_items ci
+		JOIN carts c ON ci.cart_id = c.id
+		WHERE c.user_id = ?
+		ORDER BY ci.created_at, ci.id
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	items := []sharedCartItem{}
+	for rows.Next() {
+		var item sharedCartItem
+		if err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Cart is empty",
+			Code:      "EMPTY_CART",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Expired shares can't be claimed any more, so they are purged here
+	now := time.Now().UTC()
+	if _, err := db.Exec("DELETE FROM cart_shares WHERE expires_at <= ?", now.Format(time.RFC3339)); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to share cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	token := utils.GenerateVerificationToken()
+	expiresAt := now.Add(cartShareTTL).Format(time.RFC3339)
+	encoded, _ := json.Marshal(items)
+	_, err = db.Exec("INSERT INTO cart_shares (token, user_id, items, expires_at) VALUES (?, ?, ?, ?)",
+		token, userID, string(encoded), expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to share cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"token":      token,
+			"items":      len(items),
+			"expires_at": expiresAt,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ClaimCart copies the items of a shared cart into the current user's cart.
+// A share can be claimed once. Items are checked as in ValidateCart: only
+// available ones are added, the rest are reported with their status.
+func ClaimCart(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var sharedBy, encoded, expiresAt string
+	var claimedBy sql.NullString
+	err = tx.QueryRow("SELECT user_id, items, expires_at, claimed_by FROM cart_shares WHERE token = ?", req.Token).
+		Scan(&sharedBy, &encoded, &expiresAt, &claimedBy)
+	if err == sql.ErrNoRows {
+		notFound(c, "Shared cart")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	switch {
+	case sharedBy == userID:
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Cannot claim your own cart",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	case claimedBy.Valid:
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Shared cart was already claimed",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	case expiresAt <= time.Now().UTC().Format(time.RFC3339):
+		c.JSON(http.StatusGone, models.APIResponse{
+			Success:   false,
+			Error:     "Shared cart has expired",
+			Code:      "SHARE_EXPIRED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var items []sharedCartItem
+	if err := json.Unmarshal([]byte(encoded), &items); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to read shared cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var cartID string
+	err = tx.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+	if err == sql.ErrNoRows {
+		cartID = utils.GenerateID()
+		_, err = tx.Exec("INSERT INTO carts (id, user_id) VALUES (?, ?)", cartID, userID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	storeID := currentStoreID(c)
+	added, skipped := []gin.H{}, []gin.H{}
+	for _, item := range items {
+		status, err := sharedCartItemStatus(tx, storeID, item)
+		if err == nil && status == cartItemAvailable {
+			err = addCartItem(tx, cartID, item.ProductID, item.VariantID, item.Quantity)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to claim cart",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		entry := gin.H{"product_id": item.ProductID, "variant_id": item.VariantID, "quantity": item.Quantity}
+		if status == cartItemAvailable {
+			added = append(added, entry)
+		} else {
+			entry["status"] = status
+			skipped = append(skipped, entry)
+		}
+	}
+
+	// Guarded again here, as a concurrent claim may have passed the check above
+	result, err := tx.Exec("UPDATE cart_shares SET claimed_by = ?, claimed_at = ? WHERE token = ? AND claimed_by IS NULL",
+		userID, time.Now().UTC().Format(time.RFC3339), req.Token)
+	var claimed int64
+	if err == nil {
+		claimed, err = result.RowsAffected()
+	}
+	if err == nil && claimed == 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Shared cart was already claimed",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to claim cart",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"added":   added,
+			"skipped": skipped,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// sharedCartItemStatus checks a shared item against the current product
+// status and stock, returning one of the cart item availability statuses
+func sharedCartItemStatus(tx *sql.Tx, storeID string, item sharedCartItem) (string, error) {
+	var productStatus string
+	var productStock, variantStock int
+	var variantExists bool
+	err := tx.QueryRow(`
+		SELECT p.status, p.stock_quantity, v.id IS NOT NULL, COALESCE(v.stock_quantity, 0)
+		FROM products p
+		LEFT JOIN product_variants v ON v.id = ? AND v.product_id = p.id
+		WHERE p.id = ? AND p.store_id = ?
+	`, item.VariantID, item.ProductID, storeID).Scan(&productStatus, &productStock, &variantExists, &variantStock)
+	if err == sql.ErrNoRows {
+		return cartItemUnavailable, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if productStatus != "active" || (item.VariantID != nil && !variantExists) {
+		return cartItemUnavailable, nil
+	}
+
+	available := productStock
+	if item.VariantID != nil {
+		available = variantStock
+	}
+	switch {
+	case available <= 0:
+		return cartItemOutOfStock, nil
+	case available < item.Quantity:
+		return cartItemInsufficientStock, nil
+	}
+
+	// Claiming adds to a cart, so the product's reserve applies as in AddToCart
+	reserved, err := stockReserved(tx, item.ProductID, item.VariantID, item.Quantity)
+	if err != nil {
+		return "", err
+	}
+	if reserved {
+		return cartItemReserved, nil
+	}
+	return cartItemAvailable, nil
+}
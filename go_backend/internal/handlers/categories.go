@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ReparentCategories moves several categories under new parents at once. The
+// whole batch is checked against the category tree as it would be after every
+// move, and rejected if it would create a cycle anywhere.
+func ReparentCategories(c *gin.Context) {
+	var req struct {
+		Moves []struct {
+			CategoryID  string  `json:"category_id" binding:"required"`
+			NewParentID *string `json:"new_parent_id"`
+		} `json:"moves" binding:"required,min=1,max=500,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	parents, err := categoryParents(tx, storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Build the proposed tree, validating each move on its own first
+	proposed := make(map[string]string, len(parents))
+	for id, parentID := range parents {
+		proposed[id] = parentID
+	}
+	var fieldErrors []utils.FieldError
+	moved := map[string]bool{}
+	for i, move := range req.Moves {
+		field := fmt.Sprintf("moves[%d]", i)
+		newParentID := ""
+		if move.NewParentID != nil {
+			newParentID = *move.NewParentID
+		}
+
+		switch _, exists := parents[move.CategoryID]; {
+		case !exists:
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".category_id", Message: "category not found"})
+		case moved[move.CategoryID]:
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".category_id", Message: "category is moved more than once"})
+		case newParentID == move.CategoryID:
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".category_id", Message: "category cannot be its own parent"})
+		default:
+			if _, parentExists := parents[newParentID]; newParentID != "" && !parentExists {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".new_parent_id", Message: "category not found"})
+			}
+		}
+
+		moved[move.CategoryID] = true
+		proposed[move.CategoryID] = newParentID
+	}
+
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid category moves",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if cycle := findCategoryCycle(proposed); cycle != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Moves would create a category cycle",
+			Code:      "CATEGORY_CYCLE",
+			Details:   gin.H{"cycle": cycle},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	changes := []gin.H{}
+	for _, move := range req.Moves {
+		oldParentID, newParentID := parents[move.CategoryID], proposed[move.CategoryID]
+		if oldParentID == newParentID {
+			continue
+		}
+
+		_, err := tx.Exec("UPDATE categories SET parent_id = ? WHERE id = ? AND store_id = ?",
+			nullableString(newParentID), move.CategoryID, storeID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to move categories",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		change := gin.H{
+			"category_id":   move.CategoryID,
+			"old_parent_id": nullableString(oldParentID),
+			"new_parent_id": nullableString(newParentID),
+		}
+		if err := recordAudit(tx, c, "category_reparent", "category", move.CategoryID, change); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to move categories",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		changes = append(changes, change)
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"moved":   len(changes),
+			"changes": changes,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// categoryParents maps each of a store's categories to its parent's id, or
+// "" for top-level categories
+func categoryParents(tx *sql.Tx, storeID string) (map[string]string, error) {
+	rows, err := tx.Query("SELECT id, COALESCE(parent_id, '') FROM categories WHERE store_id = ?", storeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := map[string]string{}
+	for rows.Next() {
+		var id, parentID string
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, err
+		}
+		parents[id] = parentID
+	}
+	return parents, rows.Err()
+}
+
+// findCategoryCycle returns the ids along a cycle in a category tree given as
+// child to parent ids, or nil when it has none. Each category is walked
+// towards the root once; reaching a category already on the current walk
+// means the walk has looped.
+func findCategoryCycle(parents map[string]string) []string {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int, len(parents))
+
+	for start := range parents {
+		var path []string
+		id := start
+		for id != "" && state[id] == unvisited {
+			state[id] = onPath
+			path = append(path, id)
+			id = parents[id]
+		}
+
+		if id != "" && state[id] == onPath {
+			for i, pathID := range path {
+				if pathID == id {
+					return append(path[i:], id)
+				}
+			}
+		}
+
+		for _, pathID := range path {
+			state[pathID] = done
+		}
+	}
+
+	return nil
+}
+
+// nullableString maps "" to NULL
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// taxRate is the sales tax percentage applied to the discounted subtotal
+var taxRate float64
+
+// SetTaxRate sets the sales tax percentage charged at checkout
+func SetTaxRate(percent float64) {
+	if percent < 0 {
+		percent = 0
+	}
+	taxRate = percent
+}
+
+// freeShippingThreshold is the order amount, after discounts, from which
+// shipping is free; 0 disables free shipping
+var freeShippingThreshold models.Money
+
+// SetFreeShippingThreshold sets the amount from which shipping is free. 0
+// disables free shipping.
+func SetFreeShippingThreshold(threshold models.Money) error {
+	if threshold < 0 {
+		return fmt.Errorf("free shipping threshold must not be negative, got %s", threshold)
+	}
+	freeShippingThreshold = threshold
+	return nil
+}
+
+// freeShipping describes how an amount compares to the free shipping
+// threshold, or is nil when free shipping is disabled
+func freeShipping(amount models.Money) gin.H {
+	if freeShippingThreshold == 0 {
+		return nil
+	}
+	remaining := freeShippingThreshold - amount
+	if remaining < 0 {
+		remaining = 0
+	}
+	return gin.H{
+		"threshold": freeShippingThreshold,
+		"qualified": remaining == 0,
+		"remaining": remaining,
+	}
+}
+
+// checkoutRequest is the body accepted by both CreateOrder and PreviewCheckout
+type checkoutRequest struct {
+	ShippingAddressID string  `json:"shipping_address_id"`
+	ShippingMethodID  *string `json:"shipping_method_id"`
+	CouponCode        *string `json:"coupon_code"`
+	// Items optionally ships individual cart items to other addresses
+	Items []struct {
+		CartItemID        string `json:"cart_item_id" binding:"required"`
+		ShippingAddressID string `json:"shipping_address_id" binding:"required"`
+	} `json:"items" binding:"dive"`
+}
+
+// checkoutLine is a cart item priced for checkout
+type checkoutLine struct {
+	CartItemID        string
+	ShippingAddressID string
+	ProductID         string
+	VariantID         *string
+	Quantity          int
+	Price             models.Money
+	StockQuantity     int
+	Weight            float64
+}
+
+// checkout is the computed result of checking out a cart. CreateOrder
+// persists it and PreviewCheckout returns it, so both always agree.
+type checkout struct {
+	CartID           string
+	Lines            []checkoutLine
+	Shipments        []string
+	ShipmentCosts    map[string]models.Money
+	PrimaryAddressID string
+	CouponID         *string
+	Subtotal         models.Money
+	Discount         models.Money
+	Shipping         models.Money
+	Tax              models.Money
+	Total            models.Money
+	TotalWeight      float64
+	OutOfStock       []string
+	Unavailable      []string
+}
+
+// checkoutError is a client-facing reason a checkout cannot be computed
+type checkoutError struct {
+	status  int
+	message string
+	code    string
+}
+
+func (e *checkoutError) respond(c *gin.Context) {
+	c.JSON(e.status, models.APIResponse{
+		Success:   false,
+		Error:     e.message,
+		Code:      e.code,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+var checkoutDatabaseError = &checkoutError{http.StatusInternalServerError, "Database error", "INTERNAL_ERROR"}
+
+// computeCheckout validates the user's cart against the request and prices
+// it: subtotal, coupon discount, shipping, tax and total. It performs no
+// writes. Lines without enough stock are reported in OutOfStock, and lines
+// of products that are no longer sold (inactive or archived) in Unavailable,
+// rather than failing, so callers decide whether that is an error.
+func computeCheckout(db *sql.DB, userID string, req checkoutRequest) (*checkout, *checkoutError) {
+	co := &checkout{ShipmentCosts: map[string]models.Money{}}
+
+	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&co.CartID)
+	if err != nil {
+		return nil, &checkoutError{http.StatusNotFound, "Cart not found", "NOT_FOUND"}
+	}
+
+	// Lines with a variant of their product are priced with its modifier and
+	// stocked from it; a variant_id that doesn't match the product is ignored
+	rows, err := db.Query(`
+		SELECT ci.id, ci.product_id, v.id, ci.quantity, `+effectivePrice("p")+`, p.stock_quantity, p.status,
+		       COALESCE(v.price_modifier, 0), COALESCE(v.stock_quantity, 0),
+		       p.weight, p.length, p.width, p.height
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		LEFT JOIN product_variants v ON v.id = ci.variant_id AND v.product_id = ci.product_id
+		WHERE ci.cart_id = ?
+	`, co.CartID)
+	if err != nil {
+		return nil, checkoutDatabaseError
+	}
+	for rows.Next() {
+		var line checkoutLine
+		var priceModifier models.Money
+		var variantStock int
+		var status string
+		var weight, length, width, height *float64
+		err := rows.Scan(&line.CartItemID, &line.ProductID, &line.VariantID, &line.Quantity, &line.Price, &line.StockQuantity, &status,
+			&priceModifier, &variantStock, &weight, &length, &width, &height)
+		if err != nil {
+			continue
+		}
+		if line.VariantID != nil {
+			line.Price = variantPrice(line.Price, priceModifier)
+			line.StockQuantity = variantStock
+		}
+
+		if status != "active" {
+			co.Unavailable = append(co.Unavailable, line.ProductID)
+		} else if line.StockQuantity < line.Quantity {
+			co.OutOfStock = append(co.OutOfStock, line.ProductID)
+		}
+
+		line.Weight = utils.BillableWeight(weight, length, width, height) * float64(line.Quantity)
+		co.Lines = append(co.Lines, line)
+		co.Subtotal += line.Price * models.Money(line.Quantity)
+		co.TotalWeight += line.Weight
+	}
+	rows.Close()
+
+	if len(co.Lines) == 0 {
+		return nil, &checkoutError{http.StatusBadRequest, "Cart is empty", "EMPTY_CART"}
+	}
+
+	// Resolve the shipping address of every item, defaulting to the order-level one
+	itemAddresses := map[string]string{}
+	for _, item := range req.Items {
+		itemAddresses[item.CartItemID] = item.ShippingAddressID
+	}
+
+	seenAddresses := map[string]bool{}
+	for i := range co.Lines {
+		addressID, ok := itemAddresses[co.Lines[i].CartItemID]
+		if ok {
+			delete(itemAddresses, co.Lines[i].CartItemID)
+		} else {
+			addressID = req.ShippingAddressID
+		}
+
+		if addressID == "" {
+			return nil, &checkoutError{http.StatusBadRequest, "Shipping address required for every item", "VALIDATION_ERROR"}
+		}
+
+		co.Lines[i].ShippingAddressID = addressID
+		if !seenAddresses[addressID] {
+			seenAddresses[addressID] = true
+			co.Shipments = append(co.Shipments, addressID)
+		}
+	}
+
+	if len(itemAddresses) > 0 {
+		return nil, &checkoutError{http.StatusBadRequest, "Item not found in cart", "VALIDATION_ERROR"}
+	}
+
+	if req.ShippingAddressID != "" && !seenAddresses[req.ShippingAddressID] {
+		co.Shipments = append(co.Shipments, req.ShippingAddressID)
+	}
+
+	owned, err := userOwnsAddresses(db, userID, co.Shipments)
+	if err != nil {
+		return nil, checkoutDatabaseError
+	}
+	if !owned {
+		return nil, &checkoutError{http.StatusBadRequest, "Invalid shipping address", "INVALID_ADDRESS"}
+	}
+
+	// The order-level address is the primary destination
+	co.PrimaryAddressID = req.ShippingAddressID
+	if co.PrimaryAddressID == "" {
+		co.PrimaryAddressID = co.Shipments[0]
+	}
+
+	// Each shipment is charged for the weight sent to its address
+	if req.ShippingMethodID != nil {
+		var method models.ShippingMethod
+		err := db.QueryRow("SELECT base_cost, cost_per_kg FROM shipping_methods WHERE id = ? AND is_active = 1", *req.ShippingMethodID).
+			Scan(&method.BaseCost, &method.CostPerKg)
+		if err != nil {
+			return nil, &checkoutError{http.StatusBadRequest, "Invalid shipping method", "VALIDATION_ERROR"}
+		}
+
+		weights := map[string]float64{}
+		for _, line := range co.Lines {
+			weights[line.ShippingAddressID] += line.Weight
+		}
+		for _, addressID := range co.Shipments {
+			cost := utils.ShippingCost(method.BaseCost, method.CostPerKg, weights[addressID])
+			co.ShipmentCosts[addressID] = cost
+			co.Shipping += cost
+		}
+	}
+
+	if req.CouponCode != nil && *req.CouponCode != "" {
+		coupon, err := findUsableCoupon(db, *req.CouponCode)
+		if err == sql.ErrNoRows {
+			return nil, &checkoutError{http.StatusBadRequest, "Invalid or expired coupon", "INVALID_COUPON"}
+		}
+		if err != nil {
+			return nil, checkoutDatabaseError
+		}
+		if co.Subtotal < coupon.MinPurchaseAmount {
+			return nil, &checkoutError{http.StatusBadRequest, "Order does not meet the coupon's minimum purchase amount", "INVALID_COUPON"}
+		}
+
+		co.Discount = couponDiscount(coupon, co.Subtotal)
+		co.CouponID = &coupon.ID
+	}
+
+	// Shipping is waived once the discounted subtotal reaches the threshold
+	if freeShippingThreshold > 0 && co.Subtotal-co.Discount >= freeShippingThreshold {
+		for addressID := range co.ShipmentCosts {
+			co.ShipmentCosts[addressID] = 0
+		}
+		co.Shipping = 0
+	}
+
+	co.Tax = models.Money(math.Round(float64(co.Subtotal-co.Discount) * taxRate / 100))
+	co.Total = co.Subtotal - co.Discount + co.Shipping + co.Tax
+
+	return co, nil
+}
+
+// findUsableCoupon looks up a coupon by code that is active, unexpired and
+// has uses left, returning sql.ErrNoRows when there is none
+func findUsableCoupon(db *sql.DB, code string) (models.Coupon, error) {
+	coupon := models.Coupon{Code: code}
+	err := db.QueryRow(`
+		SELECT id, discount_type, discount_value, min_purchase_amount
+		FROM coupons
+		WHERE code = ? AND is_active = 1 AND expiry_date > ? AND (max_uses < 0 OR uses_count < max_uses)
+	`, code, time.Now().UTC().Format(time.RFC3339)).
+		Scan(&coupon.ID, &coupon.DiscountType, &coupon.DiscountValue, &coupon.MinPurchaseAmount)
+	return coupon, err
+}
+
+// couponDiscount is the amount a coupon takes off a purchase, which is never
+// more than the purchase itself
+func couponDiscount(coupon models.Coupon, amount models.Money) models.Money {
+	var discount models.Money
+	if coupon.DiscountType == "percentage" {
+		discount = models.Money(math.Round(float64(amount) * coupon.DiscountValue / 100))
+	} else {
+		discount = models.Money(math.Round(coupon.DiscountValue))
+	}
+	if discount > amount {
+		discount = amount
+	}
+	return discount
+}
+
+// breakdown is the client-facing summary of a checkout's totals
+func (co *checkout) breakdown() gin.H {
+	return gin.H{
+		"subtotal":      co.Subtotal,
+		"discount":      co.Discount,
+		"shipping":      co.Shipping,
+		"tax":           co.Tax,
+		"total":         co.Total,
+		"total_weight":  co.TotalWeight,
+		"free_shipping": freeShipping(co.Subtotal - co.Discount),
+		"currency":      currencyInfo(),
+	}
+}
+
+// PreviewCheckout computes what CreateOrder would charge for the current
+// cart without placing the order
+func PreviewCheckout(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req checkoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	co, checkoutErr := computeCheckout(database.GetDB(), userID.(string), req)
+	if checkoutErr != nil {
+		checkoutErr.respond(c)
+		return
+	}
+
+	items := []gin.H{}
+	for _, line := range co.Lines {
+		items = append(items, gin.H{
+			"cart_item_id":        line.CartItemID,
+			"product_id":          line.ProductID,
+			"variant_id":          line.VariantID,
+			"quantity":            line.Quantity,
+			"unit_price":          line.Price,
+			"total_price":         line.Price * models.Money(line.Quantity),
+			"shipping_address_id": line.ShippingAddressID,
+			"in_stock":            line.StockQuantity >= line.Quantity,
+		})
+	}
+
+	shipments := []gin.H{}
+	for _, addressID := range co.Shipments {
+		shipments = append(shipments, gin.H{
+			"shipping_address_id": addressID,
+			"cost":                co.ShipmentCosts[addressID],
+		})
+	}
+
+	warnings := []string{}
+	for _, productID := range co.OutOfStock {
+		warnings = append(warnings, "Insufficient stock for product "+productID)
+	}
+	for _, productID := range co.Unavailable {
+		warnings = append(warnings, "Product "+productID+" is no longer available")
+	}
+
+	data := co.breakdown()
+	data["items"] = items
+	data["shipments"] = shipments
+	data["warnings"] = warnings
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      data,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareProducts caps how many products are compared side by side
+const maxCompareProducts = 4
+
+// comparedProduct is one column of a product comparison. Rating is the
+// average approved review rating, null for products without reviews.
+type comparedProduct struct {
+	Product     models.Product `json:"product"`
+	Rating      *float64       `json:"rating"`
+	ReviewCount int            `json:"review_count"`
+}
+
+// comparedAttribute is one row of a product comparison: an attribute and its
+// value for each compared product, in the same order, null where a product
+// doesn't have it
+type comparedAttribute struct {
+	Name   string    `json:"name"`
+	Values []*string `json:"values"`
+}
+
+// CompareProducts returns active products side by side, in the order asked
+// for, with their attributes lined up: every attribute any of them has is a
+// row with a value per product.
+func CompareProducts(c *gin.Context) {
+	var req struct {
+		ProductIDs []string `json:"product_ids" binding:"required,min=2,max=4"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("product_ids must list 2 to %d products", maxCompareProducts),
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var productIDs []string
+	for _, id := range req.ProductIDs {
+		if !slices.Contains(productIDs, id) {
+			productIDs = append(productIDs, id)
+		}
+	}
+
+	args := []interface{}{currentStoreID(c)}
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.Repeat("?, ", len(productIDs)-1) + "?"
+
+	db := database.GetDB()
+
+	rows, err := db.Query(`
+		SELECT `+productColumns+`,
+		       (SELECT AVG(rating) FROM reviews r WHERE r.product_id = products.id AND r.is_approved = 1),
+		       (SELECT COUNT(*) FROM reviews r WHERE r.product_id = products.id AND r.is_approved = 1)
+		FROM products
+		WHERE store_id = ? AND status = 'active' AND id IN (`+placeholders+`)
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	found := map[string]comparedProduct{}
+	for rows.Next() {
+		var p comparedProduct
+		var rating sql.NullFloat64
+		if err := scanProduct(rows, &p.Product, &rating, &p.ReviewCount); err != nil {
+			continue
+		}
+		if rating.Valid {
+			p.Rating = &rating.Float64
+		}
+		found[p.Product.ID] = p
+	}
+	rows.Close()
+
+	products := make([]comparedProduct, 0, len(productIDs))
+	for _, id := range productIDs {
+		p, ok := found[id]
+		if !ok {
+			notFound(c, "Product")
+			return
+		}
+		products = append(products, p)
+	}
+
+	rows, err = db.Query(`
+		SELECT product_id, name, value FROM product_attributes
+		WHERE product_id IN (`+placeholders+`)
+		ORDER BY name
+	`, args[1:]...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	attributes := []comparedAttribute{}
+	for rows.Next() {
+		var productID, name, value string
+		if err := rows.Scan(&productID, &name, &value); err != nil {
+			continue
+		}
+		if len(attributes) == 0 || attributes[len(attributes)-1].Name != name {
+			attributes = append(attributes, comparedAttribute{Name: name, Values: make([]*string, len(productIDs))})
+		}
+		attributes[len(attributes)-1].Values[slices.Index(productIDs, productID)] = &value
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"products":   products,
+			"attributes": attributes,
+			"currency":   currencyInfo(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// couponPrefixPattern restricts generated coupon code prefixes to characters
+// that are safe to print and type
+var couponPrefixPattern = regexp.MustCompile(`^[A-Z0-9-]{0,20}$`)
+
+// couponCodeAttempts is how many random codes are tried for each coupon
+// before giving up, in case they collide with existing codes
+const couponCodeAttempts = 5
+
+// GenerateCoupons creates a batch of coupons sharing the same discount, each
+// with a unique random code
+func GenerateCoupons(c *gin.Context) {
+	var req struct {
+		Count             int          `json:"count" binding:"required,min=1,max=1000"`
+		Prefix            string       `json:"prefix"`
+		Length            int          `json:"length" binding:"omitempty,min=6,max=32"`
+		DiscountType      string       `json:"discount_type" binding:"required,oneof=percentage fixed_amount"`
+		DiscountValue     float64      `json:"discount_value" binding:"required,gt=0"`
+		MinPurchaseAmount models.Money `json:"min_purchase_amount" binding:"gte=0"`
+		MaxUses           *int         `json:"max_uses" binding:"omitempty,min=1"`
+		SingleUse         bool         `json:"single_use"`
+		ExpiryDate        time.Time    `json:"expiry_date" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	req.Prefix = strings.ToUpper(strings.TrimSpace(req.Prefix))
+	if !couponPrefixPattern.MatchString(req.Prefix) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "prefix must be at most 20 letters, digits or dashes",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if req.Length == 0 {
+		req.Length = 8
+	}
+
+	if req.DiscountType == "percentage" && req.DiscountValue > 100 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Percentage discount cannot exceed 100",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if !req.ExpiryDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "expiry_date must be in the future",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Fixed amounts are stored in cents
+	discountValue := req.DiscountValue
+	if req.DiscountType == "fixed_amount" {
+		discountValue = float64(models.MoneyFromFloat(req.DiscountValue))
+	}
+
+	maxUses := -1
+	if req.SingleUse {
+		maxUses = 1
+	} else if req.MaxUses != nil {
+		maxUses = *req.MaxUses
+	}
+
+	expiryDate := req.ExpiryDate.UTC().Format(time.RFC3339)
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	codes := make([]string, 0, req.Count)
+	for len(codes) < req.Count {
+		couponID := utils.GenerateID()
+		var code string
+		// A code that already exists is skipped by the UNIQUE constraint on
+		// coupons.code, and another one is drawn
+		for attempt := 0; attempt < couponCodeAttempts && code == ""; attempt++ {
+			candidate := utils.GenerateCouponCode(req.Prefix, req.Length)
+			result, err := tx.Exec(`
+				INSERT OR IGNORE INTO coupons (id, code, discount_type, discount_value, min_purchase_amount, max_uses, expiry_date)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+			`, couponID, candidate, req.DiscountType, discountValue, req.MinPurchaseAmount, maxUses, expiryDate)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Failed to create coupons",
+					Code:      "INTERNAL_ERROR",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected == 1 {
+				code = candidate
+			}
+		}
+
+		if code == "" {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Could not generate unique coupon codes, try a longer length",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		if err := recordAudit(tx, c, "coupon_generate", "coupon", couponID, gin.H{
+			"code":           code,
+			"discount_type":  req.DiscountType,
+			"discount_value": req.DiscountValue,
+			"max_uses":       maxUses,
+			"expiry_date":    expiryDate,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to create coupons",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		codes = append(codes, code)
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"codes":               codes,
+			"count":               len(codes),
+			"discount_type":       req.DiscountType,
+			"discount_value":      req.DiscountValue,
+			"min_purchase_amount": req.MinPurchaseAmount,
+			"max_uses":            maxUses,
+			"expiry_date":         expiryDate,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// emailLayout wraps a rendered notification in HTML. Both the subject and the
+// text body are escaped, so sample data can never inject markup.
+var emailLayout = htmltemplate.Must(htmltemplate.New("email").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body>
+<h1>{{.Subject}}</h1>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}</body>
+</html>
+`))
+
+// sampleDataKey is the shape of a template variable name
+var sampleDataKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,49}$`)
+
+// maxSampleValueLength caps each sample data string
+const maxSampleValueLength = 1000
+
+// PreviewEmail renders one of the application's notification templates with
+// sample data, returning its subject and text and HTML bodies without sending
+// anything. The current user is the recipient.
+func PreviewEmail(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Template string                 `json:"template" binding:"required"`
+		Data     map[string]interface{} `json:"data"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tmpl, ok := notificationTemplates[req.Template]
+	if !ok {
+		notFound(c, "Template")
+		return
+	}
+
+	vars, fieldErrors := sanitizeSampleData(req.Data)
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid sample data",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	recipients, err := loadRecipients(database.GetDB(), []string{userID.(string)})
+	if err != nil || len(recipients) == 0 {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	vars["Recipient"] = recipients[0]
+
+	subject, err1 := renderText(tmpl.Title, vars)
+	body, err2 := renderText(tmpl.Message, vars)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Template could not be rendered with this sample data",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var html bytes.Buffer
+	err = emailLayout.Execute(&html, map[string]interface{}{
+		"Subject":    subject,
+		"Paragraphs": strings.Split(body, "\n\n"),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to render email",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"template":  req.Template,
+			"subject":   subject,
+			"body_text": body,
+			"body_html": html.String(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// renderText executes a notification template the same way NotifyMany does
+func renderText(text string, vars map[string]interface{}) (string, error) {
+	tmpl, err := template.New("").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// sanitizeSampleData only lets through flat, bounded template variables:
+// strings without control characters, numbers and booleans. Whole numbers
+// become ints, as templates compare counts against integer literals.
+func sanitizeSampleData(data map[string]interface{}) (map[string]interface{}, []utils.FieldError) {
+	vars := map[string]interface{}{}
+	var errs []utils.FieldError
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := data[key]
+		field := "data." + key
+		if !sampleDataKey.MatchString(key) || key == "Recipient" {
+			errs = append(errs, utils.FieldError{Field: field, Message: "is not a valid variable name"})
+			continue
+		}
+
+		switch v := value.(type) {
+		case string:
+			if len(v) > maxSampleValueLength {
+				errs = append(errs, utils.FieldError{Field: field, Message: "is too long"})
+				continue
+			}
+			vars[key] = strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) && r != '\n' {
+					return -1
+				}
+				return r
+			}, v)
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+				vars[key] = int(v)
+			} else {
+				vars[key] = v
+			}
+		case bool:
+			vars[key] = v
+		default:
+			errs = append(errs, utils.FieldError{Field: field, Message: "must be a string, number or boolean"})
+		}
+	}
+
+	return vars, errs
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+)
+
+// priceFacetBounds split effective prices into the buckets of the price
+// facet: under 25, 25 to 50, 50 to 100, 100 to 200 and 200 or more
+var priceFacetBounds = []models.Money{2500, 5000, 10000, 20000}
+
+// ratingFacetMinimums are the "N stars & up" steps of the rating facet
+var ratingFacetMinimums = []int{4, 3, 2, 1}
+
+// productFacets holds the filter counts shown alongside a product search
+type productFacets struct {
+	Categories []categoryFacet `json:"categories"`
+	Prices     []priceFacet    `json:"prices"`
+	Ratings    []ratingFacet   `json:"ratings"`
+}
+
+// categoryFacet counts the matching products in a category
+type categoryFacet struct {
+	CategoryID string `json:"category_id"`
+	Name       string `json:"name"`
+	Count      int    `json:"count"`
+}
+
+// priceFacet counts the matching products priced from Min up to, but not
+// including, Max; the last bucket has no Max
+type priceFacet struct {
+	Min   models.Money  `json:"min"`
+	Max   *models.Money `json:"max"`
+	Count int           `json:"count"`
+}
+
+// ratingFacet counts the matching products whose average approved review
+// rating is at least MinRating
+type ratingFacet struct {
+	MinRating int `json:"min_rating"`
+	Count     int `json:"count"`
+}
+
+// searchProductFacets counts the products matching a search per category,
+// price bucket and rating. The counts follow the search term and other
+// filters in where, never the facet values themselves, so every option in a
+// facet stays visible with the count it would give.
+func searchProductFacets(db *sql.DB, where string, args []interface{}) (productFacets, error) {
+	facets := productFacets{
+		Categories: []categoryFacet{},
+		Prices:     make([]priceFacet, len(priceFacetBounds)+1),
+		Ratings:    make([]ratingFacet, len(ratingFacetMinimums)),
+	}
+
+	rows, err := db.Query(`
+		SELECT c.id, c.name, COUNT(*) FROM (SELECT category_id FROM products WHERE `+where+`) p
+		JOIN categories c ON c.id = p.category_id
+		GROUP BY c.id, c.name
+		ORDER BY COUNT(*) DESC, c.name
+	`, args...)
+	if err != nil {
+		return facets, err
+	}
+	for rows.Next() {
+		var f categoryFacet
+		if err := rows.Scan(&f.CategoryID, &f.Name, &f.Count); err != nil {
+			rows.Close()
+			return facets, err
+		}
+		facets.Categories = append(facets.Categories, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return facets, err
+	}
+
+	// Bucket i holds prices from bound i-1 up to bound i
+	var bucket strings.Builder
+	bucket.WriteString("CASE")
+	for i, bound := range priceFacetBounds {
+		bucket.WriteString(" WHEN price < " + strconv.FormatInt(int64(bound), 10) + " THEN " + strconv.Itoa(i))
+	}
+	bucket.WriteString(" ELSE " + strconv.Itoa(len(priceFacetBounds)) + " END")
+
+	for i := range facets.Prices {
+		if i > 0 {
+			facets.Prices[i].Min = priceFacetBounds[i-1]
+		}
+		if i < len(priceFacetBounds) {
+			facets.Prices[i].Max = &priceFacetBounds[i]
+		}
+	}
+	rows, err = db.Query(`
+		SELECT `+bucket.String()+` AS bucket, COUNT(*)
+		FROM (SELECT `+effectivePrice("products")+` AS price FROM products WHERE `+where+`)
+		GROUP BY bucket
+	`, args...)
+	if err != nil {
+		return facets, err
+	}
+	for rows.Next() {
+		var i, count int
+		if err := rows.Scan(&i, &count); err != nil {
+			rows.Close()
+			return facets, err
+		}
+		facets.Prices[i].Count = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return facets, err
+	}
+
+	columns := make([]string, len(ratingFacetMinimums))
+	counts := make([]interface{}, len(ratingFacetMinimums))
+	for i, minimum := range ratingFacetMinimums {
+		facets.Ratings[i].MinRating = minimum
+		columns[i] = "COUNT(CASE WHEN rating >= " + strconv.Itoa(minimum) + " THEN 1 END)"
+		counts[i] = &facets.Ratings[i].Count
+	}
+	err = db.QueryRow(`
+		SELECT `+strings.Join(columns, ", ")+` FROM (
+			SELECT (SELECT AVG(r.rating) FROM reviews r WHERE r.product_id = products.id AND r.is_approved = 1) AS rating
+			FROM products WHERE `+where+`
+		)
+	`, args...).Scan(counts...)
+	return facets, err
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/features"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// featureFlagName is the shape of a feature flag name, e.g. new_checkout
+var featureFlagName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ListFeatureFlags lists every feature flag
+func ListFeatureFlags(c *gin.Context) {
+	flags, err := features.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(flags, len(flags)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// UpdateFeatureFlag creates or updates a feature flag, taking effect at once
+func UpdateFeatureFlag(c *gin.Context) {
+	name := c.Param("name")
+	if !featureFlagName.MatchString(name) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Flag names are lowercase letters, digits and underscores",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var req struct {
+		Enabled           *bool  `json:"enabled" binding:"required"`
+		RolloutPercentage *int   `json:"rollout_percentage" binding:"omitempty,min=0,max=100"`
+		Description       string `json:"description" binding:"max=500"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	flag := models.FeatureFlag{
+		Name:              name,
+		Enabled:           *req.Enabled,
+		RolloutPercentage: 100,
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Omitted fields keep their current values
+	err = tx.QueryRow("SELECT description, rollout_percentage FROM feature_flags WHERE name = ?", name).
+		Scan(&flag.Description, &flag.RolloutPercentage)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if req.Description != "" {
+		flag.Description = req.Description
+	}
+	if req.RolloutPercentage != nil {
+		flag.RolloutPercentage = *req.RolloutPercentage
+	}
+
+	if err := features.Set(tx, flag); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update feature flag",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := recordAudit(tx, c, "feature_flag_update", "feature_flag", name, gin.H{
+		"enabled":            flag.Enabled,
+		"rollout_percentage": flag.RolloutPercentage,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update feature flag",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	features.Invalidate()
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"feature_flag": flag},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
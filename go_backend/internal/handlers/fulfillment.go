@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// FulfillOrderItems records quantities of an order's items as shipped. The
+// order is partially_shipped until every item is fulfilled, then shipped.
+func FulfillOrderItems(c *gin.Context) {
+	orderID := c.Param("id")
+
+	var req struct {
+		Items []struct {
+			OrderItemID string `json:"order_item_id" binding:"required"`
+			Quantity    int    `json:"quantity" binding:"required,gt=0"`
+		} `json:"items" binding:"required,min=1,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var status, buyerID string
+	err = tx.QueryRow("SELECT status, user_id FROM orders WHERE id = ? AND deleted_at IS NULL", orderID).Scan(&status, &buyerID)
+	if err == sql.ErrNoRows {
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if !canTransition(status, "shipped") {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Items of a " + status + " order cannot be fulfilled",
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	for _, item := range req.Items {
+		var quantity, fulfilled int
+		err := tx.QueryRow("SELECT quantity, fulfilled_quantity FROM order_items WHERE id = ? AND order_id = ?",
+			item.OrderItemID, orderID).Scan(&quantity, &fulfilled)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Item not found in order",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		if fulfilled+item.Quantity > quantity {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Cannot fulfill more than was ordered for item " + item.OrderItemID,
+				Code:      "FULFILLMENT_EXCEEDS_ORDERED",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		_, err = tx.Exec("UPDATE order_items SET fulfilled_quantity = fulfilled_quantity + ? WHERE id = ?", item.Quantity, item.OrderItemID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update order",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	var unfulfilled int
+	err = tx.QueryRow("SELECT COUNT(*) FROM order_items WHERE order_id = ? AND fulfilled_quantity < quantity", orderID).Scan(&unfulfilled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	newStatus := "shipped"
+	if unfulfilled > 0 {
+		newStatus = "partially_shipped"
+	}
+
+	if newStatus != status {
+		if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", newStatus, orderID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update order",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		if err := notifyOrderStatus(tx, orderID, buyerID, newStatus); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to notify order status",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err := recordAudit(tx, c, "order_fulfillment", "order", orderID, gin.H{
+		"items":  req.Items,
+		"status": gin.H{"old": status, "new": newStatus},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := tx.Query("SELECT id, quantity, fulfilled_quantity FROM order_items WHERE order_id = ?", orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	items := []gin.H{}
+	for rows.Next() {
+		var id string
+		var quantity, fulfilled int
+		if err := rows.Scan(&id, &quantity, &fulfilled); err != nil {
+			continue
+		}
+		items = append(items, gin.H{
+			"order_item_id":      id,
+			"quantity":           quantity,
+			"fulfilled_quantity": fulfilled,
+		})
+	}
+	rows.Close()
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"order_id":        orderID,
+			"status":          newStatus,
+			"previous_status": status,
+			"items":           items,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,7 +22,7 @@ func HealthCheck(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"database":  dbStatus,
 	})
 }
@@ -37,14 +38,22 @@ func APIStatus(c *gin.Context) {
 		dbStatus = "disconnected"
 	}
 
+	status := "operational"
+	if !database.Available() {
+		status = "degraded"
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"version":   "1.0.0",
-			"status":    "operational",
-			"database":  dbStatus,
-			"timestamp": time.Now().Format(time.RFC3339),
+			"version":          "1.0.0",
+			"status":           status,
+			"database":         dbStatus,
+			"database_breaker": database.Breaker(),
+			"slow_queries":     database.SlowQueries(),
+			"rate_limit":       middleware.RateLimitStats(),
+			"timestamp":        time.Now().UTC().Format(time.RFC3339),
 		},
-		"timestamp": time.Now().Format(time.RFC3339),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	})
 }
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recordStockChange adds an entry to the inventory history of a product, or
+// of one of its variants when variantID is set
+func recordStockChange(tx *sql.Tx, productID string, variantID *string, change int, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO inventory_history (id, product_id, variant_id, quantity_changed, reason)
+		VALUES (?, ?, ?, ?, ?)
+	`, utils.GenerateID(), productID, variantID, change, reason)
+	return err
+}
+
+// TransferVariantStock moves stock between two variants of the same product.
+// The product's own stock_quantity is the sum of its variants and is kept in
+// sync by triggers, so a transfer leaves it unchanged.
+func TransferVariantStock(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	var req struct {
+		FromVariantID string `json:"from_variant_id" binding:"required"`
+		ToVariantID   string `json:"to_variant_id" binding:"required"`
+		Quantity      int    `json:"quantity" binding:"required,gt=0"`
+		Reason        string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.FromVariantID == req.ToVariantID {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM product_variants WHERE product_id = ? AND id IN (?, ?)",
+		productID, req.FromVariantID, req.ToVariantID).Scan(&count)
+	if err != nil || count != 2 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Variant not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "variant_transfer"
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE product_variants SET stock_quantity = stock_quantity - ?
+		WHERE id = ? AND stock_quantity >= ?
+	`, req.Quantity, req.FromVariantID, req.Quantity)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to transfer stock",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Insufficient stock for variant",
+			Code:      "INSUFFICIENT_STOCK",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity + ? WHERE id = ?",
+		req.Quantity, req.ToVariantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to transfer stock",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Record both sides of the transfer
+	for _, entry := range []struct {
+		variantID string
+		change    int
+	}{
+		{req.FromVariantID, -req.Quantity},
+		{req.ToVariantID, req.Quantity},
+	} {
+		if err := recordStockChange(tx, productID, &entry.variantID, entry.change, reason); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to record inventory history",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id":      productID,
+			"from_variant_id": req.FromVariantID,
+			"to_variant_id":   req.ToVariantID,
+			"quantity":        req.Quantity,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// AdjustStock adds to or removes from the stock of a product, or of one of
+// its variants, and records the change in the inventory history with the
+// given reason, e.g. a recount or damaged goods. Products with variants hold
+// the sum of their variants' stock, so their stock is adjusted per variant.
+func AdjustStock(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	var req struct {
+		Change    int     `json:"change" binding:"required,ne=0"`
+		VariantID *string `json:"variant_id"`
+		Reason    string  `json:"reason" binding:"required,max=200"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var variantCount int
+	err = tx.QueryRow("SELECT COUNT(*) FROM product_variants WHERE product_id = ?", productID).Scan(&variantCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if (variantCount > 0) != (req.VariantID != nil) {
+		message := "variant_id is required for products with variants"
+		if variantCount == 0 {
+			message = "Product has no variants"
+		}
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     message,
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Stock can't go below zero
+	var result sql.Result
+	if req.VariantID != nil {
+		result, err = tx.Exec(`
+			UPDATE product_variants SET stock_quantity = stock_quantity + ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+			WHERE id = ? AND product_id = ? AND stock_quantity + ? >= 0
+		`, req.Change, *req.VariantID, productID, req.Change)
+	} else {
+		result, err = tx.Exec(`
+			UPDATE products SET stock_quantity = stock_quantity + ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+			WHERE id = ? AND stock_quantity + ? >= 0
+		`, req.Change, productID, req.Change)
+	}
+	var updated int64
+	if err == nil {
+		updated, err = result.RowsAffected()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to adjust stock",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if updated == 0 {
+		var exists int
+		if req.VariantID != nil {
+			tx.QueryRow("SELECT COUNT(*) FROM product_variants WHERE id = ? AND product_id = ?", *req.VariantID, productID).Scan(&exists)
+		}
+		if req.VariantID != nil && exists == 0 {
+			notFound(c, "Variant")
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Insufficient stock",
+			Code:      "INSUFFICIENT_STOCK",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var stock int
+	err = recordStockChange(tx, productID, req.VariantID, req.Change, reason)
+	if err == nil && req.Change > 0 {
+		err = notifyBackInStock(tx, productID)
+	}
+	if err == nil && req.VariantID != nil {
+		err = tx.QueryRow("SELECT stock_quantity FROM product_variants WHERE id = ?", *req.VariantID).Scan(&stock)
+	} else if err == nil {
+		err = tx.QueryRow("SELECT stock_quantity FROM products WHERE id = ?", productID).Scan(&stock)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to adjust stock",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id":     productID,
+			"variant_id":     req.VariantID,
+			"change":         req.Change,
+			"reason":         reason,
+			"stock_quantity": stock,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// GetInventoryHistory lists the stock changes of a product and its
+// variants, newest first, to reconcile stock discrepancies
+func GetInventoryHistory(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM inventory_history WHERE product_id = ?", productID).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, variant_id, quantity_changed, reason, created_at FROM inventory_history
+		WHERE product_id = ?
+		ORDER BY created_at DESC, rowid DESC
+		LIMIT ? OFFSET ?
+	`, productID, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	changes := []models.InventoryChange{}
+	for rows.Next() {
+		var change models.InventoryChange
+		if err := rows.Scan(&change.ID, &change.VariantID, &change.QuantityChanged, &change.Reason, database.UTCTime(&change.CreatedAt)); err != nil {
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, changes, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// GetProductStock returns a product's stock with a per-variant breakdown.
+// total is the sum of the variants' stock, or the product's own stock when it
+// has no variants; the product is purchasable when any of it is in stock.
+func GetProductStock(c *gin.Context) {
+	productID := c.Param("id")
+	db := database.GetDB()
+
+	var status string
+	var stock int
+	err := db.QueryRow("SELECT status, stock_quantity FROM products WHERE id = ? AND store_id = ?", productID, currentStoreID(c)).
+		Scan(&status, &stock)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, value, sku, stock_quantity
+		FROM product_variants WHERE product_id = ?
+		ORDER BY name, value
+	`, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	variants := []gin.H{}
+	variantTotal := 0
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.Name, &v.Value, &v.SKU, &v.StockQuantity); err != nil {
+			continue
+		}
+		variantTotal += v.StockQuantity
+		variants = append(variants, gin.H{
+			"variant_id":     v.ID,
+			"name":           v.Name,
+			"value":          v.Value,
+			"sku":            v.SKU,
+			"stock_quantity": v.StockQuantity,
+			"in_stock":       v.StockQuantity > 0,
+		})
+	}
+
+	total := stock
+	if len(variants) > 0 {
+		total = variantTotal
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id":     productID,
+			"stock_quantity": stock,
+			"total":          total,
+			"purchasable":    status == "active" && total > 0,
+			"variants":       variants,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
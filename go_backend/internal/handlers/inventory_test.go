@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+)
+
+func TestTransferVariantStock(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	productID := createTestProduct(t, 1000, 0)
+	from := createTestVariant(t, productID, "SKU-"+utils.GenerateID(), 0, 5)
+	to := createTestVariant(t, productID, "SKU-"+utils.GenerateID(), 0, 2)
+
+	transfer := func(quantity int) testResponse {
+		return serve(t, TransferVariantStock, http.MethodPost, "/products/:id/variants/transfer", "/products/"+productID+"/variants/transfer",
+			admin, "admin", map[string]interface{}{
+				"from_variant_id": from,
+				"to_variant_id":   to,
+				"quantity":        quantity,
+			})
+	}
+	expectStocks := func(fromStock, toStock int) {
+		t.Helper()
+		if got := queryInt(t, "SELECT stock_quantity FROM product_variants WHERE id = ?", from); got != fromStock {
+			t.Fatalf("source stock = %d, want %d", got, fromStock)
+		}
+		if got := queryInt(t, "SELECT stock_quantity FROM product_variants WHERE id = ?", to); got != toStock {
+			t.Fatalf("destination stock = %d, want %d", got, toStock)
+		}
+		if got := queryInt(t, "SELECT stock_quantity FROM products WHERE id = ?", productID); got != fromStock+toStock {
+			t.Fatalf("product stock = %d, want %d", got, fromStock+toStock)
+		}
+	}
+	historyRows := func() int {
+		return queryInt(t, "SELECT COUNT(*) FROM inventory_history WHERE product_id = ?", productID)
+	}
+
+	expectStocks(5, 2)
+	history := historyRows()
+
+	expectStatus(t, transfer(3), http.StatusOK, "")
+	expectStocks(2, 5)
+	if got := historyRows() - history; got != 2 {
+		t.Fatalf("inventory history rows added = %d, want 2", got)
+	}
+	if got := queryInt(t, "SELECT COUNT(*) FROM inventory_history WHERE product_id = ? AND variant_id = ? AND quantity_changed = -3", productID, from); got != 1 {
+		t.Fatalf("source history rows = %d, want 1", got)
+	}
+	if got := queryInt(t, "SELECT COUNT(*) FROM inventory_history WHERE product_id = ? AND variant_id = ? AND quantity_changed = 3", productID, to); got != 1 {
+		t.Fatalf("destination history rows = %d, want 1", got)
+	}
+
+	history = historyRows()
+	expectStatus(t, transfer(3), http.StatusBadRequest, "INSUFFICIENT_STOCK")
+	expectStocks(2, 5)
+	if got := historyRows(); got != history {
+		t.Fatalf("inventory history rows = %d after a refused transfer, want %d", got, history)
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	htmltemplate "html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxInvoicesPerArchive caps the orders in one invoice download
+const maxInvoicesPerArchive = 100
+
+// invoiceLayout renders an order as a standalone HTML invoice
+var invoiceLayout = htmltemplate.Must(htmltemplate.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Invoice {{.OrderID}}</title></head>
+<body>
+<h1>Invoice</h1>
+<p>Order {{.OrderID}}<br>Date: {{.Date}}<br>Status: {{.Status}}</p>
+<p>Billed to:<br>{{.CustomerName}}<br>{{.CustomerEmail}}</p>
+{{with .Address}}<p>Ship to:<br>{{.StreetAddress}}<br>{{.City}}, {{.State}} {{.PostalCode}}<br>{{.Country}}</p>
+{{end}}<table>
+<thead><tr><th>Item</th><th>Quantity</th><th>Unit price</th><th>Total</th></tr></thead>
+<tbody>
+{{range .Items}}<tr><td>{{.Name}}{{with .Variant}} ({{.}}){{end}}</td><td>{{.Quantity}}</td><td>{{.UnitPrice}}</td><td>{{.TotalPrice}}</td></tr>
+{{end}}</tbody>
+</table>
+<p>Subtotal: {{.Subtotal}} {{.Currency}}{{if .Charges}}<br>Shipping and taxes: {{.Charges}} {{.Currency}}{{end}}{{if .Adjustments}}<br>Discounts: -{{.Adjustments}} {{.Currency}}{{end}}<br><strong>Total: {{.Total}} {{.Currency}}</strong></p>
+</body>
+</html>
+`))
+
+// invoice is the data an invoice is rendered from
+type invoice struct {
+	OrderID       string
+	Date          string
+	Status        string
+	CustomerName  string
+	CustomerEmail string
+	Address       *models.Address
+	Items         []invoiceItem
+	Subtotal      models.Money
+	Charges       models.Money
+	Adjustments   models.Money
+	Total         models.Money
+	Currency      string
+}
+
+// invoiceItem is one line of an invoice
+type invoiceItem struct {
+	Name       string
+	Variant    string
+	Quantity   int
+	UnitPrice  models.Money
+	TotalPrice models.Money
+}
+
+// DownloadInvoices streams a zip archive with an HTML invoice for each of the
+// current user's orders placed in the from/to date range. Both bounds are
+// optional and take a date or an RFC 3339 timestamp; a to date includes that
+// whole day. Ranges with more than maxInvoicesPerArchive orders are rejected
+// so they can be downloaded in smaller pieces.
+func DownloadInvoices(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	conditions := "o.user_id = ? AND o.deleted_at IS NULL"
+	args := []interface{}{userID}
+	if value := c.Query("from"); value != "" {
+		t, err := parseReviewDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "from must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		conditions += " AND o.created_at >= ?"
+		args = append(args, t.UTC().Format(time.RFC3339))
+	}
+	if value := c.Query("to"); value != "" {
+		t, err := parseReviewDate(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "to must be an RFC 3339 timestamp or a YYYY-MM-DD date",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if len(value) == len("2006-01-02") {
+			t = t.AddDate(0, 0, 1)
+		}
+		conditions += " AND o.created_at < ?"
+		args = append(args, t.UTC().Format(time.RFC3339))
+	}
+
+	db := database.GetDB()
+
+	var customerName, customerEmail string
+	err := db.QueryRow("SELECT first_name || ' ' || last_name, email FROM users WHERE id = ?", userID).
+		Scan(&customerName, &customerEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT o.id, o.created_at, o.status, o.total_amount, o.adjustment_amount,
+			a.street_address, a.city, a.state, a.postal_code, a.country
+		FROM orders o
+		LEFT JOIN addresses a ON a.id = o.shipping_address_id
+		WHERE `+conditions+`
+		ORDER BY o.created_at, o.id
+		LIMIT ?
+	`, append(args, maxInvoicesPerArchive+1)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	invoices := []invoice{}
+	for rows.Next() {
+		inv := invoice{CustomerName: customerName, CustomerEmail: customerEmail, Currency: models.Currency()}
+		var street, city, state, postalCode, country sql.NullString
+		err := rows.Scan(&inv.OrderID, &inv.Date, &inv.Status, &inv.Total, &inv.Adjustments,
+			&street, &city, &state, &postalCode, &country)
+		if err != nil {
+			continue
+		}
+		if street.Valid {
+			inv.Address = &models.Address{
+				StreetAddress: street.String,
+				City:          city.String,
+				State:         state.String,
+				PostalCode:    postalCode.String,
+				Country:       country.String,
+			}
+		}
+		invoices = append(invoices, inv)
+	}
+	rows.Close()
+
+	if len(invoices) == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "No orders in this date range",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if len(invoices) > maxInvoicesPerArchive {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Too many orders in this date range, choose a shorter one",
+			Code:      "TOO_MANY_INVOICES",
+			Details:   gin.H{"max_invoices": maxInvoicesPerArchive},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// From here on the archive is written straight to the client, one
+	// invoice at a time; a failure can only cut the download short
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="invoices.zip"`)
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	for _, inv := range invoices {
+		if err := writeInvoice(archive, db, inv); err != nil {
+			log.Printf("Failed to write invoice for order %s: %v", inv.OrderID, err)
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Failed to finish invoice archive: %v", err)
+	}
+}
+
+// writeInvoice loads an order's items and adds its rendered invoice to the
+// archive
+func writeInvoice(archive *zip.Writer, db *sql.DB, inv invoice) error {
+	rows, err := db.Query(`
+		SELECT p.name, COALESCE(v.name || ': ' || v.value, ''), oi.quantity, oi.unit_price, oi.total_price
+		FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		LEFT JOIN product_variants v ON v.id = oi.variant_id
+		WHERE oi.order_id = ?
+		ORDER BY oi.created_at, oi.id
+	`, inv.OrderID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var item invoiceItem
+		if err := rows.Scan(&item.Name, &item.Variant, &item.Quantity, &item.UnitPrice, &item.TotalPrice); err != nil {
+			rows.Close()
+			return err
+		}
+		inv.Items = append(inv.Items, item)
+		inv.Subtotal += item.TotalPrice
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// The order total is what is left after discounts, so the rest of it
+	// is shipping and tax
+	inv.Charges = inv.Total + inv.Adjustments - inv.Subtotal
+
+	header := &zip.FileHeader{Name: "invoice-" + inv.OrderID + ".html", Method: zip.Deflate}
+	if t, err := time.Parse(time.RFC3339, inv.Date); err == nil {
+		inv.Date = t.Format("2006-01-02")
+		header.Modified = t
+	}
+
+	file, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	return invoiceLayout.Execute(file, inv)
+}
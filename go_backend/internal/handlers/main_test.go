@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain runs the handler tests against a fresh database in a temporary
+// directory, as InitDB opens ./ecommerce.db
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(io.Discard)
+
+	dir, err := os.MkdirTemp("", "handlers-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if _, err := database.InitDB(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testResponse is a decoded API response
+type testResponse struct {
+	Status  int
+	Success bool                   `json:"success"`
+	Data    map[string]interface{} `json:"data"`
+	Error   string                 `json:"error"`
+	Code    string                 `json:"code"`
+	Details json.RawMessage        `json:"details"`
+}
+
+// serve calls handler, registered on route, with a request to path. The
+// request is made as userID with role, as AuthMiddleware would set them, or
+// anonymously when userID is empty. body, when not nil, is sent as JSON.
+func serve(t *testing.T, handler gin.HandlerFunc, method, route, path, userID, role string, body interface{}) testResponse {
+	t.Helper()
+
+	r := gin.New()
+	r.Handle(method, route, func(c *gin.Context) {
+		if userID != "" {
+			c.Set("userID", userID)
+			c.Set("role", role)
+		}
+		c.Next()
+	}, handler)
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	res := testResponse{Status: w.Code}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s %s: invalid response %q: %v", method, path, w.Body.String(), err)
+	}
+	return res
+}
+
+// expectStatus fails the test when the response doesn't have the status and
+// error code, code being empty for successful responses
+func expectStatus(t *testing.T, res testResponse, status int, code string) {
+	t.Helper()
+	if res.Status != status || res.Code != code {
+		t.Fatalf("got %d %q (%s), want %d %q", res.Status, res.Code, res.Error, status, code)
+	}
+}
+
+// mustExec runs a statement on the test database
+func mustExec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := database.GetDB().Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// queryInt returns the integer a query selects
+func queryInt(t *testing.T, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.GetDB().QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// createTestUser adds a user with a role and returns its id
+func createTestUser(t *testing.T, role string) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, "INSERT INTO users (id, email, password_hash, first_name, last_name, role) VALUES (?, ?, 'x', 'Test', 'User', ?)",
+		id, id+"@example.com", role)
+	return id
+}
+
+// createTestVendor makes a user a vendor and returns the vendor id
+func createTestVendor(t *testing.T, userID string) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, "INSERT INTO vendors (id, user_id, business_name) VALUES (?, ?, 'Test Vendor')", id, userID)
+	return id
+}
+
+// createTestCategory adds a category of the default store and returns its id
+func createTestCategory(t *testing.T) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, "INSERT INTO categories (id, name) VALUES (?, ?)", id, "Category "+id)
+	return id
+}
+
+// createTestProduct adds an active product of the default store, in a
+// category of its own, and returns its id. price is in cents.
+func createTestProduct(t *testing.T, price int, stock int) string {
+	t.Helper()
+	categoryID := createTestCategory(t)
+	id := utils.GenerateID()
+	mustExec(t, `
+		INSERT INTO products (id, name, slug, description, price, category_id, stock_quantity, sku)
+		VALUES (?, 'Test Product', ?, 'A product', ?, ?, ?, ?)
+	`, id, "test-product-"+id, price, categoryID, stock, "SKU-"+id)
+	return id
+}
+
+// createTestVariant adds a variant to a product and returns its id. The
+// product's stock becomes the sum of its variants'.
+func createTestVariant(t *testing.T, productID, sku string, priceModifier, stock int) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, `
+		INSERT INTO product_variants (id, product_id, name, value, price_modifier, stock_quantity, sku)
+		VALUES (?, ?, 'Size', ?, ?, ?, ?)
+	`, id, productID, id, priceModifier, stock, sku)
+	return id
+}
+
+// createTestAddress adds an address for a user and returns its id
+func createTestAddress(t *testing.T, userID string) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, `
+		INSERT INTO addresses (id, user_id, street_address, city, state, postal_code, country)
+		VALUES (?, ?, '1 Main St', 'Springfield', 'IL', '62701', 'US')
+	`, id, userID)
+	return id
+}
+
+// createTestOrder adds a pending order for a user, shipped to a new address,
+// and returns its id. total is in cents.
+func createTestOrder(t *testing.T, userID string, total int) string {
+	t.Helper()
+	id := utils.GenerateID()
+	mustExec(t, "INSERT INTO orders (id, user_id, total_amount, shipping_address_id) VALUES (?, ?, ?, ?)",
+		id, userID, total, createTestAddress(t, userID))
+	return id
+}
+
+// addTestCartItem puts a quantity of a product, or of one of its variants,
+// in a user's cart
+func addTestCartItem(t *testing.T, userID, productID string, variantID *string, quantity int) string {
+	t.Helper()
+	var cartID string
+	err := database.GetDB().QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+	if err != nil {
+		cartID = utils.GenerateID()
+		mustExec(t, "INSERT INTO carts (id, user_id) VALUES (?, ?)", cartID, userID)
+	}
+	id := utils.GenerateID()
+	mustExec(t, "INSERT INTO cart_items (id, cart_id, product_id, variant_id, quantity) VALUES (?, ?, ?, ?, ?)",
+		id, cartID, productID, variantID, quantity)
+	return id
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// productMerge counts what merging one product into another changed
+type productMerge struct {
+	OrderItems        int64 `json:"order_items"`
+	CartItems         int64 `json:"cart_items"`
+	CartItemsCombined int64 `json:"cart_items_combined"`
+	Reviews           int64 `json:"reviews"`
+	VariantsMoved     int   `json:"variants_moved"`
+	VariantsMerged    int   `json:"variants_merged"`
+}
+
+// mergeVariant is the part of a variant that merging looks at
+type mergeVariant struct {
+	id, name, value string
+	stock           int
+}
+
+// MergeProducts merges a duplicate product into another product of the
+// current store. The source's order items, cart items, reviews and variants
+// are moved to the target, its stock is added to the target's and it is
+// archived. Variant SKUs are unique across the catalog, so moved variants keep
+// theirs; a source variant for an option the target already has (same name
+// and value) is folded into the target's variant instead, stock included.
+func MergeProducts(c *gin.Context) {
+	var req struct {
+		SourceProductID string `json:"source_product_id" binding:"required"`
+		TargetProductID string `json:"target_product_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.SourceProductID == req.TargetProductID {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Source and target must be different products",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var sourceStock, targetStock int
+	var targetStatus string
+	err = tx.QueryRow("SELECT stock_quantity FROM products WHERE id = ? AND store_id = ?",
+		req.SourceProductID, storeID).Scan(&sourceStock)
+	if err == nil {
+		err = tx.QueryRow("SELECT stock_quantity, status FROM products WHERE id = ? AND store_id = ?",
+			req.TargetProductID, storeID).Scan(&targetStock, &targetStatus)
+	}
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if targetStatus == "archived" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Cannot merge into an archived product",
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	sourceVariants, err := mergeVariants(tx, req.SourceProductID)
+	var targetVariants []mergeVariant
+	if err == nil {
+		targetVariants, err = mergeVariants(tx, req.TargetProductID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// A product with variants holds exactly its variants' stock, so stock of
+	// a product without variants has no variant to go to
+	if (len(sourceVariants) == 0 && len(targetVariants) > 0 && sourceStock > 0) ||
+		(len(targetVariants) == 0 && len(sourceVariants) > 0 && targetStock > 0) {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Only one of the products has variants, so the other's stock cannot be merged into them",
+			Code:      "STOCK_CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	merge, err := mergeProduct(tx, req.SourceProductID, req.TargetProductID, sourceVariants, targetVariants, sourceStock)
+	if err == nil {
+		err = tx.QueryRow("SELECT stock_quantity FROM products WHERE id = ?", req.TargetProductID).Scan(&targetStock)
+	}
+	if err == nil {
+		err = notifyBackInStock(tx, req.TargetProductID)
+	}
+	if err == nil {
+		err = recordAudit(tx, c, "product_merge", "product", req.TargetProductID, gin.H{
+			"source_product_id": req.SourceProductID,
+			"merged":            merge,
+			"stock_quantity":    targetStock,
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to merge products",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"source_product_id": req.SourceProductID,
+			"target_product_id": req.TargetProductID,
+			"merged":            merge,
+			"stock_quantity":    targetStock,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// mergeVariants lists a product's variants
+func mergeVariants(tx *sql.Tx, productID string) ([]mergeVariant, error) {
+	rows, err := tx.Query("SELECT id, name, value, stock_quantity FROM product_variants WHERE product_id = ?", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var variants []mergeVariant
+	for rows.Next() {
+		var v mergeVariant
+		if err := rows.Scan(&v.id, &v.name, &v.value, &v.stock); err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+// mergeProduct moves everything merging covers from the source product to
+// the target and archives the source
+func mergeProduct(tx *sql.Tx, sourceID, targetID string, sourceVariants, targetVariants []mergeVariant, sourceStock int) (productMerge, error) {
+	var merge productMerge
+
+	// Variants first, so cart and order items of folded variants point at
+	// the target's variant before they are combined and moved
+	options := map[[2]string]string{}
+	for _, v := range targetVariants {
+		options[[2]string{v.name, v.value}] = v.id
+	}
+	for _, v := range sourceVariants {
+		targetVariantID, ok := options[[2]string{v.name, v.value}]
+		if !ok {
+			if _, err := tx.Exec("UPDATE product_variants SET product_id = ? WHERE id = ?", targetID, v.id); err != nil {
+				return merge, err
+			}
+			merge.VariantsMoved++
+			continue
+		}
+
+		_, err := tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity + ? WHERE id = ?", v.stock, targetVariantID)
+		if err != nil {
+			return merge, err
+		}
+		for _, table := range []string{"cart_items", "order_items", "inventory_history"} {
+			if _, err := tx.Exec("UPDATE "+table+" SET variant_id = ? WHERE variant_id = ?", targetVariantID, v.id); err != nil {
+				return merge, err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM product_variants WHERE id = ?", v.id); err != nil {
+			return merge, err
+		}
+		merge.VariantsMerged++
+	}
+
+	// Without variants on either side the stock is summed directly; with
+	// them, the variant triggers keep both products' stock in step
+	if len(sourceVariants) == 0 && len(targetVariants) == 0 {
+		if _, err := tx.Exec("UPDATE products SET stock_quantity = stock_quantity + ? WHERE id = ?", sourceStock, targetID); err != nil {
+			return merge, err
+		}
+	}
+
+	// A cart holding both products for the same variant keeps one line
+	_, err := tx.Exec(`
+		UPDATE cart_items SET quantity = quantity + (
+			SELECT SUM(s.quantity) FROM cart_items s
+			WHERE s.cart_id = cart_items.cart_id AND s.product_id = ? AND s.variant_id IS cart_items.variant_id
+		)
+		WHERE product_id = ? AND EXISTS (
+			SELECT 1 FROM cart_items s
+			WHERE s.cart_id = cart_items.cart_id AND s.product_id = ? AND s.variant_id IS cart_items.variant_id
+		)
+	`, sourceID, targetID, sourceID)
+	if err != nil {
+		return merge, err
+	}
+	result, err := tx.Exec(`
+		DELETE FROM cart_items
+		WHERE product_id = ? AND EXISTS (
+			SELECT 1 FROM cart_items t
+			WHERE t.cart_id = cart_items.cart_id AND t.product_id = ? AND t.variant_id IS cart_items.variant_id
+		)
+	`, sourceID, targetID)
+	if err != nil {
+		return merge, err
+	}
+	merge.CartItemsCombined, _ = result.RowsAffected()
+
+	moves := []struct {
+		table string
+		count *int64
+	}{
+		{"cart_items", &merge.CartItems},
+		{"order_items", &merge.OrderItems},
+		{"reviews", &merge.Reviews},
+	}
+	for _, move := range moves {
+		result, err := tx.Exec("UPDATE "+move.table+" SET product_id = ? WHERE product_id = ?", targetID, sourceID)
+		if err != nil {
+			return merge, err
+		}
+		*move.count, _ = result.RowsAffected()
+	}
+
+	_, err = tx.Exec("UPDATE products SET status = 'archived', stock_quantity = 0 WHERE id = ?", sourceID)
+	return merge, err
+}
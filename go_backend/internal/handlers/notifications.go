@@ -0,0 +1,575 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// notificationTypes are the notification types that can be filtered on
+var notificationTypes = map[string]bool{
+	models.NotificationOrderStatus:       true,
+	models.NotificationOrderConfirmation: true,
+	models.NotificationSavedSearch:       true,
+	models.NotificationAnnouncement:      true,
+	models.NotificationCartUpdate:        true,
+	models.NotificationBackInStock:       true,
+}
+
+// notificationTemplate is the title and message of a notification sent by
+// the application, rendered by NotifyMany
+type notificationTemplate struct {
+	Type    string
+	Title   string
+	Message string
+}
+
+// notificationTemplates are the notifications the application sends, by
+// name. Keeping them here lets admins preview them with PreviewEmail.
+var notificationTemplates = map[string]notificationTemplate{
+	"order_confirmation": {
+		Type:    models.NotificationOrderConfirmation,
+		Title:   "Order {{.OrderID}} confirmed",
+		Message: "Hi {{.Recipient.FirstName}}, thanks for your order {{.OrderID}}. Your total is {{.Total}}.",
+	},
+	"order_status": {
+		Type:    models.NotificationOrderStatus,
+		Title:   "Your order is {{.Status}}",
+		Message: "Hi {{.Recipient.FirstName}}, your order {{.OrderID}} is now {{.Status}}.",
+	},
+	"vendor_order_status": {
+		Type:    models.NotificationOrderStatus,
+		Title:   "Order {{.OrderID}} is {{.Status}}",
+		Message: "Hi {{.Recipient.FirstName}}, an order containing your products ({{.OrderID}}) is now {{.Status}}.",
+	},
+	"cart_item_removed": {
+		Type:    models.NotificationCartUpdate,
+		Title:   "An item was removed from your cart",
+		Message: "Hi {{.Recipient.FirstName}}, {{.ProductName}} is no longer available and was removed from your cart.",
+	},
+	"back_in_stock": {
+		Type:    models.NotificationBackInStock,
+		Title:   "{{.ProductName}} is back in stock",
+		Message: "Hi {{.Recipient.FirstName}}, {{.ProductName}} is available again.",
+	},
+	"saved_search_alert": {
+		Type:    models.NotificationSavedSearch,
+		Title:   `New matches for "{{.Name}}"`,
+		Message: `{{.Count}} new product{{if ne .Count 1}}s{{end}} match your saved search "{{.Name}}", including {{.Examples}}.`,
+	},
+}
+
+// notificationBatchSize keeps each multi-row insert well under SQLite's
+// bound parameter limit
+const notificationBatchSize = 100
+
+// queryExecer is satisfied by both *sql.DB and *sql.Tx
+type queryExecer interface {
+	execer
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// notificationRecipient is available to templates as .Recipient
+type notificationRecipient struct {
+	ID        string
+	FirstName string
+	LastName  string
+	Email     string
+}
+
+// NotifyMany renders a notification for each user and inserts them in
+// batches. title and message are text/template strings executed with data
+// plus a .Recipient entry describing the user. Unknown and duplicate user ids
+// are skipped.
+func NotifyMany(qx queryExecer, userIDs []string, notificationType, title, message string, data map[string]interface{}) error {
+	titleTmpl, err := template.New("title").Option("missingkey=zero").Parse(title)
+	if err != nil {
+		return fmt.Errorf("invalid notification title template: %w", err)
+	}
+	messageTmpl, err := template.New("message").Option("missingkey=zero").Parse(message)
+	if err != nil {
+		return fmt.Errorf("invalid notification message template: %w", err)
+	}
+
+	recipients, err := loadRecipients(qx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(recipients); start += notificationBatchSize {
+		end := start + notificationBatchSize
+		if end > len(recipients) {
+			end = len(recipients)
+		}
+
+		var placeholders []string
+		var args []interface{}
+		for _, recipient := range recipients[start:end] {
+			vars := map[string]interface{}{}
+			for k, v := range data {
+				vars[k] = v
+			}
+			vars["Recipient"] = recipient
+
+			var renderedTitle, renderedMessage bytes.Buffer
+			if err := titleTmpl.Execute(&renderedTitle, vars); err != nil {
+				return fmt.Errorf("rendering notification title: %w", err)
+			}
+			if err := messageTmpl.Execute(&renderedMessage, vars); err != nil {
+				return fmt.Errorf("rendering notification message: %w", err)
+			}
+
+			placeholders = append(placeholders, "(?, ?, ?, ?, ?)")
+			args = append(args, utils.GenerateID(), recipient.ID, notificationType, renderedTitle.String(), renderedMessage.String())
+		}
+
+		if _, err := qx.Exec("INSERT INTO notifications (id, user_id, type, title, message) VALUES "+strings.Join(placeholders, ", "), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRecipients looks up the users to notify, in the order given
+func loadRecipients(qx queryExecer, userIDs []string) ([]notificationRecipient, error) {
+	seen := map[string]bool{}
+	var ids []string
+	for _, id := range userIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	found := map[string]notificationRecipient{}
+	for start := 0; start < len(ids); start += notificationBatchSize {
+		end := start + notificationBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		args := make([]interface{}, 0, end-start)
+		for _, id := range ids[start:end] {
+			args = append(args, id)
+		}
+
+		rows, err := qx.Query("SELECT id, first_name, last_name, email FROM users WHERE id IN (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var r notificationRecipient
+			if err := rows.Scan(&r.ID, &r.FirstName, &r.LastName, &r.Email); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			found[r.ID] = r
+		}
+		rows.Close()
+	}
+
+	recipients := make([]notificationRecipient, 0, len(found))
+	for _, id := range ids {
+		if r, ok := found[id]; ok {
+			recipients = append(recipients, r)
+		}
+	}
+	return recipients, nil
+}
+
+// broadcastSyncLimit is the largest segment a broadcast notifies within the
+// request; larger segments are notified in the background
+const broadcastSyncLimit = 1000
+
+// BroadcastNotification sends an announcement to every active user in a
+// segment, optionally narrowed to a role and to buyers of a product. title and
+// message are templates with the same .Recipient data as NotifyMany.
+func BroadcastNotification(c *gin.Context) {
+	var req struct {
+		Segment struct {
+			Role               string `json:"role" binding:"omitempty,oneof=admin customer vendor"`
+			PurchasedProductID string `json:"purchased_product_id"`
+		} `json:"segment"`
+		Title   string `json:"title" binding:"required,max=200"`
+		Message string `json:"message" binding:"required,max=2000"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Check the templates now, as large segments are rendered after responding
+	for _, text := range []string{req.Title, req.Message} {
+		if _, err := template.New("").Parse(text); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid template: " + err.Error(),
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	conditions := []string{"is_active = 1"}
+	var args []interface{}
+	if req.Segment.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, req.Segment.Role)
+	}
+	if req.Segment.PurchasedProductID != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM orders o JOIN order_items oi ON oi.order_id = o.id
+			WHERE o.user_id = users.id AND oi.product_id = ? AND o.status != 'cancelled' AND o.deleted_at IS NULL
+		)`)
+		args = append(args, req.Segment.PurchasedProductID)
+	}
+
+	db := database.GetDB()
+
+	rows, err := db.Query("SELECT id FROM users WHERE "+strings.Join(conditions, " AND ")+" ORDER BY created_at", args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	broadcastID := utils.GenerateID()
+	background := len(userIDs) > broadcastSyncLimit
+
+	if !background {
+		if err := NotifyMany(tx, userIDs, models.NotificationAnnouncement, req.Title, req.Message, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to send notifications",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err := recordAudit(tx, c, "notification_broadcast", "broadcast", broadcastID, gin.H{
+		"segment":    req.Segment,
+		"title":      req.Title,
+		"message":    req.Message,
+		"recipients": len(userIDs),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to send notifications",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	status := http.StatusCreated
+	state := "sent"
+	if background {
+		go func() {
+			if err := NotifyMany(db, userIDs, models.NotificationAnnouncement, req.Title, req.Message, nil); err != nil {
+				log.Printf("Broadcast %s: %v", broadcastID, err)
+			}
+		}()
+		status = http.StatusAccepted
+		state = "queued"
+	}
+
+	c.JSON(status, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"broadcast_id": broadcastID,
+			"recipients":   len(userIDs),
+			"status":       state,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ListNotifications lists the current user's notifications, newest first,
+// optionally filtered by type and a from/to creation date range
+func ListNotifications(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	params, ok := bindListParams(c, utils.ListSpec{
+		Filters: map[string]utils.Filter{
+			"type": {Choices: slices.Sorted(maps.Keys(notificationTypes))},
+		},
+	})
+	if !ok {
+		return
+	}
+
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if notificationType := params.String("type"); notificationType != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, notificationType)
+	}
+
+	for _, bound := range []struct {
+		param    string
+		operator string
+	}{{"from", ">="}, {"to", "<"}} {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		timestamp, err := parseDateParam(value, bound.param == "to")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid " + bound.param + " date, expected YYYY-MM-DD or RFC 3339",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		conditions = append(conditions, "created_at "+bound.operator+" ?")
+		args = append(args, timestamp)
+	}
+
+	where := strings.Join(conditions, " AND ")
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notifications WHERE "+where, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, user_id, type, title, message, is_read, created_at, updated_at
+		FROM notifications WHERE `+where+`
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var n models.Notification
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Message, &n.IsRead, database.UTCTime(&n.CreatedAt), database.UTCTime(&n.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, notifications, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteNotification dismisses one of the current user's notifications
+func DeleteNotification(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	notificationID := c.Param("id")
+
+	db := database.GetDB()
+	result, err := db.Exec("DELETE FROM notifications WHERE id = ? AND user_id = ?", notificationID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete notification",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Notification not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Notification deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// maxMarkReadIDs caps the notifications one mark-read request can name
+const maxMarkReadIDs = 100
+
+// MarkNotificationsRead marks the listed notifications read, e.g. the ones
+// the user just viewed, and returns how many are still unread. Ids of
+// notifications that don't exist or belong to someone else are ignored.
+func MarkNotificationsRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		IDs []string `json:"ids" binding:"required,min=1,max=100"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     fmt.Sprintf("ids must list 1 to %d notifications", maxMarkReadIDs),
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	args := []interface{}{userID}
+	for _, id := range req.IDs {
+		args = append(args, id)
+	}
+
+	db := database.GetDB()
+	result, err := db.Exec(`
+		UPDATE notifications SET is_read = 1
+		WHERE user_id = ? AND is_read = 0 AND id IN (?`+strings.Repeat(", ?", len(req.IDs)-1)+`)
+	`, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to mark notifications read",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var unread int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notifications WHERE user_id = ? AND is_read = 0", userID).Scan(&unread); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	updated, _ := result.RowsAffected()
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"updated":      updated,
+			"unread_count": unread,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ClearReadNotifications deletes all of the current user's read notifications
+func ClearReadNotifications(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+	result, err := db.Exec("DELETE FROM notifications WHERE user_id = ? AND is_read = 1", userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to clear notifications",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	deleted, _ := result.RowsAffected()
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"deleted": deleted},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// parseDateParam parses a YYYY-MM-DD or RFC 3339 query value into the UTC
+// format timestamps are stored in. A bare date used as an exclusive upper
+// bound is moved to the following day so the whole day is included.
+func parseDateParam(value string, upperBound bool) (string, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", err
+	}
+	if upperBound {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.Format(time.RFC3339), nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+)
+
+func TestNotifyManyInsertsInBatches(t *testing.T) {
+	var userIDs []string
+	for i := 0; i < notificationBatchSize+5; i++ {
+		userIDs = append(userIDs, createTestUser(t, "customer"))
+	}
+	// Duplicates and unknown users are skipped
+	recipients := append(userIDs, userIDs[0], "no-such-user")
+
+	db := database.GetDB()
+	notificationType := "batch_test_" + utils.GenerateID()
+	err := NotifyMany(db, recipients, notificationType, "Order {{.OrderID}} shipped",
+		"Hi {{.Recipient.FirstName}}, order {{.OrderID}} is on its way.", map[string]interface{}{"OrderID": "ord-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := queryInt(t, "SELECT COUNT(*) FROM notifications WHERE type = ?", notificationType); n != len(userIDs) {
+		t.Fatalf("%d notifications, want %d", n, len(userIDs))
+	}
+	if n := queryInt(t, "SELECT COUNT(DISTINCT user_id) FROM notifications WHERE type = ?", notificationType); n != len(userIDs) {
+		t.Fatalf("%d users notified, want %d", n, len(userIDs))
+	}
+
+	var title, message string
+	err = db.QueryRow("SELECT title, message FROM notifications WHERE type = ? AND user_id = ?", notificationType, userIDs[len(userIDs)-1]).
+		Scan(&title, &message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "Order ord-1 shipped" || message != "Hi Test, order ord-1 is on its way." {
+		t.Fatalf("got %q / %q", title, message)
+	}
+}
+
+func TestNotifyManyRejectsInvalidTemplates(t *testing.T) {
+	userID := createTestUser(t, "customer")
+	db := database.GetDB()
+
+	if err := NotifyMany(db, []string{userID}, "invalid_test", "{{.Broken", "message", nil); err == nil {
+		t.Error("expected an error for an invalid title")
+	}
+	if err := NotifyMany(db, []string{userID}, "invalid_test", "title", "{{end}}", nil); err == nil {
+		t.Error("expected an error for an invalid message")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM notifications WHERE type = 'invalid_test'"); n != 0 {
+		t.Errorf("%d notifications inserted, want none", n)
+	}
+}
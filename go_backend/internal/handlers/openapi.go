@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Request bodies documented in the spec. The handlers bind anonymous structs,
+// so these mirror their fields and binding tags.
+type (
+	createProductBody struct {
+		Name           string        `json:"name" binding:"required"`
+		Description    string        `json:"description" binding:"required"`
+		Price          models.Money  `json:"price" binding:"required,gt=0"`
+		CompareAtPrice *models.Money `json:"compare_at_price"`
+		CategoryID     string        `json:"category_id" binding:"required"`
+		SKU            string        `json:"sku" binding:"required"`
+		Stock          int           `json:"stock_quantity"`
+		Weight         *float64      `json:"weight" binding:"omitempty,gte=0"`
+		Length         *float64      `json:"length" binding:"omitempty,gte=0"`
+		Width          *float64      `json:"width" binding:"omitempty,gte=0"`
+		Height         *float64      `json:"height" binding:"omitempty,gte=0"`
+	}
+
+	addToCartBody struct {
+		ProductID string  `json:"product_id" binding:"required"`
+		VariantID *string `json:"variant_id"`
+		Quantity  int     `json:"quantity" binding:"required,gt=0"`
+	}
+)
+
+// openAPISchemas are the named component schemas, derived from the models
+var openAPISchemas = map[string]interface{}{
+	"User":             models.User{},
+	"Product":          models.Product{},
+	"ProductVariant":   models.ProductVariant{},
+	"ProductAttribute": models.ProductAttribute{},
+	"ProductQuestion":  models.ProductQuestion{},
+	"ProductAnswer":    models.ProductAnswer{},
+	"Category":         models.Category{},
+	"Order":            models.Order{},
+	"OrderItem":        models.OrderItem{},
+	"OrderShipping":    models.OrderShipping{},
+	"Pagination":       models.PaginationResponse{},
+	"RegisterRequest":  models.RegisterRequest{},
+	"LoginRequest":     models.LoginRequest{},
+	"CreateProduct":    createProductBody{},
+	"AddToCart":        addToCartBody{},
+	"Checkout":         checkoutRequest{},
+	"ErrorResponse":    models.APIResponse{},
+	"AuthTokenPayload": struct {
+		Token string      `json:"token" binding:"required"`
+		User  models.User `json:"user" binding:"required"`
+	}{},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  gin.H
+)
+
+// OpenAPISpec serves the OpenAPI 3 description of the API
+func OpenAPISpec(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = buildOpenAPISpec()
+	})
+	c.JSON(http.StatusOK, openAPIDoc)
+}
+
+// SwaggerUI serves a Swagger UI page for /openapi.json
+func SwaggerUI(c *gin.Context) {
+	c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; style-src 'self' https://unpkg.com; img-src 'self' data:")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>E-Commerce API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });</script>
+</body>
+</html>
+`
+
+func buildOpenAPISpec() gin.H {
+	schemas := gin.H{}
+	for name, model := range openAPISchemas {
+		schemas[name] = schemaForType(reflect.TypeOf(model))
+	}
+
+	return gin.H{
+		"openapi": "3.0.3",
+		"info": gin.H{
+			"title":   "E-Commerce Backend API",
+			"version": "1.0.0",
+		},
+		"servers": []gin.H{{"url": "/api/v1"}},
+		"components": gin.H{
+			"schemas": schemas,
+			"securitySchemes": gin.H{
+				"bearerAuth": gin.H{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"paths": gin.H{
+			"/auth/register": gin.H{
+				"post": operation("Register a new customer", "auth", false, ref("RegisterRequest"), http.StatusCreated, ref("AuthTokenPayload")),
+			},
+			"/auth/login": gin.H{
+				"post": operation("Log in with email and password", "auth", false, ref("LoginRequest"), http.StatusOK, ref("AuthTokenPayload")),
+			},
+			"/auth/logout": gin.H{
+				"post": operation("Log out", "auth", false, nil, http.StatusOK, nil),
+			},
+			"/auth/me": gin.H{
+				"get": operation("Get the current user", "auth", true, nil, http.StatusOK, object(gin.H{"user": ref("User")})),
+			},
+			"/products": gin.H{
+				"get": withParameters(
+					operation("List active products in the current store", "products", false, nil, http.StatusOK, object(gin.H{
+						"data":       arrayOf(ref("Product")),
+						"pagination": ref("Pagination"),
+					})),
+					queryParam("page", "integer"), queryParam("limit", "integer"),
+					queryParam("search", "string"), queryParam("on_sale", "boolean"),
+					queryParam("tags", "string"), queryParam("tag_match", "string"),
+				),
+				"post": operation("Create a product", "products", true, ref("CreateProduct"), http.StatusCreated, object(gin.H{"product": ref("Product")})),
+			},
+			"/products/{id}": gin.H{
+				"get": withParameters(
+					operation("Get a product with its variants and attributes", "products", false, nil, http.StatusOK, object(gin.H{
+						"product":    ref("Product"),
+						"variants":   arrayOf(ref("ProductVariant")),
+						"attributes": arrayOf(ref("ProductAttribute")),
+						"tags":       arrayOf(gin.H{"type": "string"}),
+					})),
+					pathParam("id"),
+				),
+			},
+			"/products/{id}/questions": gin.H{
+				"get": withParameters(
+					operation("List a product's questions with their answers", "products", false, nil, http.StatusOK, object(gin.H{
+						"data":       arrayOf(ref("ProductQuestion")),
+						"pagination": ref("Pagination"),
+					})),
+					pathParam("id"), queryParam("page", "integer"), queryParam("limit", "integer"),
+					queryParam("unanswered", "boolean"),
+				),
+				"post": withParameters(
+					operation("Ask a question about a product", "products", true, object(gin.H{"question": gin.H{"type": "string"}}),
+						http.StatusCreated, object(gin.H{"question": ref("ProductQuestion")})),
+					pathParam("id"),
+				),
+			},
+			"/cart": gin.H{
+				"get": operation("Get the current user's cart", "cart", true, nil, http.StatusOK, object(gin.H{
+					"cart_id": gin.H{"type": "string"},
+					"items":   arrayOf(gin.H{"type": "object"}),
+					"total":   gin.H{"type": "number"},
+				})),
+				"delete": operation("Remove every item from the cart", "cart", true, nil, http.StatusOK, nil),
+			},
+			"/cart/items": gin.H{
+				"post": operation("Add an item to the cart", "cart", true, ref("AddToCart"), http.StatusCreated, nil),
+			},
+			"/cart/items/{itemId}": gin.H{
+				"delete": withParameters(
+					operation("Remove an item from the cart", "cart", true, nil, http.StatusOK, nil),
+					pathParam("itemId"),
+				),
+			},
+			"/orders": gin.H{
+				"get": withParameters(
+					operation("List the current user's orders", "orders", true, nil, http.StatusOK, object(gin.H{
+						"data":       arrayOf(ref("Order")),
+						"pagination": ref("Pagination"),
+					})),
+					queryParam("page", "integer"), queryParam("limit", "integer"),
+				),
+				"post": operation("Create an order from the cart", "orders", true, ref("Checkout"), http.StatusCreated, object(gin.H{
+					"order_id":     gin.H{"type": "string"},
+					"subtotal":     gin.H{"type": "number"},
+					"discount":     gin.H{"type": "number"},
+					"shipping":     gin.H{"type": "number"},
+					"tax":          gin.H{"type": "number"},
+					"total":        gin.H{"type": "number"},
+					"total_amount": gin.H{"type": "number"},
+					"total_weight": gin.H{"type": "number"},
+					"status":       gin.H{"type": "string"},
+					"shipments":    arrayOf(gin.H{"type": "object"}),
+				})),
+			},
+			"/checkout/preview": gin.H{
+				"post": operation("Preview the totals CreateOrder would charge, without placing the order", "orders", true, ref("Checkout"), http.StatusOK, object(gin.H{
+					"subtotal":     gin.H{"type": "number"},
+					"discount":     gin.H{"type": "number"},
+					"shipping":     gin.H{"type": "number"},
+					"tax":          gin.H{"type": "number"},
+					"total":        gin.H{"type": "number"},
+					"total_weight": gin.H{"type": "number"},
+					"items":        arrayOf(gin.H{"type": "object"}),
+					"shipments":    arrayOf(gin.H{"type": "object"}),
+					"warnings":     arrayOf(gin.H{"type": "string"}),
+				})),
+			},
+			"/orders/{id}": gin.H{
+				"get": withParameters(
+					operation("Get an order with its items and shipments", "orders", true, nil, http.StatusOK, object(gin.H{
+						"order":     ref("Order"),
+						"items":     arrayOf(ref("OrderItem")),
+						"shipments": arrayOf(ref("OrderShipping")),
+					})),
+					pathParam("id"),
+				),
+				"delete": withParameters(
+					operation("Cancel an order", "orders", true, nil, http.StatusOK, nil),
+					pathParam("id"),
+				),
+			},
+		},
+	}
+}
+
+// operation describes an endpoint whose success response wraps data in the
+// standard envelope
+func operation(summary, tag string, authenticated bool, body gin.H, status int, data gin.H) gin.H {
+	envelope := gin.H{
+		"type": "object",
+		"properties": gin.H{
+			"success":   gin.H{"type": "boolean"},
+			"timestamp": gin.H{"type": "string", "format": "date-time"},
+		},
+	}
+	if data != nil {
+		envelope["properties"].(gin.H)["data"] = data
+	}
+
+	op := gin.H{
+		"summary": summary,
+		"tags":    []string{tag},
+	}
+	op["responses"] = gin.H{
+		strconv.Itoa(status): gin.H{
+			"description": http.StatusText(status),
+			"content":     gin.H{"application/json": gin.H{"schema": envelope}},
+		},
+		"default": gin.H{
+			"description": "Error",
+			"content":     gin.H{"application/json": gin.H{"schema": ref("ErrorResponse")}},
+		},
+	}
+	if body != nil {
+		op["requestBody"] = gin.H{
+			"required": true,
+			"content":  gin.H{"application/json": gin.H{"schema": body}},
+		}
+	}
+	if authenticated {
+		op["security"] = []gin.H{{"bearerAuth": []string{}}}
+	}
+	return op
+}
+
+func withParameters(op gin.H, params ...gin.H) gin.H {
+	op["parameters"] = params
+	return op
+}
+
+func pathParam(name string) gin.H {
+	return gin.H{"name": name, "in": "path", "required": true, "schema": gin.H{"type": "string"}}
+}
+
+func queryParam(name, typ string) gin.H {
+	return gin.H{"name": name, "in": "query", "schema": gin.H{"type": typ}}
+}
+
+func ref(name string) gin.H {
+	return gin.H{"$ref": "#/components/schemas/" + name}
+}
+
+func object(properties gin.H) gin.H {
+	return gin.H{"type": "object", "properties": properties}
+}
+
+func arrayOf(items gin.H) gin.H {
+	return gin.H{"type": "array", "items": items}
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	moneyType = reflect.TypeOf(models.Money(0))
+)
+
+// schemaForType derives a JSON schema from a Go type using its json and
+// binding tags
+func schemaForType(t reflect.Type) gin.H {
+	nullable := false
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema gin.H
+	switch {
+	case t == timeType:
+		schema = gin.H{"type": "string", "format": "date-time"}
+	case t == moneyType:
+		schema = gin.H{"type": "number", "multipleOf": 0.01}
+	case t.Kind() == reflect.String:
+		schema = gin.H{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		schema = gin.H{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = gin.H{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = gin.H{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		schema = arrayOf(schemaForType(t.Elem()))
+	case t.Kind() == reflect.Struct:
+		properties := gin.H{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property := schemaForType(field.Type)
+			for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+				switch {
+				case rule == "required":
+					required = append(required, name)
+				case rule == "email":
+					property["format"] = "email"
+				case strings.HasPrefix(rule, "min="):
+					if n, err := strconv.Atoi(strings.TrimPrefix(rule, "min=")); err == nil {
+						property["minLength"] = n
+					}
+				case strings.HasPrefix(rule, "gt="):
+					if n, err := strconv.ParseFloat(strings.TrimPrefix(rule, "gt="), 64); err == nil {
+						property["minimum"] = n
+						property["exclusiveMinimum"] = true
+					}
+				case strings.HasPrefix(rule, "gte="):
+					if n, err := strconv.ParseFloat(strings.TrimPrefix(rule, "gte="), 64); err == nil {
+						property["minimum"] = n
+					}
+				}
+			}
+			properties[name] = property
+		}
+		schema = gin.H{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	default:
+		schema = gin.H{}
+	}
+
+	if nullable {
+		schema["nullable"] = true
+	}
+	return schema
+}
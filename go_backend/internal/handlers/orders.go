@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
@@ -15,19 +19,22 @@ import (
 // GetUserOrders lists all orders for the current user
 func GetUserOrders(c *gin.Context) {
 	userID, _ := c.Get("userID")
-	page, limit, offset := utils.ValidatePagination(c.Query("page"), c.Query("limit"))
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
 
 	db := database.GetDB()
 
 	// Get total count
 	var total int
-	err := db.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = ?", userID).Scan(&total)
+	err := db.QueryRow("SELECT COUNT(*) FROM orders WHERE user_id = ? AND deleted_at IS NULL", userID).Scan(&total)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -35,16 +42,16 @@ func GetUserOrders(c *gin.Context) {
 	// Get orders
 	rows, err := db.Query(`
 		SELECT id, user_id, status, total_amount, shipping_address_id, created_at, updated_at
-		FROM orders WHERE user_id = ?
+		FROM orders WHERE user_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
-	`, userID, limit, offset)
+	`, userID, params.Limit, params.Offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -54,27 +61,17 @@ func GetUserOrders(c *gin.Context) {
 	for rows.Next() {
 		var o models.Order
 		err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.TotalAmount,
-			&o.ShippingAddressID, &o.CreatedAt, &o.UpdatedAt)
+			&o.ShippingAddressID, database.UTCTime(&o.CreatedAt), database.UTCTime(&o.UpdatedAt))
 		if err != nil {
 			continue
 		}
 		orders = append(orders, o)
 	}
 
-	pages := int(math.Ceil(float64(total) / float64(limit)))
-
 	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data: models.ListResponse{
-			Data: orders,
-			Pagination: models.PaginationResponse{
-				Page:  page,
-				Limit: limit,
-				Total: total,
-				Pages: pages,
-			},
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Success:   true,
+		Data:      paginated(c, orders, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
@@ -88,19 +85,14 @@ func GetOrder(c *gin.Context) {
 	var order models.Order
 	err := db.QueryRow(`
 		SELECT id, user_id, status, total_amount, shipping_address_id, created_at, updated_at
-		FROM orders WHERE id = ? AND user_id = ?
+		FROM orders WHERE id = ? AND user_id = ? AND deleted_at IS NULL
 	`, orderID, userID).Scan(
 		&order.ID, &order.UserID, &order.Status, &order.TotalAmount,
-		&order.ShippingAddressID, &order.CreatedAt, &order.UpdatedAt,
+		&order.ShippingAddressID, database.UTCTime(&order.CreatedAt), database.UTCTime(&order.UpdatedAt),
 	)
 
 	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success:   false,
-			Error:     "Order not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
+		notFound(c, "Order")
 		return
 	}
 
@@ -109,14 +101,14 @@ func GetOrder(c *gin.Context) {
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	// Get order items
 	rows, err := db.Query(`
-		SELECT id, order_id, product_id, variant_id, quantity, unit_price, total_price, created_at
+		SELECT id, order_id, product_id, variant_id, quantity, unit_price, total_price, fulfilled_quantity, shipping_address_id, created_at
 		FROM order_items WHERE order_id = ?
 	`, orderID)
 	if err != nil {
@@ -124,7 +116,7 @@ func GetOrder(c *gin.Context) {
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -134,111 +126,172 @@ func GetOrder(c *gin.Context) {
 	for rows.Next() {
 		var item models.OrderItem
 		err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.VariantID,
-			&item.Quantity, &item.UnitPrice, &item.TotalPrice, &item.CreatedAt)
+			&item.Quantity, &item.UnitPrice, &item.TotalPrice, &item.FulfilledQuantity, &item.ShippingAddressID, database.UTCTime(&item.CreatedAt))
 		if err != nil {
 			continue
 		}
 		items = append(items, item)
 	}
 
+	// Get shipments
+	shipments := []models.OrderShipping{}
+	shipRows, err := db.Query(`
+		SELECT id, order_id, shipping_address_id, shipping_method_id, tracking_number, status, estimated_delivery, created_at, updated_at
+		FROM order_shipping WHERE order_id = ?
+	`, orderID)
+	if err == nil {
+		defer shipRows.Close()
+		for shipRows.Next() {
+			var s models.OrderShipping
+			if err := shipRows.Scan(&s.ID, &s.OrderID, &s.ShippingAddressID, &s.ShippingMethodID, &s.TrackingNumber,
+				&s.Status, &s.EstimatedDelivery, database.UTCTime(&s.CreatedAt), database.UTCTime(&s.UpdatedAt)); err == nil {
+				shipments = append(shipments, s)
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
 		Data: gin.H{
-			"order": order,
-			"items": items,
+			"order":     order,
+			"items":     items,
+			"shipments": shipments,
+			"currency":  currencyInfo(),
 		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
-// CreateOrder creates a new order from cart
-func CreateOrder(c *gin.Context) {
+// maxOrderStatusIDs caps the orders one status request can ask about
+const maxOrderStatusIDs = 100
+
+// GetOrderStatuses returns the status and shipment tracking of several of the
+// current user's orders at once, for dashboards tracking many orders. Orders
+// that don't exist or belong to someone else are left out rather than
+// failing the request.
+func GetOrderStatuses(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
 	var req struct {
-		ShippingAddressID string `json:"shipping_address_id" binding:"required"`
+		OrderIDs []string `json:"order_ids" binding:"required,min=1,max=100"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Error:     "Invalid request body",
+			Error:     "order_ids must list 1 to " + strconv.Itoa(maxOrderStatusIDs) + " orders",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
-	db := database.GetDB()
-
-	// Get cart
-	var cartID string
-	err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, models.APIResponse{
-			Success:   false,
-			Error:     "Cart not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
-		})
-		return
+	args := []interface{}{userID}
+	for _, id := range req.OrderIDs {
+		args = append(args, id)
 	}
 
-	// Get cart items
-	rows, err := db.Query(`
-		SELECT ci.product_id, ci.variant_id, ci.quantity, p.price, p.stock_quantity
-		FROM cart_items ci
-		JOIN products p ON ci.product_id = p.id
-		WHERE ci.cart_id = ?
-	`, cartID)
+	rows, err := database.GetDB().Query(`
+		SELECT o.id, o.status, o.updated_at, s.id, s.tracking_number, s.status, s.estimated_delivery
+		FROM orders o
+		LEFT JOIN order_shipping s ON s.order_id = o.id
+		WHERE o.user_id = ? AND o.deleted_at IS NULL AND o.id IN (?`+strings.Repeat(", ?", len(req.OrderIDs)-1)+`)
+		ORDER BY o.created_at DESC, o.id, s.created_at
+	`, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 	defer rows.Close()
 
-	type CartItemData struct {
-		ProductID     string
-		VariantID     *string
-		Quantity      int
-		Price         float64
-		StockQuantity int
+	type shipmentTracking struct {
+		ShipmentID        string  `json:"shipment_id"`
+		TrackingNumber    *string `json:"tracking_number,omitempty"`
+		Status            string  `json:"status"`
+		EstimatedDelivery *string `json:"estimated_delivery,omitempty"`
+	}
+	type orderStatus struct {
+		OrderID   string             `json:"order_id"`
+		Status    string             `json:"status"`
+		UpdatedAt string             `json:"updated_at"`
+		Shipments []shipmentTracking `json:"shipments"`
 	}
 
-	cartItems := []CartItemData{}
-	var totalAmount float64
+	statuses := []*orderStatus{}
 	for rows.Next() {
-		var item CartItemData
-		err := rows.Scan(&item.ProductID, &item.VariantID, &item.Quantity, &item.Price, &item.StockQuantity)
+		var order orderStatus
+		var shipmentID, shipmentStatus sql.NullString
+		var shipment shipmentTracking
+		err := rows.Scan(&order.OrderID, &order.Status, &order.UpdatedAt,
+			&shipmentID, &shipment.TrackingNumber, &shipmentStatus, &shipment.EstimatedDelivery)
 		if err != nil {
 			continue
 		}
 
-		if item.StockQuantity < item.Quantity {
-			c.JSON(http.StatusBadRequest, models.APIResponse{
-				Success:   false,
-				Error:     "Insufficient stock for product",
-				Code:      "INSUFFICIENT_STOCK",
-				Timestamp: time.Now().Format(time.RFC3339),
-			})
-			return
+		// Rows of an order's shipments arrive together
+		if len(statuses) == 0 || statuses[len(statuses)-1].OrderID != order.OrderID {
+			order.Shipments = []shipmentTracking{}
+			statuses = append(statuses, &order)
+		}
+		if shipmentID.Valid {
+			shipment.ShipmentID = shipmentID.String
+			shipment.Status = shipmentStatus.String
+			last := statuses[len(statuses)-1]
+			last.Shipments = append(last.Shipments, shipment)
 		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(statuses, len(statuses)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CreateOrder creates a new order from cart
+func CreateOrder(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req checkoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	co, checkoutErr := computeCheckout(db, userID.(string), req)
+	if checkoutErr != nil {
+		checkoutErr.respond(c)
+		return
+	}
 
-		cartItems = append(cartItems, item)
-		totalAmount += item.Price * float64(item.Quantity)
+	if len(co.Unavailable) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Product is no longer available: " + strings.Join(co.Unavailable, ", "),
+			Code:      "PRODUCT_UNAVAILABLE",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
 	}
 
-	if len(cartItems) == 0 {
+	if len(co.OutOfStock) > 0 {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Error:     "Cart is empty",
-			Code:      "EMPTY_CART",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Error:     "Insufficient stock for product",
+			Code:      "INSUFFICIENT_STOCK",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -250,71 +303,131 @@ func CreateOrder(c *gin.Context) {
 			Success:   false,
 			Error:     "Failed to start transaction",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 	defer tx.Rollback()
 
 	orderID := utils.GenerateID()
-	now := time.Now().Format(time.RFC3339)
 
 	_, err = tx.Exec(`
-		INSERT INTO orders (id, user_id, status, total_amount, shipping_address_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, orderID, userID, "pending", totalAmount, req.ShippingAddressID, now, now)
+		INSERT INTO orders (id, user_id, status, total_amount, shipping_address_id)
+		VALUES (?, ?, ?, ?, ?)
+	`, orderID, userID, "pending", co.Total, co.PrimaryAddressID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to create order",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	// Create order items and update stock
-	for _, item := range cartItems {
+	for _, item := range co.Lines {
 		itemID := utils.GenerateID()
-		itemTotal := item.Price * float64(item.Quantity)
+		itemTotal := item.Price * models.Money(item.Quantity)
 
 		_, err = tx.Exec(`
-			INSERT INTO order_items (id, order_id, product_id, variant_id, quantity, unit_price, total_price, created_at)
+			INSERT INTO order_items (id, order_id, product_id, variant_id, quantity, unit_price, total_price, shipping_address_id)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-		`, itemID, orderID, item.ProductID, item.VariantID, item.Quantity, item.Price, itemTotal, now)
+		`, itemID, orderID, item.ProductID, item.VariantID, item.Quantity, item.Price, itemTotal, item.ShippingAddressID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
 				Error:     "Failed to create order items",
 				Code:      "INTERNAL_ERROR",
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
 			})
 			return
 		}
 
-		// Update stock
-		_, err = tx.Exec(`
-			UPDATE products SET stock_quantity = stock_quantity - ? WHERE id = ?
-		`, item.Quantity, item.ProductID)
+		// Update stock. A variant's product stock follows from its variants'
+		// through the variant stock triggers.
+		if item.VariantID != nil {
+			_, err = tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity - ? WHERE id = ?",
+				item.Quantity, *item.VariantID)
+		} else {
+			_, err = tx.Exec(`
+				UPDATE products SET stock_quantity = stock_quantity - ? WHERE id = ?
+			`, item.Quantity, item.ProductID)
+		}
+		if err == nil {
+			err = recordStockChange(tx, item.ProductID, item.VariantID, -item.Quantity, "order_placed")
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, models.APIResponse{
 				Success:   false,
 				Error:     "Failed to update stock",
 				Code:      "INTERNAL_ERROR",
-				Timestamp: time.Now().Format(time.RFC3339),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	// Create one shipment per destination address
+	shipmentData := []gin.H{}
+	for _, addressID := range co.Shipments {
+		shipmentID := utils.GenerateID()
+		_, err = tx.Exec(`
+			INSERT INTO order_shipping (id, order_id, shipping_address_id, shipping_method_id, status)
+			VALUES (?, ?, ?, ?, ?)
+		`, shipmentID, orderID, addressID, req.ShippingMethodID, "pending")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to create shipment",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		shipmentData = append(shipmentData, gin.H{
+			"id":                  shipmentID,
+			"shipping_address_id": addressID,
+			"cost":                co.ShipmentCosts[addressID],
+		})
+	}
+
+	if co.CouponID != nil {
+		_, err = tx.Exec("INSERT INTO coupon_usage (id, coupon_id, user_id, order_id, discount_amount, used_at) VALUES (?, ?, ?, ?, ?, ?)",
+			utils.GenerateID(), *co.CouponID, userID, orderID, co.Discount, time.Now().UTC().Format(time.RFC3339))
+		if err == nil {
+			_, err = tx.Exec("UPDATE coupons SET uses_count = uses_count + 1 WHERE id = ?", *co.CouponID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to apply coupon",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
 			})
 			return
 		}
 	}
 
 	// Clear cart
-	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id = ?", cartID)
+	_, err = tx.Exec("DELETE FROM cart_items WHERE cart_id = ?", co.CartID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to clear cart",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := notifyOrderConfirmation(tx, orderID, userID.(string), co.Total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to send order confirmation",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -324,22 +437,44 @@ func CreateOrder(c *gin.Context) {
 			Success:   false,
 			Error:     "Failed to commit transaction",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
+	data := co.breakdown()
+	data["order_id"] = orderID
+	data["total_amount"] = co.Total
+	data["status"] = "pending"
+	data["shipments"] = shipmentData
+
 	c.JSON(http.StatusCreated, models.APIResponse{
-		Success: true,
-		Data: gin.H{
-			"order_id":     orderID,
-			"total_amount": totalAmount,
-			"status":       "pending",
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Success:   true,
+		Data:      data,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// userOwnsAddresses reports whether every address id belongs to the user
+func userOwnsAddresses(db *sql.DB, userID string, addressIDs []string) (bool, error) {
+	if len(addressIDs) == 0 {
+		return false, nil
+	}
+
+	placeholders := strings.Repeat("?, ", len(addressIDs)-1) + "?"
+	args := []interface{}{userID}
+	for _, id := range addressIDs {
+		args = append(args, id)
+	}
+
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM addresses WHERE user_id = ? AND id IN ("+placeholders+")", args...).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == len(addressIDs), nil
+}
+
 // CancelOrder cancels an order
 func CancelOrder(c *gin.Context) {
 	userID, _ := c.Get("userID")
@@ -349,13 +484,17 @@ func CancelOrder(c *gin.Context) {
 
 	// Check if order exists and belongs to user
 	var status string
-	err := db.QueryRow("SELECT status FROM orders WHERE id = ? AND user_id = ?", orderID, userID).Scan(&status)
+	err := db.QueryRow("SELECT status FROM orders WHERE id = ? AND user_id = ? AND deleted_at IS NULL", orderID, userID).Scan(&status)
 	if err == sql.ErrNoRows {
-		c.JSON(http.StatusNotFound, models.APIResponse{
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Error:     "Order not found",
-			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -365,19 +504,76 @@ func CancelOrder(c *gin.Context) {
 			Success:   false,
 			Error:     "Order cannot be cancelled",
 			Code:      "INVALID_STATUS",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
+	defer tx.Rollback()
 
-	now := time.Now().Format(time.RFC3339)
-	_, err = db.Exec("UPDATE orders SET status = ?, updated_at = ? WHERE id = ?", "cancelled", now, orderID)
+	// The status is checked again as part of the update, so of two concurrent
+	// cancellations only one restores the stock
+	result, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ? AND status = ?", "cancelled", orderID, "pending")
+	var updated int64
+	if err == nil {
+		updated, err = result.RowsAffected()
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to cancel order",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if updated != 1 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Order cannot be cancelled",
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := restockOrder(tx, orderID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to restore stock",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// The buyer cancelled the order themselves, so only vendors are told
+	if err := notifyOrderStatus(tx, orderID, "", "cancelled"); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to cancel order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -385,6 +581,662 @@ func CancelOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Data:      gin.H{"message": "Order cancelled"},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// restockOrder returns the stock a cancelled order took when it was placed,
+// recording each item in the inventory history. Orders can only be cancelled
+// while pending or processing, before anything was shipped.
+func restockOrder(tx *sql.Tx, orderID string) error {
+	rows, err := tx.Query("SELECT product_id, variant_id, quantity FROM order_items WHERE order_id = ?", orderID)
+	if err != nil {
+		return err
+	}
+
+	type orderStock struct {
+		productID string
+		variantID *string
+		quantity  int
+	}
+	var items []orderStock
+	for rows.Next() {
+		var item orderStock
+		if err := rows.Scan(&item.productID, &item.variantID, &item.quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var productIDs []string
+	for _, item := range items {
+		// As when the order was placed, a variant's product stock follows
+		// from its variants' through the variant stock triggers
+		if item.variantID != nil {
+			_, err = tx.Exec("UPDATE product_variants SET stock_quantity = stock_quantity + ? WHERE id = ?",
+				item.quantity, *item.variantID)
+		} else {
+			_, err = tx.Exec("UPDATE products SET stock_quantity = stock_quantity + ? WHERE id = ?",
+				item.quantity, item.productID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordStockChange(tx, item.productID, item.variantID, item.quantity, "order_cancelled"); err != nil {
+			return err
+		}
+
+		if !slices.Contains(productIDs, item.productID) {
+			productIDs = append(productIDs, item.productID)
+		}
+	}
+
+	for _, productID := range productIDs {
+		if err := notifyBackInStock(tx, productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderStatusTransitions lists the statuses an order may move to from each status
+var orderStatusTransitions = map[string][]string{
+	"pending":           {"processing", "cancelled"},
+	"processing":        {"partially_shipped", "shipped", "cancelled"},
+	"partially_shipped": {"shipped"},
+	"shipped":           {"delivered", "returned"},
+	"delivered":         {"returned"},
+}
+
+// canTransition reports whether an order may move from one status to another
+func canTransition(from, to string) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateOrderStatus moves an order to a new status and notifies the buyer and
+// the vendors whose products are in it
+func UpdateOrderStatus(c *gin.Context) {
+	orderID := c.Param("id")
+
+	var req struct {
+		Status string `json:"status" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var status, buyerID string
+	err = tx.QueryRow("SELECT status, user_id FROM orders WHERE id = ? AND deleted_at IS NULL", orderID).Scan(&status, &buyerID)
+	if err == sql.ErrNoRows {
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// partially_shipped follows from fulfilling items, not from being set
+	if !canTransition(status, req.Status) || req.Status == "partially_shipped" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Order cannot move from " + status + " to " + req.Status,
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Guarded by the status read above, in case the order changed since
+	result, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ? AND status = ?", req.Status, orderID, status)
+	var updated int64
+	if err == nil {
+		updated, err = result.RowsAffected()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if updated != 1 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Order status changed, try again",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.Status == "cancelled" {
+		if err := restockOrder(tx, orderID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to restore stock",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	// Shipping an order ships whatever has not been fulfilled yet
+	if req.Status == "shipped" {
+		if _, err := tx.Exec("UPDATE order_items SET fulfilled_quantity = quantity WHERE order_id = ?", orderID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update order",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err := notifyOrderStatus(tx, orderID, buyerID, req.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to notify order status",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := recordAudit(tx, c, "order_status_update", "order", orderID, gin.H{
+		"status": gin.H{"old": status, "new": req.Status},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"order_id":        orderID,
+			"status":          req.Status,
+			"previous_status": status,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// notifyOrderConfirmation sends the buyer the confirmation of a placed order
+func notifyOrderConfirmation(qx queryExecer, orderID, buyerID string, total models.Money) error {
+	tmpl := notificationTemplates["order_confirmation"]
+	return NotifyMany(qx, []string{buyerID}, tmpl.Type, tmpl.Title, tmpl.Message,
+		map[string]interface{}{
+			"OrderID": orderID,
+			"Total":   total,
+		})
+}
+
+// confirmationResendInterval is how often the confirmation of an order may
+// be resent
+const confirmationResendInterval = time.Minute
+
+// confirmationResends remembers when each order's confirmation was last resent
+var confirmationResends = struct {
+	sync.Mutex
+	sentAt map[string]time.Time
+}{sentAt: map[string]time.Time{}}
+
+// ResendOrderConfirmation sends the buyer the confirmation of one of their
+// orders again, at most once per confirmationResendInterval per order
+func ResendOrderConfirmation(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	orderID := c.Param("id")
+
+	db := database.GetDB()
+
+	var total models.Money
+	err := db.QueryRow("SELECT total_amount FROM orders WHERE id = ? AND user_id = ? AND deleted_at IS NULL", orderID, userID).
+		Scan(&total)
+	if err == sql.ErrNoRows {
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	confirmationResends.Lock()
+	now := time.Now()
+	if wait := confirmationResends.sentAt[orderID].Add(confirmationResendInterval).Sub(now); wait > 0 {
+		confirmationResends.Unlock()
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success:   false,
+			Error:     "Order confirmation was resent recently, try again later",
+			Code:      "RATE_LIMIT_EXCEEDED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	for id, sentAt := range confirmationResends.sentAt {
+		if now.Sub(sentAt) >= confirmationResendInterval {
+			delete(confirmationResends.sentAt, id)
+		}
+	}
+	confirmationResends.sentAt[orderID] = now
+	confirmationResends.Unlock()
+
+	if err := notifyOrderConfirmation(db, orderID, userID.(string), total); err != nil {
+		confirmationResends.Lock()
+		delete(confirmationResends.sentAt, orderID)
+		confirmationResends.Unlock()
+
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to send order confirmation",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Order confirmation resent"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// notifyOrderStatus tells the buyer (when buyerID is set) and every vendor
+// with products in the order that the order's status changed
+func notifyOrderStatus(tx *sql.Tx, orderID, buyerID, status string) error {
+	data := map[string]interface{}{
+		"OrderID": orderID,
+		"Status":  status,
+	}
+
+	if buyerID != "" {
+		tmpl := notificationTemplates["order_status"]
+		err := NotifyMany(tx, []string{buyerID}, tmpl.Type, tmpl.Title, tmpl.Message, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(`
+		SELECT DISTINCT v.user_id
+		FROM order_items oi
+		JOIN products p ON p.id = oi.product_id
+		JOIN vendors v ON v.id = p.vendor_id
+		WHERE oi.order_id = ?
+	`, orderID)
+	if err != nil {
+		return err
+	}
+	var vendorUserIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		vendorUserIDs = append(vendorUserIDs, id)
+	}
+	rows.Close()
+
+	tmpl := notificationTemplates["vendor_order_status"]
+	return NotifyMany(tx, vendorUserIDs, tmpl.Type, tmpl.Title, tmpl.Message, data)
+}
+
+// ListAllOrders lists every customer's orders for admins. Soft-deleted orders
+// are only included with ?include_deleted=true.
+func ListAllOrders(c *gin.Context) {
+	params, ok := bindListParams(c, utils.ListSpec{
+		Filters: map[string]utils.Filter{"include_deleted": {Kind: utils.FilterBool}},
+	})
+	if !ok {
+		return
+	}
+
+	where := "deleted_at IS NULL"
+	if params.Bool("include_deleted") {
+		where = "1 = 1"
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM orders WHERE " + where).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, user_id, status, total_amount, shipping_address_id, created_at, updated_at, deleted_at
+		FROM orders WHERE `+where+`
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	orders := []models.Order{}
+	for rows.Next() {
+		var o models.Order
+		err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.TotalAmount,
+			&o.ShippingAddressID, database.UTCTime(&o.CreatedAt), database.UTCTime(&o.UpdatedAt), database.NullUTCTime(&o.DeletedAt))
+		if err != nil {
+			continue
+		}
+		orders = append(orders, o)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, orders, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteOrder soft-deletes an order, hiding it from every listing while
+// keeping its data
+func DeleteOrder(c *gin.Context) {
+	orderID := c.Param("id")
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE orders SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339), orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		notFound(c, "Order")
+		return
+	}
+
+	if err := recordAudit(tx, c, "order_delete", "order", orderID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Order deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ApplyOrderDiscount grants an ad-hoc fixed or percentage discount on a
+// pending order that hasn't been paid yet. Percentages apply to the item
+// subtotal; tax and shipping are left as they were charged. The acting admin
+// and reason are recorded in the audit log.
+func ApplyOrderDiscount(c *gin.Context) {
+	orderID := c.Param("id")
+
+	var req struct {
+		DiscountType  string  `json:"discount_type" binding:"required,oneof=percentage fixed_amount"`
+		DiscountValue float64 `json:"discount_value" binding:"required,gt=0"`
+		Reason        string  `json:"reason" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.DiscountType == "percentage" && req.DiscountValue > 100 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Percentage discount cannot exceed 100",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	var total, adjustments models.Money
+	err = tx.QueryRow("SELECT status, total_amount, adjustment_amount FROM orders WHERE id = ? AND deleted_at IS NULL", orderID).
+		Scan(&status, &total, &adjustments)
+	if err == sql.ErrNoRows {
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if status != "pending" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Only pending orders can be discounted",
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Paid orders stay pending, but their payment was taken for the old total
+	var subtotal, couponDiscount models.Money
+	var paid bool
+	err = tx.QueryRow(`
+		SELECT COALESCE((SELECT SUM(total_price) FROM order_items WHERE order_id = ?), 0),
+		       COALESCE((SELECT SUM(discount_amount) FROM coupon_usage WHERE order_id = ?), 0),
+		       EXISTS (SELECT 1 FROM payments WHERE order_id = ?)
+	`, orderID, orderID, orderID).Scan(&subtotal, &couponDiscount, &paid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if paid {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Paid orders cannot be discounted",
+			Code:      "ORDER_PAID",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var discount models.Money
+	if req.DiscountType == "percentage" {
+		discount = models.Money(math.Round(float64(subtotal) * req.DiscountValue / 100))
+	} else {
+		discount = models.MoneyFromFloat(req.DiscountValue)
+	}
+	if discount > total {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Discount exceeds the order total",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	_, err = tx.Exec("UPDATE orders SET total_amount = ?, adjustment_amount = ? WHERE id = ?",
+		total-discount, adjustments+discount, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := recordAudit(tx, c, "order_discount", "order", orderID, gin.H{
+		"discount_type":  req.DiscountType,
+		"discount_value": req.DiscountValue,
+		"discount":       discount,
+		"reason":         req.Reason,
+		"total_amount":   gin.H{"old": total, "new": total - discount},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update order",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"order_id":        orderID,
+			"subtotal":        subtotal,
+			"coupon_discount": couponDiscount,
+			"admin_discount":  adjustments + discount,
+			"discount":        discount,
+			"previous_total":  total,
+			"total":           total - discount,
+			"currency":        currencyInfo(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+)
+
+func TestCreateOrderPricesVariants(t *testing.T) {
+	user := createTestUser(t, "customer")
+	productID := createTestProduct(t, 2000, 0)
+	variantID := createTestVariant(t, productID, "ORDER-VAR-"+productID, 500, 5)
+	addTestCartItem(t, user, productID, &variantID, 2)
+
+	res := serve(t, CreateOrder, http.MethodPost, "/orders", "/orders", user, "customer", map[string]string{
+		"shipping_address_id": createTestAddress(t, user),
+	})
+	expectStatus(t, res, http.StatusCreated, "")
+	orderID := res.Data["order_id"]
+
+	if price := queryInt(t, "SELECT unit_price FROM order_items WHERE order_id = ? AND variant_id = ?", orderID, variantID); price != 2500 {
+		t.Fatalf("unit price = %d cents, want 2500", price)
+	}
+	if subtotal := queryInt(t, "SELECT SUM(total_price) FROM order_items WHERE order_id = ?", orderID); subtotal != 5000 {
+		t.Fatalf("subtotal = %d cents, want 5000", subtotal)
+	}
+	if stock := queryInt(t, "SELECT stock_quantity FROM product_variants WHERE id = ?", variantID); stock != 3 {
+		t.Fatalf("variant stock = %d, want 3", stock)
+	}
+}
+
+// listedIDs returns the ids of the items of a paginated list response
+func listedIDs(res testResponse) map[string]bool {
+	ids := map[string]bool{}
+	items, _ := res.Data["data"].([]interface{})
+	for _, item := range items {
+		ids[item.(map[string]interface{})["id"].(string)] = true
+	}
+	return ids
+}
+
+func TestDeleteOrderArchivesOrder(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	user := createTestUser(t, "customer")
+	orderID := createTestOrder(t, user, 1000)
+	keptID := createTestOrder(t, user, 1000)
+
+	del := func() testResponse {
+		return serve(t, DeleteOrder, http.MethodDelete, "/admin/orders/:id", "/admin/orders/"+orderID, admin, "admin", nil)
+	}
+	expectStatus(t, del(), http.StatusOK, "")
+	expectStatus(t, del(), http.StatusNotFound, "NOT_FOUND")
+
+	// The data is kept
+	if n := queryInt(t, "SELECT COUNT(*) FROM orders WHERE id = ? AND deleted_at IS NOT NULL", orderID); n != 1 {
+		t.Fatal("order was not archived")
+	}
+
+	// but the owner no longer sees it
+	expectStatus(t, serve(t, GetOrder, http.MethodGet, "/orders/:id", "/orders/"+orderID, user, "customer", nil), http.StatusNotFound, "NOT_FOUND")
+	res := serve(t, GetUserOrders, http.MethodGet, "/orders", "/orders", user, "customer", nil)
+	expectStatus(t, res, http.StatusOK, "")
+	if ids := listedIDs(res); ids[orderID] || !ids[keptID] {
+		t.Fatalf("user's orders = %v, want only %s", ids, keptID)
+	}
+
+	// and admins only with include_deleted
+	res = serve(t, ListAllOrders, http.MethodGet, "/admin/orders", "/admin/orders?limit=100", admin, "admin", nil)
+	expectStatus(t, res, http.StatusOK, "")
+	if ids := listedIDs(res); ids[orderID] || !ids[keptID] {
+		t.Fatal("archived order listed without include_deleted")
+	}
+	res = serve(t, ListAllOrders, http.MethodGet, "/admin/orders", "/admin/orders?limit=100&include_deleted=true", admin, "admin", nil)
+	expectStatus(t, res, http.StatusOK, "")
+	if ids := listedIDs(res); !ids[orderID] || !ids[keptID] {
+		t.Fatal("archived order not listed with include_deleted")
+	}
+}
+
+func TestStoredTimestampsAreUTC(t *testing.T) {
+	// Run as a server outside UTC
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.FixedZone("UTC+5", 5*3600)
+
+	admin := createTestUser(t, "admin")
+	user := createTestUser(t, "customer")
+	addTestCartItem(t, user, createTestProduct(t, 1000, 5), nil, 1)
+
+	res := serve(t, CreateOrder, http.MethodPost, "/orders", "/orders", user, "customer", map[string]string{
+		"shipping_address_id": createTestAddress(t, user),
+	})
+	expectStatus(t, res, http.StatusCreated, "")
+	orderID := res.Data["order_id"].(string)
+	expectStatus(t, serve(t, DeleteOrder, http.MethodDelete, "/admin/orders/:id", "/admin/orders/"+orderID, admin, "admin", nil), http.StatusOK, "")
+
+	var createdAt, updatedAt, deletedAt string
+	err := database.GetDB().QueryRow("SELECT created_at, updated_at, deleted_at FROM orders WHERE id = ?", orderID).
+		Scan(&createdAt, &updatedAt, &deletedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"created_at": createdAt, "updated_at": updatedAt, "deleted_at": deletedAt} {
+		stored, err := time.Parse(time.RFC3339, value)
+		if err != nil || !strings.HasSuffix(value, "Z") {
+			t.Errorf("%s = %q, want an RFC 3339 UTC timestamp", name, value)
+			continue
+		}
+		if d := time.Since(stored); d < -time.Minute || d > time.Minute {
+			t.Errorf("%s = %q, want about now", name, value)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// paymentMethods are all the payment methods the payments table accepts
+var paymentMethods = []string{"credit_card", "debit_card", "paypal", "bank_transfer"}
+
+// enabledPaymentMethods are the payment methods this deployment accepts
+var enabledPaymentMethods = paymentMethods
+
+// SetPaymentMethods restricts the accepted payment methods to a subset of
+// credit_card, debit_card, paypal and bank_transfer
+func SetPaymentMethods(methods []string) error {
+	enabled := map[string]bool{}
+	for _, method := range methods {
+		method = strings.TrimSpace(method)
+		if !slices.Contains(paymentMethods, method) {
+			return fmt.Errorf("unknown payment method %q", method)
+		}
+		enabled[method] = true
+	}
+	if len(enabled) == 0 {
+		return fmt.Errorf("at least one payment method must be enabled")
+	}
+
+	enabledPaymentMethods = nil
+	for _, method := range paymentMethods {
+		if enabled[method] {
+			enabledPaymentMethods = append(enabledPaymentMethods, method)
+		}
+	}
+	return nil
+}
+
+// ListPaymentMethods lists the payment methods orders can be paid with
+func ListPaymentMethods(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"methods": enabledPaymentMethods},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// PayOrder records the payment of one of the current user's orders for its
+// total. Every call needs an idempotency_key: retrying with the same key, or
+// paying an order that already has a payment, returns the existing payment
+// instead of charging again.
+func PayOrder(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	orderID := c.Param("id")
+
+	var req struct {
+		Method         string `json:"method" binding:"required,oneof=credit_card debit_card paypal bank_transfer"`
+		IdempotencyKey string `json:"idempotency_key" binding:"required,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// A retry of an earlier request gets that request's payment
+	var keyOrderID, paymentID string
+	err = tx.QueryRow("SELECT order_id, payment_id FROM payment_idempotency_keys WHERE user_id = ? AND idempotency_key = ?",
+		userID, req.IdempotencyKey).Scan(&keyOrderID, &paymentID)
+	if err == nil && keyOrderID != orderID {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Idempotency key was already used for a different order",
+			Code:      "IDEMPOTENCY_KEY_REUSED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err == nil {
+		respondWithExistingPayment(c, tx, paymentID)
+		return
+	}
+	if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var status string
+	var total models.Money
+	err = tx.QueryRow("SELECT status, total_amount FROM orders WHERE id = ? AND user_id = ? AND deleted_at IS NULL",
+		orderID, userID).Scan(&status, &total)
+	if err == sql.ErrNoRows {
+		notFound(c, "Order")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// payments.order_id is unique, so an order is only ever paid once
+	err = tx.QueryRow("SELECT id FROM payments WHERE order_id = ?", orderID).Scan(&paymentID)
+	if err == nil {
+		respondWithExistingPayment(c, tx, paymentID)
+		return
+	}
+	if err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Checked only now, so retries of payments made before a method was
+	// disabled still get their payment back
+	if !slices.Contains(enabledPaymentMethods, req.Method) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Payment method is not accepted",
+			Code:      "PAYMENT_METHOD_DISABLED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if status == "cancelled" || status == "returned" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Order cannot be paid",
+			Code:      "INVALID_STATUS",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	transactionID := "txn_" + utils.GenerateID()
+	payment := models.Payment{
+		ID:            utils.GenerateID(),
+		OrderID:       orderID,
+		UserID:        userID.(string),
+		Amount:        total,
+		Status:        "completed",
+		Method:        req.Method,
+		TransactionID: &transactionID,
+	}
+	_, err = tx.Exec(`
+		INSERT INTO payments (id, order_id, user_id, amount, status, method, transaction_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, payment.ID, payment.OrderID, payment.UserID, payment.Amount, payment.Status, payment.Method, payment.TransactionID)
+	if err != nil && strings.Contains(err.Error(), "payments.order_id") {
+		// A concurrent request paid the order first
+		tx.Rollback()
+		if err := db.QueryRow("SELECT id FROM payments WHERE order_id = ?", orderID).Scan(&paymentID); err == nil {
+			respondWithExistingPayment(c, db, paymentID)
+			return
+		}
+	}
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO payment_idempotency_keys (user_id, idempotency_key, order_id, payment_id) VALUES (?, ?, ?, ?)",
+			userID, req.IdempotencyKey, orderID, payment.ID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to record payment",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	payment.CreatedAt = time.Now().UTC()
+	payment.UpdatedAt = payment.CreatedAt
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"payment":   payment,
+			"duplicate": false,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// respondWithExistingPayment responds with a payment that was already made,
+// marked as a duplicate
+func respondWithExistingPayment(c *gin.Context, q rowQuerier, paymentID string) {
+	var payment models.Payment
+	err := q.QueryRow(`
+		SELECT id, order_id, user_id, amount, status, method, transaction_id, created_at, updated_at
+		FROM payments WHERE id = ?
+	`, paymentID).Scan(&payment.ID, &payment.OrderID, &payment.UserID, &payment.Amount, &payment.Status,
+		&payment.Method, &payment.TransactionID, database.UTCTime(&payment.CreatedAt), database.UTCTime(&payment.UpdatedAt))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"payment":   payment,
+			"duplicate": true,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPayOrderIsIdempotent(t *testing.T) {
+	user := createTestUser(t, "customer")
+	orderID := createTestOrder(t, user, 2500)
+	otherOrderID := createTestOrder(t, user, 1000)
+
+	pay := func(orderID, key string) testResponse {
+		return serve(t, PayOrder, http.MethodPost, "/orders/:id/pay", "/orders/"+orderID+"/pay", user, "customer", map[string]string{
+			"method":          "credit_card",
+			"idempotency_key": key,
+		})
+	}
+	paymentID := func(res testResponse) interface{} {
+		return res.Data["payment"].(map[string]interface{})["id"]
+	}
+
+	first := pay(orderID, "key-1")
+	expectStatus(t, first, http.StatusCreated, "")
+	if first.Data["duplicate"] != false {
+		t.Fatalf("first payment: duplicate = %v", first.Data["duplicate"])
+	}
+
+	t.Run("same key", func(t *testing.T) {
+		res := pay(orderID, "key-1")
+		expectStatus(t, res, http.StatusOK, "")
+		if res.Data["duplicate"] != true || paymentID(res) != paymentID(first) {
+			t.Fatalf("got duplicate = %v, payment %v; want the first payment %v", res.Data["duplicate"], paymentID(res), paymentID(first))
+		}
+	})
+
+	t.Run("new key for a paid order", func(t *testing.T) {
+		res := pay(orderID, "key-2")
+		expectStatus(t, res, http.StatusOK, "")
+		if res.Data["duplicate"] != true || paymentID(res) != paymentID(first) {
+			t.Fatalf("got duplicate = %v, payment %v; want the first payment %v", res.Data["duplicate"], paymentID(res), paymentID(first))
+		}
+	})
+
+	t.Run("key reused for another order", func(t *testing.T) {
+		expectStatus(t, pay(otherOrderID, "key-1"), http.StatusConflict, "IDEMPOTENCY_KEY_REUSED")
+	})
+
+	if n := queryInt(t, "SELECT COUNT(*) FROM payments WHERE order_id IN (?, ?)", orderID, otherOrderID); n != 1 {
+		t.Fatalf("%d payments recorded, want 1", n)
+	}
+}
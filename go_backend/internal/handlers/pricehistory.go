@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// recordPriceChange adds a price_history entry for a change of a product's
+// base price. It is written with the price update itself, so the history
+// can't miss a change or record one that was rolled back; setting a price to
+// what it already was records nothing.
+func recordPriceChange(ex execer, c *gin.Context, productID string, oldPrice, newPrice models.Money) error {
+	if oldPrice == newPrice {
+		return nil
+	}
+
+	userID, _ := c.Get("userID")
+	_, err := ex.Exec(`
+		INSERT INTO price_history (id, product_id, old_price, new_price, changed_by)
+		VALUES (?, ?, ?, ?, ?)
+	`, utils.GenerateID(), productID, oldPrice, newPrice, userID)
+	return err
+}
+
+// GetPriceHistory lists the changes of a product's base price, newest first
+func GetPriceHistory(c *gin.Context) {
+	productID := c.Param("id")
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM products WHERE id = ? AND store_id = ?", productID, currentStoreID(c)).Scan(&exists)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM price_history WHERE product_id = ?", productID).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, old_price, new_price, changed_at FROM price_history
+		WHERE product_id = ?
+		ORDER BY changed_at DESC, rowid DESC
+		LIMIT ? OFFSET ?
+	`, productID, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	changes := []models.PriceChange{}
+	for rows.Next() {
+		var change models.PriceChange
+		if err := rows.Scan(&change.ID, &change.OldPrice, &change.NewPrice, database.UTCTime(&change.ChangedAt)); err != nil {
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, changes, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// effectivePrice returns a SQL expression for the current price of the
+// products row aliased as table: the price of the active price rule with the
+// most recent start, or the base price when no rule applies. Rule timestamps
+// are stored in UTC so they compare correctly against SQLite's clock.
+func effectivePrice(table string) string {
+	return `COALESCE((
+		SELECT pr.price FROM price_rules pr
+		WHERE pr.product_id = ` + table + `.id AND pr.cancelled_at IS NULL
+		  AND pr.starts_at <= strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		  AND (pr.ends_at IS NULL OR pr.ends_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+		ORDER BY pr.starts_at DESC, pr.created_at DESC
+		LIMIT 1
+	), ` + table + `.price)`
+}
+
+// effectiveVariantPrice returns a SQL expression for the current price of a
+// cart or order line, as variantPrice computes it: the effective price of the
+// products row aliased as productTable plus the modifier of the
+// product_variants row aliased as variantTable, never below zero. Lines
+// without a variant, where the variant row is NULL, get the product's price.
+func effectiveVariantPrice(productTable, variantTable string) string {
+	return `MAX(` + effectivePrice(productTable) + ` + COALESCE(` + variantTable + `.price_modifier, 0), 0)`
+}
+
+// priceChangeSampleSize caps the number of changes echoed back by bulk updates
+const priceChangeSampleSize = 10
+
+type priceChange struct {
+	ProductID string       `json:"product_id"`
+	OldPrice  models.Money `json:"old_price"`
+	NewPrice  models.Money `json:"new_price"`
+}
+
+// BulkUpdatePrices updates many product prices at once, either to absolute
+// prices per product or by a percentage applied to a category/vendor selection
+func BulkUpdatePrices(c *gin.Context) {
+	var req struct {
+		Prices []struct {
+			ProductID string       `json:"product_id" binding:"required"`
+			Price     models.Money `json:"price" binding:"gte=0"`
+		} `json:"prices" binding:"dive"`
+		AdjustmentPercent *float64 `json:"adjustment_percent"`
+		CategoryID        *string  `json:"category_id"`
+		VendorID          *string  `json:"vendor_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || (len(req.Prices) == 0) == (req.AdjustmentPercent == nil) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Provide either prices or adjustment_percent",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.AdjustmentPercent != nil && req.CategoryID == nil && req.VendorID == nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "A percentage adjustment requires category_id or vendor_id",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Work out the new price for every selected product
+	changes := []priceChange{}
+	if len(req.Prices) > 0 {
+		for _, p := range req.Prices {
+			var oldPrice models.Money
+			if err := tx.QueryRow("SELECT price FROM products WHERE id = ? AND store_id = ?", p.ProductID, storeID).Scan(&oldPrice); err != nil {
+				c.JSON(http.StatusNotFound, models.APIResponse{
+					Success:   false,
+					Error:     "Product not found: " + p.ProductID,
+					Code:      "NOT_FOUND",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			changes = append(changes, priceChange{ProductID: p.ProductID, OldPrice: oldPrice, NewPrice: p.Price})
+		}
+	} else {
+		conditions := []string{"store_id = ?"}
+		args := []interface{}{storeID}
+		if req.CategoryID != nil {
+			conditions = append(conditions, "category_id = ?")
+			args = append(args, *req.CategoryID)
+		}
+		if req.VendorID != nil {
+			conditions = append(conditions, "vendor_id = ?")
+			args = append(args, *req.VendorID)
+		}
+
+		rows, err := tx.Query("SELECT id, price FROM products WHERE "+strings.Join(conditions, " AND "), args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		for rows.Next() {
+			var change priceChange
+			if err := rows.Scan(&change.ProductID, &change.OldPrice); err != nil {
+				continue
+			}
+			change.NewPrice = models.Money(math.Round(float64(change.OldPrice) * (1 + *req.AdjustmentPercent/100)))
+			changes = append(changes, change)
+		}
+		rows.Close()
+	}
+
+	for _, change := range changes {
+		if change.NewPrice < 0 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Price would fall below zero for product " + change.ProductID,
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	for _, change := range changes {
+		if _, err := tx.Exec("UPDATE products SET price = ? WHERE id = ?", change.NewPrice, change.ProductID); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update prices",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		if err := recordPriceChange(tx, c, change.ProductID, change.OldPrice, change.NewPrice); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to record price history",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		err := recordAudit(tx, c, "price_update", "product", change.ProductID, gin.H{
+			"price": gin.H{"old": change.OldPrice, "new": change.NewPrice},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to record audit log",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	sample := changes
+	if len(sample) > priceChangeSampleSize {
+		sample = sample[:priceChangeSampleSize]
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"updated": len(changes),
+			"sample":  sample,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ListPriceRules lists the price rules of a product, newest first
+func ListPriceRules(c *gin.Context) {
+	productID := c.Param("id")
+
+	db := database.GetDB()
+	rows, err := db.Query(`
+		SELECT id, product_id, price, starts_at, ends_at, cancelled_at, created_at
+		FROM price_rules WHERE product_id = ?
+		ORDER BY starts_at DESC, created_at DESC
+	`, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	rules := []models.PriceRule{}
+	for rows.Next() {
+		var r models.PriceRule
+		err := rows.Scan(&r.ID, &r.ProductID, &r.Price, database.UTCTime(&r.StartsAt), database.NullUTCTime(&r.EndsAt), database.NullUTCTime(&r.CancelledAt), database.UTCTime(&r.CreatedAt))
+		if err != nil {
+			continue
+		}
+		rules = append(rules, r)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(rules, len(rules)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CreatePriceRule schedules a price for a product. When rules overlap, the
+// one that started most recently wins.
+func CreatePriceRule(c *gin.Context) {
+	productID := c.Param("id")
+
+	var req struct {
+		Price    models.Money `json:"price" binding:"gte=0"`
+		StartsAt time.Time    `json:"starts_at" binding:"required"`
+		EndsAt   *time.Time   `json:"ends_at"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.EndsAt != nil && !req.EndsAt.After(req.StartsAt) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "ends_at must be after starts_at",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND store_id = ?", productID, currentStoreID(c)).Scan(&exists); err != nil || exists == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Product not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rule := models.PriceRule{
+		ID:        utils.GenerateID(),
+		ProductID: productID,
+		Price:     req.Price,
+		StartsAt:  req.StartsAt.UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var endsAt *string
+	if req.EndsAt != nil {
+		t := req.EndsAt.UTC()
+		rule.EndsAt = &t
+		s := t.Format(time.RFC3339)
+		endsAt = &s
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO price_rules (id, product_id, price, starts_at, ends_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, rule.ID, rule.ProductID, rule.Price, rule.StartsAt.Format(time.RFC3339), endsAt, rule.CreatedAt.Format(time.RFC3339Nano))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create price rule",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"price_rule": rule},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CancelPriceRule cancels a price rule so it no longer applies
+func CancelPriceRule(c *gin.Context) {
+	ruleID := c.Param("ruleId")
+
+	db := database.GetDB()
+	result, err := db.Exec("UPDATE price_rules SET cancelled_at = ? WHERE id = ? AND cancelled_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339), ruleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to cancel price rule",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Price rule not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Price rule cancelled"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// GetEffectivePrice breaks down what a product costs right now: its list
+// price, the sale price when it has a compare_at_price, the active price
+// rule, and optionally a coupon. Coupons apply to whole orders, so one is
+// treated as applicable when the product alone meets its minimum purchase
+// amount; an unusable coupon is reported rather than failing the request.
+func GetEffectivePrice(c *gin.Context) {
+	productID := c.Param("id")
+
+	db := database.GetDB()
+
+	var basePrice models.Money
+	var compareAtPrice *models.Money
+	err := db.QueryRow("SELECT price, compare_at_price FROM products WHERE id = ? AND store_id = ?",
+		productID, currentStoreID(c)).Scan(&basePrice, &compareAtPrice)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Discounts are listed in the order they apply, starting from the list price
+	listPrice := basePrice
+	discounts := []gin.H{}
+	if compareAtPrice != nil && *compareAtPrice > basePrice {
+		listPrice = *compareAtPrice
+		discounts = append(discounts, gin.H{"type": "sale", "amount": listPrice - basePrice})
+	}
+
+	price := basePrice
+	var ruleID, startsAt string
+	var rulePrice models.Money
+	var endsAt *string
+	err = db.QueryRow(`
+		SELECT pr.id, pr.price, pr.starts_at, pr.ends_at FROM price_rules pr
+		WHERE pr.product_id = ? AND pr.cancelled_at IS NULL
+		  AND pr.starts_at <= strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		  AND (pr.ends_at IS NULL OR pr.ends_at > strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+		ORDER BY pr.starts_at DESC, pr.created_at DESC
+		LIMIT 1
+	`, productID).Scan(&ruleID, &rulePrice, &startsAt, &endsAt)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err == nil {
+		// A rule may also raise the price, which shows as a negative amount
+		discounts = append(discounts, gin.H{
+			"type":      "price_rule",
+			"amount":    price - rulePrice,
+			"rule_id":   ruleID,
+			"starts_at": startsAt,
+			"ends_at":   endsAt,
+		})
+		price = rulePrice
+	}
+
+	data := gin.H{
+		"product_id":     productID,
+		"list_price":     listPrice,
+		"base_price":     basePrice,
+		"price":          price,
+		"discounts":      discounts,
+		"final_price":    price,
+		"total_discount": listPrice - price,
+		"currency":       currencyInfo(),
+	}
+
+	if code := strings.TrimSpace(c.Query("coupon")); code != "" {
+		coupon, err := findUsableCoupon(db, code)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		result := gin.H{"code": code, "applicable": false}
+		switch {
+		case err == sql.ErrNoRows:
+			result["reason"] = "Invalid or expired coupon"
+		case price < coupon.MinPurchaseAmount:
+			result["reason"] = "Price does not meet the coupon's minimum purchase amount"
+			result["min_purchase_amount"] = coupon.MinPurchaseAmount
+		default:
+			amount := couponDiscount(coupon, price)
+			result["applicable"] = true
+			result["discount_type"] = coupon.DiscountType
+			discounts = append(discounts, gin.H{"type": "coupon", "amount": amount, "code": code})
+			data["discounts"] = discounts
+			data["final_price"] = price - amount
+			data["total_discount"] = listPrice - (price - amount)
+		}
+		data["coupon"] = result
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      data,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
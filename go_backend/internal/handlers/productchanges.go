@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// productChange is one entry of the product changes feed. Products that are
+// no longer listed, because they were archived or deactivated, are tombstones:
+// deleted is set and product is left out.
+type productChange struct {
+	ID        string          `json:"id"`
+	Deleted   bool            `json:"deleted"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Product   *models.Product `json:"product,omitempty"`
+}
+
+// ListProductChanges lets sync clients such as mobile catalogs keep a local
+// copy of the current store's products. It lists every product updated at or
+// after since, oldest change first, including tombstones for the ones that
+// were removed from the catalog. Without since it lists every product, for
+// the first sync. Clients pass the last updated_at they received as the next
+// since; the bound is inclusive so changes made in the same second aren't
+// missed, which means the last entries can come again.
+func ListProductChanges(c *gin.Context) {
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	where := "store_id = ?"
+	args := []interface{}{currentStoreID(c)}
+	if value := c.Query("since"); value != "" {
+		since, err := parseDateParam(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid since, expected YYYY-MM-DD or an RFC 3339 timestamp",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		where += " AND updated_at >= ?"
+		args = append(args, since)
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM products WHERE "+where, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT `+productColumns+` FROM products WHERE `+where+`
+		ORDER BY updated_at, id
+		LIMIT ? OFFSET ?
+	`, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	changes := []productChange{}
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			continue
+		}
+		change := productChange{ID: p.ID, UpdatedAt: p.UpdatedAt}
+		if p.Status == "active" {
+			change.Product = &p
+		} else {
+			change.Deleted = true
+		}
+		changes = append(changes, change)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, changes, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
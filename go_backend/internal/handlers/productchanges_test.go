@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListProductChangesFlagsArchivedProducts(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	old := "2020-01-01T00:00:00Z"
+	archivedID := createTestProduct(t, 1000, 5)
+	updatedID := createTestProduct(t, 1000, 5)
+	unchangedID := createTestProduct(t, 1000, 5)
+	mustExec(t, "UPDATE products SET updated_at = ? WHERE id IN (?, ?, ?)", old, archivedID, updatedID, unchangedID)
+
+	since := time.Now().UTC().Add(-time.Second).Format(time.RFC3339)
+	expectStatus(t, serve(t, DeleteProduct, http.MethodDelete, "/products/:id", "/products/"+archivedID, admin, "admin", nil), http.StatusOK, "")
+	mustExec(t, "UPDATE products SET price = 1200 WHERE id = ?", updatedID)
+
+	res := serve(t, ListProductChanges, http.MethodGet, "/products/changes", "/products/changes?limit=100&since="+since, "", "", nil)
+	expectStatus(t, res, http.StatusOK, "")
+
+	changes := map[string]map[string]interface{}{}
+	for _, item := range res.Data["data"].([]interface{}) {
+		change := item.(map[string]interface{})
+		changes[change["id"].(string)] = change
+	}
+
+	archived, ok := changes[archivedID]
+	if !ok || archived["deleted"] != true || archived["product"] != nil {
+		t.Fatalf("archived product change = %v, want a tombstone", archived)
+	}
+	updated, ok := changes[updatedID]
+	if !ok || updated["deleted"] != false || updated["product"] == nil {
+		t.Fatalf("updated product change = %v, want the product", updated)
+	}
+	if _, ok := changes[unchangedID]; ok {
+		t.Fatal("unchanged product listed")
+	}
+}
@@ -2,69 +2,239 @@ package handlers
 
 import (
 	"database/sql"
-	"math"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
 	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
 	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
-// ListProducts lists all products with pagination
-func ListProducts(c *gin.Context) {
-	page, limit, offset := utils.ValidatePagination(
-		c.Query("page"),
-		c.Query("limit"),
-	)
+// productColumns is the column list scanned by scanProduct. price is the
+// currently effective price and base_price the product's own price.
+var productColumns = "id, name, slug, description, " + effectivePrice("products") + ", price, compare_at_price, category_id, vendor_id, store_id, status, stock_quantity, reserve_stock, sku, weight, length, width, height, restock_date, created_at, updated_at"
 
-	search := utils.SanitizeSearchQuery(c.Query("search"))
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	db := database.GetDB()
+// scanProduct scans a row selected with productColumns into a product, and
+// any columns selected after them into extra
+func scanProduct(row rowScanner, p *models.Product, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{&p.ID, &p.Name, &p.Slug, &p.Description, &p.Price, &p.BasePrice, &p.CompareAtPrice, &p.CategoryID,
+		&p.VendorID, &p.StoreID, &p.Status, &p.StockQuantity, &p.ReserveStock, &p.SKU,
+		&p.Weight, &p.Length, &p.Width, &p.Height, &p.RestockDate, database.UTCTime(&p.CreatedAt), database.UTCTime(&p.UpdatedAt)}, extra...)...)
+}
+
+// regenerateSlugOnRename makes a renamed product take a slug derived from its
+// new name. By default slugs stay stable, so existing links keep working.
+var regenerateSlugOnRename = false
+
+// productSlugAttempts is how many slugs are tried for a new product in case
+// a concurrent request claims the same one first
+const productSlugAttempts = 3
+
+// SetProductSlugOnRename sets what happens to a product's slug when it is
+// renamed: "keep" leaves it unchanged and "regenerate" derives a new one
+func SetProductSlugOnRename(mode string) error {
+	switch mode {
+	case "keep":
+		regenerateSlugOnRename = false
+	case "regenerate":
+		regenerateSlugOnRename = true
+	default:
+		return fmt.Errorf("unknown mode %q, expected keep or regenerate", mode)
+	}
+	return nil
+}
+
+// uniqueProductSlug derives a slug from a product name that no other product
+// in the store uses. Taken slugs get the lowest free numeric suffix, e.g.
+// "blue-mug-2". productID is the product being renamed, if any, whose own
+// slug doesn't count as taken.
+func uniqueProductSlug(q queryExecer, storeID, name, productID string) (string, error) {
+	base := utils.Slugify(name)
+	rows, err := q.Query("SELECT slug FROM products WHERE store_id = ? AND id != ? AND (slug = ? OR slug LIKE ?)",
+		storeID, productID, base, base+"-%")
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return utils.NextSlug(base, taken), nil
+}
+
+// renamedProductSlug returns the slug a product should have after being
+// renamed, depending on whether slugs are regenerated on rename
+func renamedProductSlug(q queryExecer, storeID, productID, slug, newName string) (string, error) {
+	if !regenerateSlugOnRename {
+		return slug, nil
+	}
+	return uniqueProductSlug(q, storeID, newName, productID)
+}
+
+// productSearch is the set of filters a product listing can be narrowed by.
+// Saved searches store it as JSON.
+type productSearch struct {
+	Search       string        `json:"search,omitempty"`
+	OnSale       bool          `json:"on_sale,omitempty"`
+	Tags         []string      `json:"tags,omitempty"`
+	MatchAllTags bool          `json:"match_all_tags,omitempty"`
+	CategoryID   string        `json:"category_id,omitempty"`
+	MinPrice     *models.Money `json:"min_price,omitempty"`
+	MaxPrice     *models.Money `json:"max_price,omitempty"`
+}
+
+// productListSpec describes the query parameters of a product listing. tags
+// is a comma-separated list; tag_match=all requires every tag instead of any
+// one of them. Every sort ends with the id, so pages don't overlap when
+// products tie.
+var productListSpec = utils.ListSpec{
+	Sorts: map[string]string{
+		"newest":     "created_at DESC, id",
+		"oldest":     "created_at, id",
+		"price_asc":  effectivePrice("products") + ", id",
+		"price_desc": effectivePrice("products") + " DESC, id",
+		"name_asc":   "name COLLATE NOCASE, id",
+		"name_desc":  "name COLLATE NOCASE DESC, id",
+	},
+	DefaultSort: "newest",
+	Search:      true,
+	Filters: map[string]utils.Filter{
+		"on_sale":   {Kind: utils.FilterBool},
+		"tags":      {},
+		"tag_match": {Choices: []string{"any", "all"}},
+		"facets":    {Kind: utils.FilterBool},
+	},
+}
+
+// productSearchFromParams reads the product filters from the parameters of
+// a product listing. category_id, min_price and max_price come straight from
+// the query; prices that aren't valid amounts are ignored.
+func productSearchFromParams(params utils.ListParams, query url.Values) productSearch {
+	s := productSearch{
+		Search:       params.Search,
+		OnSale:       params.Bool("on_sale"),
+		MatchAllTags: params.String("tag_match") == "all",
+		CategoryID:   query.Get("category_id"),
+	}
+	if tags := params.String("tags"); tags != "" {
+		s.Tags = utils.NormalizeTags(strings.Split(tags, ","))
+	}
+	if price, err := models.ParseMoney(query.Get("min_price")); err == nil {
+		s.MinPrice = &price
+	}
+	if price, err := models.ParseMoney(query.Get("max_price")); err == nil {
+		s.MaxPrice = &price
+	}
+	return s
+}
 
-	// Build query
-	query := "SELECT id, name, description, price, category_id, vendor_id, status, stock_quantity, sku, created_at, updated_at FROM products WHERE status = ?"
-	args := []interface{}{"active"}
+// where returns the WHERE clause and arguments selecting the active products
+// of a store that match the search
+func (s productSearch) where(storeID string) (string, []interface{}) {
+	conditions := []string{"store_id = ?", "status = ?"}
+	args := []interface{}{storeID, "active"}
 
-	if search != "" {
-		query += " AND (name LIKE ? OR description LIKE ?)"
-		searchPattern := "%" + search + "%"
+	if s.Search != "" {
+		conditions = append(conditions, "(name LIKE ? OR description LIKE ?)")
+		searchPattern := "%" + s.Search + "%"
 		args = append(args, searchPattern, searchPattern)
 	}
 
-	// Get total count
-	countQuery := "SELECT COUNT(*) FROM products WHERE status = ?"
-	countArgs := []interface{}{"active"}
-	if search != "" {
-		countQuery += " AND (name LIKE ? OR description LIKE ?)"
-		searchPattern := "%" + search + "%"
-		countArgs = append(countArgs, searchPattern, searchPattern)
+	if s.OnSale {
+		conditions = append(conditions, "compare_at_price IS NOT NULL AND "+effectivePrice("products")+" < compare_at_price")
+	}
+
+	if s.CategoryID != "" {
+		conditions = append(conditions, "category_id = ?")
+		args = append(args, s.CategoryID)
 	}
 
+	if s.MinPrice != nil {
+		conditions = append(conditions, effectivePrice("products")+" >= ?")
+		args = append(args, *s.MinPrice)
+	}
+
+	if s.MaxPrice != nil {
+		conditions = append(conditions, effectivePrice("products")+" <= ?")
+		args = append(args, *s.MaxPrice)
+	}
+
+	if len(s.Tags) > 0 {
+		tagMatch := "> 0"
+		if s.MatchAllTags {
+			tagMatch = "= " + strconv.Itoa(len(s.Tags))
+		}
+		conditions = append(conditions, `(
+			SELECT COUNT(*) FROM product_tags pt JOIN tags t ON pt.tag_id = t.id
+			WHERE pt.product_id = products.id AND t.name IN (?`+strings.Repeat(", ?", len(s.Tags)-1)+`)
+		) `+tagMatch)
+		for _, tag := range s.Tags {
+			args = append(args, tag)
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// ListProducts lists all products with pagination. With facets=true the
+// response also counts the matching products per category, price bucket and
+// rating.
+func ListProducts(c *gin.Context) {
+	params, ok := bindListParams(c, productListSpec)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	search := productSearchFromParams(params, c.Request.URL.Query())
+	where, args := search.where(currentStoreID(c))
+
+	// Get total count
 	var total int
-	err := db.QueryRow(countQuery, countArgs...).Scan(&total)
+	err := db.QueryRow("SELECT COUNT(*) FROM products WHERE "+where, args...).Scan(&total)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	// Get products
-	query += " LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
-
-	rows, err := db.Query(query, args...)
+	rows, err := db.Query("SELECT "+productColumns+" FROM products WHERE "+where+" ORDER BY "+params.OrderBy+" LIMIT ? OFFSET ?",
+		append(args, params.Limit, params.Offset)...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -73,52 +243,68 @@ func ListProducts(c *gin.Context) {
 	products := []models.Product{}
 	for rows.Next() {
 		var p models.Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.Price, &p.CategoryID,
-			&p.VendorID, &p.Status, &p.StockQuantity, &p.SKU, &p.CreatedAt, &p.UpdatedAt)
-		if err != nil {
+		if err := scanProduct(rows, &p); err != nil {
 			continue
 		}
 		products = append(products, p)
 	}
 
-	pages := int(math.Ceil(float64(total) / float64(limit)))
+	list := paginated(c, products, params.Page, params.Limit, total)
+	if params.Bool("facets") {
+		// Categories and prices are facets themselves, so they don't narrow
+		// the facet counts
+		facetSearch := search
+		facetSearch.CategoryID, facetSearch.MinPrice, facetSearch.MaxPrice = "", nil, nil
+		facetWhere, facetArgs := facetSearch.where(currentStoreID(c))
+		facets, err := searchProductFacets(db, facetWhere, facetArgs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		list.Facets = facets
+	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
-		Success: true,
-		Data: models.ListResponse{
-			Data: products,
-			Pagination: models.PaginationResponse{
-				Page:  page,
-				Limit: limit,
-				Total: total,
-				Pages: pages,
-			},
-		},
-		Timestamp: time.Now().Format(time.RFC3339),
+		Success:   true,
+		Data:      list,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
 // GetProduct gets a single product by ID
 func GetProduct(c *gin.Context) {
-	productID := c.Param("id")
+	getProduct(c, "id", c.Param("id"))
+}
 
+// GetProductBySlug gets a single product by its slug
+func GetProductBySlug(c *gin.Context) {
+	getProduct(c, "slug", c.Param("slug"))
+}
+
+// getProduct responds with the product whose column matches value, along
+// with its variants, attributes and tags
+func getProduct(c *gin.Context, column, value string) {
 	db := database.GetDB()
 	var product models.Product
-	err := db.QueryRow(`
-		SELECT id, name, description, price, category_id, vendor_id, status, stock_quantity, sku, created_at, updated_at
-		FROM products WHERE id = ?
-	`, productID).Scan(
-		&product.ID, &product.Name, &product.Description, &product.Price, &product.CategoryID,
-		&product.VendorID, &product.Status, &product.StockQuantity, &product.SKU,
-		&product.CreatedAt, &product.UpdatedAt,
-	)
+	var views int
+	err := scanProduct(db.QueryRow("SELECT "+productColumns+`,
+		COALESCE((SELECT views FROM product_view_counts WHERE product_id = products.id), 0)
+		FROM products WHERE `+column+" = ? AND store_id = ?",
+		value, currentStoreID(c)), &product, &views)
+	productID := product.ID
+	product.Views = &views
 
 	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, models.APIResponse{
 			Success:   false,
 			Error:     "Product not found",
 			Code:      "NOT_FOUND",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -128,117 +314,808 @@ func GetProduct(c *gin.Context) {
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
+	recordProductView(c, productID)
+
 	// Get variants
+	variants := []models.ProductVariant{}
 	rows, err := db.Query(`
 		SELECT id, product_id, name, value, price_modifier, stock_quantity, sku, created_at, updated_at
 		FROM product_variants WHERE product_id = ?
 	`, productID)
-	if err == nil {
-		defer rows.Close()
-		variants := []models.ProductVariant{}
-		for rows.Next() {
-			var v models.ProductVariant
-			if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.Value, &v.PriceModifier,
-				&v.StockQuantity, &v.SKU, &v.CreatedAt, &v.UpdatedAt); err == nil {
-				variants = append(variants, v)
-			}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	for rows.Next() {
+		var v models.ProductVariant
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.Value, &v.PriceModifier,
+			&v.StockQuantity, &v.SKU, database.UTCTime(&v.CreatedAt), database.UTCTime(&v.UpdatedAt)); err == nil {
+			variants = append(variants, v)
 		}
+	}
+	rows.Close()
 
-		c.JSON(http.StatusOK, models.APIResponse{
-			Success: true,
-			Data: gin.H{
-				"product":  product,
-				"variants": variants,
-			},
-			Timestamp: time.Now().Format(time.RFC3339),
+	// Get attributes
+	attributes := []models.ProductAttribute{}
+	rows, err = db.Query(`
+		SELECT id, product_id, name, value, created_at
+		FROM product_attributes WHERE product_id = ?
+		ORDER BY name
+	`, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	for rows.Next() {
+		var a models.ProductAttribute
+		if err := rows.Scan(&a.ID, &a.ProductID, &a.Name, &a.Value, database.UTCTime(&a.CreatedAt)); err == nil {
+			attributes = append(attributes, a)
+		}
+	}
+	rows.Close()
+
+	tags, err := productTags(db, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, models.APIResponse{
-		Success:   true,
-		Data:      product,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Success: true,
+		Data: gin.H{
+			"product":    product,
+			"variants":   variants,
+			"attributes": attributes,
+			"tags":       tags,
+			"currency":   currencyInfo(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// productFieldsValid responds with a VALIDATION_ERROR detailing every invalid
+// product field, reporting whether all of them were valid
+func productFieldsValid(c *gin.Context, fields utils.ProductFields) bool {
+	fieldErrors := utils.ValidateProductFields(fields)
+	if len(fieldErrors) == 0 {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, models.APIResponse{
+		Success:   false,
+		Error:     "Invalid product",
+		Code:      "VALIDATION_ERROR",
+		Details:   fieldErrors,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	return false
+}
+
 // CreateProduct creates a new product
 func CreateProduct(c *gin.Context) {
 	var req struct {
-		Name        string  `json:"name" binding:"required"`
-		Description string  `json:"description" binding:"required"`
-		Price       float64 `json:"price" binding:"required,gt=0"`
-		CategoryID  string  `json:"category_id" binding:"required"`
-		SKU         string  `json:"sku" binding:"required"`
-		Stock       int     `json:"stock_quantity"`
+		Name           string        `json:"name" binding:"required"`
+		Description    string        `json:"description" binding:"required"`
+		Price          models.Money  `json:"price" binding:"required,gt=0"`
+		CompareAtPrice *models.Money `json:"compare_at_price"`
+		CategoryID     string        `json:"category_id" binding:"required"`
+		SKU            string        `json:"sku" binding:"required"`
+		Stock          int           `json:"stock_quantity"`
+		Weight         *float64      `json:"weight" binding:"omitempty,gte=0"`
+		Length         *float64      `json:"length" binding:"omitempty,gte=0"`
+		Width          *float64      `json:"width" binding:"omitempty,gte=0"`
+		Height         *float64      `json:"height" binding:"omitempty,gte=0"`
+		RestockDate    *string       `json:"restock_date"`
+	}
+	// The price as sent, to check its precision before it is rounded to cents
+	var raw struct {
+		Price json.RawMessage `json:"price"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
 			Error:     "Invalid request body",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
+	c.ShouldBindBodyWith(&raw, binding.JSON)
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.SKU = utils.NormalizeSKU(req.SKU)
+	price := string(raw.Price)
+	if !productFieldsValid(c, utils.ProductFields{
+		Name:        &req.Name,
+		Description: &req.Description,
+		SKU:         &req.SKU,
+		Price:       &price,
+	}) {
+		return
+	}
+
+	if req.CompareAtPrice != nil && *req.CompareAtPrice <= req.Price {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "compare_at_price must be greater than price",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.RestockDate != nil {
+		if _, err := time.Parse("2006-01-02", *req.RestockDate); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "restock_date must be a date in YYYY-MM-DD format",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
 
 	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	var categoryCount int
+	err := db.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ? AND store_id = ?", req.CategoryID, storeID).Scan(&categoryCount)
+	if err != nil || categoryCount == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Category not found",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
 	productID := utils.GenerateID()
-	now := time.Now().Format(time.RFC3339)
 
-	_, err := db.Exec(`
-		INSERT INTO products (id, name, description, price, category_id, status, stock_quantity, sku, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`, productID, req.Name, req.Description, req.Price, req.CategoryID, "active", req.Stock, req.SKU, now, now)
+	var slug string
+	for attempt := 0; attempt < productSlugAttempts; attempt++ {
+		slug, err = uniqueProductSlug(db, storeID, req.Name, productID)
+		if err != nil {
+			break
+		}
+		_, err = db.Exec(`
+			INSERT INTO products (id, name, slug, description, price, compare_at_price, category_id, store_id, status, stock_quantity, sku, weight, length, width, height, restock_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, productID, req.Name, slug, req.Description, req.Price, req.CompareAtPrice, req.CategoryID, storeID, "active", req.Stock, req.SKU,
+			req.Weight, req.Length, req.Width, req.Height, req.RestockDate)
+		// Another product claimed the slug in the meantime, pick the next one
+		if err == nil || !strings.Contains(err.Error(), "products.slug") {
+			break
+		}
+	}
 
+	if err != nil && strings.Contains(err.Error(), "products.sku") {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "SKU already exists",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil && strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+		// The category was deleted after it was checked above
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Category not found",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to create product",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	product := models.Product{
-		ID:            productID,
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		CategoryID:    req.CategoryID,
-		Status:        "active",
-		StockQuantity: req.Stock,
-		SKU:           req.SKU,
+		ID:             productID,
+		Name:           req.Name,
+		Slug:           slug,
+		Description:    req.Description,
+		Price:          req.Price,
+		BasePrice:      req.Price,
+		CompareAtPrice: req.CompareAtPrice,
+		CategoryID:     req.CategoryID,
+		StoreID:        storeID,
+		Status:         "active",
+		StockQuantity:  req.Stock,
+		SKU:            req.SKU,
+		Weight:         req.Weight,
+		Length:         req.Length,
+		Width:          req.Width,
+		Height:         req.Height,
+		RestockDate:    req.RestockDate,
 	}
 
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success:   true,
-		Data:      product,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      gin.H{"product": product},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
+// productStatuses are the statuses a product can be given
+var productStatuses = []string{"active", "inactive", "archived"}
+
+// UpdateProduct changes some of a product's fields. Fields left out of the
+// body keep their value. Price changes are recorded in the price history,
+// and subscribers are notified when the product comes back in stock.
+func UpdateProduct(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	var req struct {
+		Name          *string       `json:"name"`
+		Description   *string       `json:"description"`
+		Price         *models.Money `json:"price"`
+		CategoryID    *string       `json:"category_id"`
+		Status        *string       `json:"status"`
+		StockQuantity *int          `json:"stock_quantity"`
+		ReserveStock  *int          `json:"reserve_stock"`
+	}
+	// The price as sent, to check its precision before it is rounded to cents
+	var raw struct {
+		Price json.RawMessage `json:"price"`
+	}
+
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	c.ShouldBindBodyWith(&raw, binding.JSON)
+
+	fields := utils.ProductFields{Name: req.Name, Description: req.Description}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+		fields.Name = req.Name
+	}
+	if req.Price != nil {
+		price := string(raw.Price)
+		fields.Price = &price
+	}
+	fieldErrors := utils.ValidateProductFields(fields)
+	if req.Price != nil && *req.Price <= 0 {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "price", Message: "must be greater than 0"})
+	}
+	if req.Status != nil && !slices.Contains(productStatuses, *req.Status) {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "status", Message: "must be one of " + strings.Join(productStatuses, ", ")})
+	}
+	if req.StockQuantity != nil && *req.StockQuantity < 0 {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "stock_quantity", Message: "must not be negative"})
+	}
+	if req.ReserveStock != nil && *req.ReserveStock < 0 {
+		fieldErrors = append(fieldErrors, utils.FieldError{Field: "reserve_stock", Message: "must not be negative"})
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid product",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	// The reserve is a merchandising control, so vendors can't set it
+	if req.ReserveStock != nil && role != "admin" {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success:   false,
+			Error:     "Only admins can set reserve_stock",
+			Code:      "FORBIDDEN",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if req.CategoryID != nil {
+		var categoryCount int
+		err := db.QueryRow("SELECT COUNT(*) FROM categories WHERE id = ? AND store_id = ?", *req.CategoryID, storeID).Scan(&categoryCount)
+		if err != nil || categoryCount == 0 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Category not found",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var current models.Product
+	err = scanProduct(tx.QueryRow("SELECT "+productColumns+" FROM products WHERE id = ?", productID), &current)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// A product with variants holds the sum of its variants' stock, which
+	// is changed per variant
+	if req.StockQuantity != nil {
+		var variantCount int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM product_variants WHERE product_id = ?", productID).Scan(&variantCount); err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if variantCount > 0 {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "stock_quantity can't be set on products with variants, adjust the variants' stock instead",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if req.Price != nil && current.CompareAtPrice != nil && *current.CompareAtPrice <= *req.Price {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "compare_at_price must be greater than price",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	// Only the supplied fields that differ are set, and updated_at always is
+	sets := []string{"updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')"}
+	var args []interface{}
+	changes := gin.H{}
+	if req.Name != nil && *req.Name != current.Name {
+		slug, err := renamedProductSlug(tx, storeID, productID, current.Slug, *req.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to update product",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		sets = append(sets, "name = ?", "slug = ?")
+		args = append(args, *req.Name, slug)
+		changes["name"] = gin.H{"old": current.Name, "new": *req.Name}
+	}
+	if req.Description != nil && *req.Description != current.Description {
+		sets = append(sets, "description = ?")
+		args = append(args, *req.Description)
+		changes["description"] = gin.H{"old": current.Description, "new": *req.Description}
+	}
+	if req.Price != nil && *req.Price != current.BasePrice {
+		sets = append(sets, "price = ?")
+		args = append(args, *req.Price)
+		changes["price"] = gin.H{"old": current.BasePrice, "new": *req.Price}
+	}
+	if req.CategoryID != nil && *req.CategoryID != current.CategoryID {
+		sets = append(sets, "category_id = ?")
+		args = append(args, *req.CategoryID)
+		changes["category_id"] = gin.H{"old": current.CategoryID, "new": *req.CategoryID}
+	}
+	if req.Status != nil && *req.Status != current.Status {
+		sets = append(sets, "status = ?")
+		args = append(args, *req.Status)
+		changes["status"] = gin.H{"old": current.Status, "new": *req.Status}
+	}
+	if req.StockQuantity != nil && *req.StockQuantity != current.StockQuantity {
+		sets = append(sets, "stock_quantity = ?")
+		args = append(args, *req.StockQuantity)
+		changes["stock_quantity"] = gin.H{"old": current.StockQuantity, "new": *req.StockQuantity}
+	}
+	if req.ReserveStock != nil && *req.ReserveStock != current.ReserveStock {
+		sets = append(sets, "reserve_stock = ?")
+		args = append(args, *req.ReserveStock)
+		changes["reserve_stock"] = gin.H{"old": current.ReserveStock, "new": *req.ReserveStock}
+	}
+
+	_, err = tx.Exec("UPDATE products SET "+strings.Join(sets, ", ")+" WHERE id = ?", append(args, productID)...)
+	if err == nil && req.Price != nil {
+		err = recordPriceChange(tx, c, productID, current.BasePrice, *req.Price)
+	}
+	if err == nil && changes["stock_quantity"] != nil {
+		err = recordStockChange(tx, productID, nil, *req.StockQuantity-current.StockQuantity, "product_update")
+	}
+	if err == nil && (changes["stock_quantity"] != nil || changes["status"] != nil) {
+		err = notifyBackInStock(tx, productID)
+	}
+	if err == nil && len(changes) > 0 {
+		err = recordAudit(tx, c, "product_update", "product", productID, changes)
+	}
+	var product models.Product
+	if err == nil {
+		err = scanProduct(tx.QueryRow("SELECT "+productColumns+" FROM products WHERE id = ?", productID), &product)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to update product",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"product": product},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteProduct removes a product from the catalog by archiving it. The row
+// stays, since past orders still refer to it; archived products are no longer
+// listed or sold.
+func DeleteProduct(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM products WHERE id = ?", productID).Scan(&status)
+	if err == nil && status != "archived" {
+		_, err = tx.Exec("UPDATE products SET status = 'archived' WHERE id = ?", productID)
+		if err == nil {
+			err = recordAudit(tx, c, "product_delete", "product", productID, gin.H{
+				"status": gin.H{"old": status, "new": "archived"},
+			})
+		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete product",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Product deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DuplicateProduct copies a product with its variants, attributes and tags
+// into a new inactive product the caller can edit before publishing it. The
+// copy has its own slug and SKUs, and no stock.
+func DuplicateProduct(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	sourceID := c.Param("id")
+
+	db := database.GetDB()
+	if !productAccess(c, db, userID, role, sourceID) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var sourceSKU string
+	product := models.Product{ID: utils.GenerateID(), Status: "inactive"}
+	err = tx.QueryRow(`
+		SELECT name, description, price, compare_at_price, category_id, vendor_id, store_id, sku, weight, length, width, height
+		FROM products WHERE id = ?
+	`, sourceID).Scan(&product.Name, &product.Description, &product.BasePrice, &product.CompareAtPrice, &product.CategoryID,
+		&product.VendorID, &product.StoreID, &sourceSKU, &product.Weight, &product.Length, &product.Width, &product.Height)
+	if err == nil {
+		product.Price = product.BasePrice
+		product.SKU, err = copySKU(tx, "products", product.StoreID, sourceSKU)
+	}
+	if err == nil {
+		product.Slug, err = uniqueProductSlug(tx, product.StoreID, product.Name, product.ID)
+	}
+	if err == nil {
+		_, err = tx.Exec(`
+			INSERT INTO products (id, name, slug, description, price, compare_at_price, category_id, vendor_id, store_id, status, stock_quantity, sku, weight, length, width, height)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?, ?, ?)
+		`, product.ID, product.Name, product.Slug, product.Description, product.BasePrice, product.CompareAtPrice, product.CategoryID,
+			product.VendorID, product.StoreID, product.Status, product.SKU, product.Weight, product.Length, product.Width, product.Height)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to duplicate product",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	variants, err := duplicateVariants(tx, sourceID, product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to duplicate product variants",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	attributes, err := duplicateAttributes(tx, sourceID, product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to duplicate product attributes",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	_, err = tx.Exec("INSERT INTO product_tags (product_id, tag_id) SELECT ?, tag_id FROM product_tags WHERE product_id = ?",
+		product.ID, sourceID)
+	var tags []string
+	if err == nil {
+		tags, err = productTags(tx, product.ID)
+	}
+	if err == nil {
+		err = recordAudit(tx, c, "product_duplicate", "product", product.ID, gin.H{
+			"source_product_id": sourceID,
+			"sku":               product.SKU,
+			"variants":          len(variants),
+			"attributes":        len(attributes),
+		})
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to duplicate product",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	product.CreatedAt = time.Now().UTC()
+	product.UpdatedAt = product.CreatedAt
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product":           product,
+			"variants":          variants,
+			"attributes":        attributes,
+			"tags":              tags,
+			"source_product_id": sourceID,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// copySKU returns a SKU for a copy of an item, unique within table, or
+// within a store's rows of it when storeID is set: the original SKU,
+// normalized, suffixed with -COPY, then -COPY-2, -COPY-3 and so on
+func copySKU(tx *sql.Tx, table, storeID, sku string) (string, error) {
+	base := utils.NormalizeSKU(sku) + "-COPY"
+	query := "SELECT sku FROM " + table + " WHERE substr(sku, 1, ?) = ?"
+	args := []interface{}{utf8.RuneCountInString(base), base}
+	if storeID != "" {
+		query += " AND store_id = ?"
+		args = append(args, storeID)
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := map[string]bool{}
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&existing); err != nil {
+			return "", err
+		}
+		taken[existing] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return utils.NextSlug(base, taken), nil
+}
+
+// duplicateVariants copies a product's variants to another product, without
+// their stock
+func duplicateVariants(tx *sql.Tx, fromID, toID string) ([]models.ProductVariant, error) {
+	rows, err := tx.Query(`
+		SELECT name, value, price_modifier, sku FROM product_variants
+		WHERE product_id = ? ORDER BY created_at, id
+	`, fromID)
+	if err != nil {
+		return nil, err
+	}
+	variants := []models.ProductVariant{}
+	for rows.Next() {
+		v := models.ProductVariant{ID: utils.GenerateID(), ProductID: toID}
+		if err := rows.Scan(&v.Name, &v.Value, &v.PriceModifier, &v.SKU); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	for i := range variants {
+		v := &variants[i]
+		// Variant SKUs stay unique across all stores
+		if v.SKU, err = copySKU(tx, "product_variants", "", v.SKU); err != nil {
+			return nil, err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO product_variants (id, product_id, name, value, price_modifier, stock_quantity, sku)
+			VALUES (?, ?, ?, ?, ?, 0, ?)
+		`, v.ID, v.ProductID, v.Name, v.Value, v.PriceModifier, v.SKU)
+		if err != nil {
+			return nil, err
+		}
+		v.CreatedAt, v.UpdatedAt = now, now
+	}
+	return variants, nil
+}
+
+// duplicateAttributes copies a product's attributes to another product
+func duplicateAttributes(tx *sql.Tx, fromID, toID string) ([]models.ProductAttribute, error) {
+	rows, err := tx.Query("SELECT name, value FROM product_attributes WHERE product_id = ? ORDER BY name", fromID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []models.ProductAttribute{}
+	for rows.Next() {
+		a := models.ProductAttribute{ID: utils.GenerateID(), ProductID: toID}
+		if err := rows.Scan(&a.Name, &a.Value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		attributes = append(attributes, a)
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	for i := range attributes {
+		a := &attributes[i]
+		_, err := tx.Exec("INSERT INTO product_attributes (id, product_id, name, value) VALUES (?, ?, ?, ?)",
+			a.ID, a.ProductID, a.Name, a.Value)
+		if err != nil {
+			return nil, err
+		}
+		a.CreatedAt = now
+	}
+	return attributes, nil
+}
+
 // ListCategories lists all categories
 func ListCategories(c *gin.Context) {
 	db := database.GetDB()
 
 	rows, err := db.Query(`
-		SELECT id, name, description, parent_id, image_url, created_at, updated_at
-		FROM categories
-	`)
+		SELECT id, name, description, parent_id, image_url, store_id, created_at, updated_at
+		FROM categories WHERE store_id = ?
+	`, currentStoreID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Database error",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -248,7 +1125,7 @@ func ListCategories(c *gin.Context) {
 	for rows.Next() {
 		var cat models.Category
 		err := rows.Scan(&cat.ID, &cat.Name, &cat.Description, &cat.ParentID,
-			&cat.ImageURL, &cat.CreatedAt, &cat.UpdatedAt)
+			&cat.ImageURL, &cat.StoreID, database.UTCTime(&cat.CreatedAt), database.UTCTime(&cat.UpdatedAt))
 		if err != nil {
 			continue
 		}
@@ -257,8 +1134,8 @@ func ListCategories(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Data:      categories,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      singlePage(categories, len(categories)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
 
@@ -274,26 +1151,35 @@ func CreateCategory(c *gin.Context) {
 			Success:   false,
 			Error:     "Invalid request body",
 			Code:      "VALIDATION_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
 
 	db := database.GetDB()
 	categoryID := utils.GenerateID()
-	now := time.Now().Format(time.RFC3339)
+	storeID := currentStoreID(c)
 
 	_, err := db.Exec(`
-		INSERT INTO categories (id, name, description, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, categoryID, req.Name, req.Description, now, now)
+		INSERT INTO categories (id, name, description, store_id)
+		VALUES (?, ?, ?, ?)
+	`, categoryID, req.Name, req.Description, storeID)
 
+	if err != nil && strings.Contains(err.Error(), "categories.name") {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Category name already exists",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
 			Error:     "Failed to create category",
 			Code:      "INTERNAL_ERROR",
-			Timestamp: time.Now().Format(time.RFC3339),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		})
 		return
 	}
@@ -302,11 +1188,42 @@ func CreateCategory(c *gin.Context) {
 		ID:          categoryID,
 		Name:        req.Name,
 		Description: req.Description,
+		StoreID:     storeID,
 	}
 
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success:   true,
-		Data:      category,
-		Timestamp: time.Now().Format(time.RFC3339),
+		Data:      gin.H{"category": category},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	})
 }
+
+// currentStoreID returns the store resolved for the request by StoreMiddleware
+func currentStoreID(c *gin.Context) string {
+	if storeID := c.GetString("storeID"); storeID != "" {
+		return storeID
+	}
+	return models.DefaultStoreID
+}
+
+// canManageProduct reports whether the user may modify a product: admins may
+// manage any product and vendors only those they sell
+func canManageProduct(db *sql.DB, userID, role interface{}, productID string) (bool, error) {
+	if role == "admin" {
+		return true, nil
+	}
+	if role != "vendor" {
+		return false, nil
+	}
+
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM products p
+		JOIN vendors v ON p.vendor_id = v.id
+		WHERE p.id = ? AND v.user_id = ?
+	`, productID, userID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
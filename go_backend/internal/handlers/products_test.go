@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateProductRejectsSKUsDifferingInCaseOrWhitespace(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	categoryID := createTestCategory(t)
+
+	sku := "wid-" + utils.GenerateID()
+	create := func(sku string) testResponse {
+		return serve(t, CreateProduct, http.MethodPost, "/products", "/products", admin, "admin", map[string]interface{}{
+			"name":           "Widget " + sku,
+			"description":    "A widget",
+			"price":          9.99,
+			"category_id":    categoryID,
+			"sku":            sku,
+			"stock_quantity": 1,
+		})
+	}
+
+	res := create(sku)
+	expectStatus(t, res, http.StatusCreated, "")
+	normalized := strings.ToUpper(sku)
+	if got := res.Data["product"].(map[string]interface{})["sku"]; got != normalized {
+		t.Fatalf("sku = %v, want %s", got, normalized)
+	}
+
+	for _, variant := range []string{normalized, " " + sku + " ", "Wid-" + strings.TrimPrefix(sku, "wid-") + "\t"} {
+		expectStatus(t, create(variant), http.StatusConflict, "CONFLICT")
+	}
+}
+
+func TestDuplicateProductNormalizesVariantSKUs(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	productID := createTestProduct(t, 1000, 0)
+	// A variant stored before SKUs were normalized
+	sku := "red-" + utils.GenerateID()
+	createTestVariant(t, productID, " "+sku+" ", 0, 3)
+
+	path := "/products/" + productID + "/duplicate"
+	expectStatus(t, serve(t, DuplicateProduct, http.MethodPost, "/products/:id/duplicate", path, admin, "admin", nil), http.StatusCreated, "")
+	expectStatus(t, serve(t, DuplicateProduct, http.MethodPost, "/products/:id/duplicate", path, admin, "admin", nil), http.StatusCreated, "")
+
+	copySKU := strings.ToUpper(sku) + "-COPY"
+	rows, err := database.GetDB().Query("SELECT sku FROM product_variants WHERE sku LIKE ? ORDER BY sku", copySKU+"%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var skus []string
+	for rows.Next() {
+		var sku string
+		if err := rows.Scan(&sku); err != nil {
+			t.Fatal(err)
+		}
+		skus = append(skus, sku)
+	}
+	if len(skus) != 2 || skus[0] != copySKU || skus[1] != copySKU+"-2" {
+		t.Fatalf("variant copies = %v, want %s and %s-2", skus, copySKU, copySKU)
+	}
+}
+
+func TestCategoryNamesAndSKUsAreUniquePerStore(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	storeID := utils.GenerateID()
+	mustExec(t, "INSERT INTO stores (id, slug, name) VALUES (?, ?, 'Other Store')", storeID, storeID)
+
+	// inStore runs a handler as StoreMiddleware would for a request to storeID
+	inStore := func(storeID string, handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("storeID", storeID)
+			handler(c)
+		}
+	}
+	createCategory := func(storeID, name string) testResponse {
+		return serve(t, inStore(storeID, CreateCategory), http.MethodPost, "/categories", "/categories", admin, "admin",
+			map[string]interface{}{"name": name})
+	}
+	createProduct := func(storeID, categoryID, sku string) testResponse {
+		return serve(t, inStore(storeID, CreateProduct), http.MethodPost, "/products", "/products", admin, "admin", map[string]interface{}{
+			"name":        "Widget",
+			"description": "A widget",
+			"price":       9.99,
+			"category_id": categoryID,
+			"sku":         sku,
+		})
+	}
+
+	name := "Category " + utils.GenerateID()
+	res := createCategory(models.DefaultStoreID, name)
+	expectStatus(t, res, http.StatusCreated, "")
+	defaultCategoryID := res.Data["category"].(map[string]interface{})["id"].(string)
+
+	res = createCategory(storeID, name)
+	expectStatus(t, res, http.StatusCreated, "")
+	otherCategoryID := res.Data["category"].(map[string]interface{})["id"].(string)
+	expectStatus(t, createCategory(storeID, name), http.StatusConflict, "CONFLICT")
+
+	sku := "SKU-" + utils.GenerateID()
+	expectStatus(t, createProduct(models.DefaultStoreID, defaultCategoryID, sku), http.StatusCreated, "")
+	expectStatus(t, createProduct(storeID, otherCategoryID, sku), http.StatusCreated, "")
+	expectStatus(t, createProduct(storeID, otherCategoryID, sku), http.StatusConflict, "CONFLICT")
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ListProductQuestions lists a product's visible questions, newest first, each
+// with its visible answers. ?unanswered=true only returns unanswered questions.
+func ListProductQuestions(c *gin.Context) {
+	productID := c.Param("id")
+	params, ok := bindListParams(c, utils.ListSpec{
+		Filters: map[string]utils.Filter{"unanswered": {Kind: utils.FilterBool}},
+	})
+	if !ok {
+		return
+	}
+
+	where := "q.product_id = ? AND q.is_hidden = 0"
+	if params.Bool("unanswered") {
+		where += " AND NOT EXISTS (SELECT 1 FROM product_answers a WHERE a.question_id = q.id AND a.is_hidden = 0)"
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM product_questions q WHERE "+where, productID).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT q.id, q.product_id, q.user_id, q.question, q.is_hidden, q.created_at, q.updated_at
+		FROM product_questions q WHERE `+where+`
+		ORDER BY q.created_at DESC
+		LIMIT ? OFFSET ?
+	`, productID, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	questions := []models.ProductQuestion{}
+	for rows.Next() {
+		var q models.ProductQuestion
+		err := rows.Scan(&q.ID, &q.ProductID, &q.UserID, &q.Question, &q.IsHidden, database.UTCTime(&q.CreatedAt), database.UTCTime(&q.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		q.Answers = []models.ProductAnswer{}
+		questions = append(questions, q)
+	}
+	rows.Close()
+
+	if err := loadAnswers(db, questions); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, questions, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// loadAnswers fills in the visible answers of each question, oldest first
+func loadAnswers(db *sql.DB, questions []models.ProductQuestion) error {
+	if len(questions) == 0 {
+		return nil
+	}
+
+	index := map[string]int{}
+	args := make([]interface{}, 0, len(questions))
+	for i, q := range questions {
+		index[q.ID] = i
+		args = append(args, q.ID)
+	}
+
+	rows, err := db.Query(`
+		SELECT a.id, a.question_id, a.user_id, u.first_name || ' ' || u.last_name, u.role, a.answer, a.is_hidden, a.created_at, a.updated_at
+		FROM product_answers a
+		JOIN users u ON a.user_id = u.id
+		WHERE a.is_hidden = 0 AND a.question_id IN (?`+strings.Repeat(", ?", len(args)-1)+`)
+		ORDER BY a.created_at ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.ProductAnswer
+		err := rows.Scan(&a.ID, &a.QuestionID, &a.UserID, &a.AuthorName, &a.AuthorRole, &a.Answer, &a.IsHidden, database.UTCTime(&a.CreatedAt), database.UTCTime(&a.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		i := index[a.QuestionID]
+		questions[i].Answers = append(questions[i].Answers, a)
+	}
+	return rows.Err()
+}
+
+// AskProductQuestion posts a question about a product
+func AskProductQuestion(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	productID := c.Param("id")
+
+	var req struct {
+		Question string `json:"question" binding:"required,max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Question) == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND store_id = ?", productID, currentStoreID(c)).Scan(&exists)
+	if err != nil || exists == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Product not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	questionID := utils.GenerateID()
+	_, err = db.Exec("INSERT INTO product_questions (id, product_id, user_id, question) VALUES (?, ?, ?, ?)",
+		questionID, productID, userID, strings.TrimSpace(req.Question))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create question",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"question": gin.H{
+				"id":         questionID,
+				"product_id": productID,
+				"user_id":    userID,
+				"question":   strings.TrimSpace(req.Question),
+				"answers":    []models.ProductAnswer{},
+			},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// AnswerProductQuestion answers a question on behalf of the product's vendor
+// or an admin
+func AnswerProductQuestion(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+	questionID := c.Param("questionId")
+
+	var req struct {
+		Answer string `json:"answer" binding:"required,max=2000"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Answer) == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	if !questionAccess(c, db, userID, role, productID, questionID) {
+		return
+	}
+
+	answerID := utils.GenerateID()
+	_, err := db.Exec("INSERT INTO product_answers (id, question_id, user_id, answer) VALUES (?, ?, ?, ?)",
+		answerID, questionID, userID, strings.TrimSpace(req.Answer))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create answer",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"answer": gin.H{
+				"id":          answerID,
+				"question_id": questionID,
+				"user_id":     userID,
+				"author_role": role,
+				"answer":      strings.TrimSpace(req.Answer),
+			},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ModerateProductQuestion hides or unhides a question, or one of its answers
+// when the route names an answer
+func ModerateProductQuestion(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+	questionID := c.Param("questionId")
+	answerID := c.Param("answerId")
+
+	var req struct {
+		IsHidden *bool `json:"is_hidden" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	if !questionAccess(c, db, userID, role, productID, questionID) {
+		return
+	}
+
+	var result sql.Result
+	var err error
+	if answerID != "" {
+		result, err = db.Exec("UPDATE product_answers SET is_hidden = ? WHERE id = ? AND question_id = ?", *req.IsHidden, answerID, questionID)
+	} else {
+		result, err = db.Exec("UPDATE product_questions SET is_hidden = ? WHERE id = ?", *req.IsHidden, questionID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to moderate question",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Answer not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Moderation updated", "is_hidden": *req.IsHidden},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteProductQuestion deletes a question and its answers, or just one
+// answer when the route names an answer
+func DeleteProductQuestion(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+	questionID := c.Param("questionId")
+	answerID := c.Param("answerId")
+
+	db := database.GetDB()
+
+	if !questionAccess(c, db, userID, role, productID, questionID) {
+		return
+	}
+
+	var result sql.Result
+	var err error
+	if answerID != "" {
+		result, err = db.Exec("DELETE FROM product_answers WHERE id = ? AND question_id = ?", answerID, questionID)
+	} else {
+		result, err = db.Exec("DELETE FROM product_questions WHERE id = ?", questionID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Answer not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// questionAccess checks that the user may manage the product, as
+// productAccess does, and that the question belongs to the product, writing
+// the error response when not
+func questionAccess(c *gin.Context, db *sql.DB, userID, role interface{}, productID, questionID string) bool {
+	if !productAccess(c, db, userID, role, productID) {
+		return false
+	}
+
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM product_questions WHERE id = ? AND product_id = ?", questionID, productID).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+	if exists == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Question not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+
+	return true
+}
+
+// UnansweredQuestionCounts returns, for the vendor dashboard, how many
+// visible questions are still unanswered on each of the vendor's products.
+// Admins see every product.
+func UnansweredQuestionCounts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+
+	query := `
+		SELECT p.id, p.name, COUNT(*)
+		FROM product_questions q
+		JOIN products p ON q.product_id = p.id
+		WHERE q.is_hidden = 0 AND p.store_id = ?
+		  AND NOT EXISTS (SELECT 1 FROM product_answers a WHERE a.question_id = q.id AND a.is_hidden = 0)
+	`
+	args := []interface{}{currentStoreID(c)}
+	if role != "admin" {
+		query += " AND p.vendor_id IN (SELECT id FROM vendors WHERE user_id = ?)"
+		args = append(args, userID)
+	}
+	query += " GROUP BY p.id, p.name ORDER BY COUNT(*) DESC"
+
+	db := database.GetDB()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	total := 0
+	products := []gin.H{}
+	for rows.Next() {
+		var productID, name string
+		var count int
+		if err := rows.Scan(&productID, &name, &count); err != nil {
+			continue
+		}
+		total += count
+		products = append(products, gin.H{
+			"product_id":   productID,
+			"product_name": name,
+			"unanswered":   count,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"unanswered": total,
+			"products":   singlePage(products, len(products)),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
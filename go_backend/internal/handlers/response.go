@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Response contract:
+//   - lists are always a models.ListResponse, even when they are not paginated;
+//     paginated lists also carry RFC 5988 Link headers (see paginated)
+//   - a single resource is always keyed by its name, alongside any related
+//     collections, e.g. {"product": ..., "variants": [...], "attributes": [...]}
+//   - responses carrying cart, order or product amounts include "currency"
+//   - resources owned by a user (orders, carts, cart items, addresses) are
+//     looked up together with their owner, so another user's resource is
+//     indistinguishable from a missing one: always 404, never 403
+
+// notFound responds that a resource does not exist. It is also the response
+// for resources that exist but belong to another user, so their existence is
+// never revealed.
+func notFound(c *gin.Context, resource string) {
+	c.JSON(http.StatusNotFound, models.APIResponse{
+		Success:   false,
+		Error:     resource + " not found",
+		Code:      "NOT_FOUND",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// currencyInfo describes the currency and precision of the amounts in a
+// response, so clients can format them without hardcoding either
+func currencyInfo() gin.H {
+	return gin.H{
+		"code":      models.Currency(),
+		"precision": models.MoneyPrecision,
+	}
+}
+
+// singlePage wraps an unpaginated list in a ListResponse covering every item
+func singlePage(data interface{}, count int) models.ListResponse {
+	pages := 1
+	if count == 0 {
+		pages = 0
+	}
+	return models.ListResponse{
+		Data: data,
+		Pagination: models.PaginationResponse{
+			Page:  1,
+			Limit: count,
+			Total: count,
+			Pages: pages,
+		},
+	}
+}
+
+// paginated wraps one page of a list in a ListResponse and sets a Link header
+// with its first, prev, next and last pages, so clients can page without
+// parsing the body. The links keep every other query parameter of the request,
+// such as search terms and filters.
+func paginated(c *gin.Context, data interface{}, page, limit, total int) models.ListResponse {
+	pages := int(math.Ceil(float64(total) / float64(limit)))
+
+	last := pages
+	if last < 1 {
+		last = 1
+	}
+	links := []string{pageLink(c, 1, limit, "first")}
+	if page > 1 {
+		links = append(links, pageLink(c, min(page-1, last), limit, "prev"))
+	}
+	if page < last {
+		links = append(links, pageLink(c, page+1, limit, "next"))
+	}
+	links = append(links, pageLink(c, last, limit, "last"))
+	c.Header("Link", strings.Join(links, ", "))
+
+	return models.ListResponse{
+		Data: data,
+		Pagination: models.PaginationResponse{
+			Page:  page,
+			Limit: limit,
+			Total: total,
+			Pages: pages,
+		},
+	}
+}
+
+// pageLink formats one Link header entry pointing at another page of the
+// current request
+func pageLink(c *gin.Context, page, limit int, rel string) string {
+	query := c.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf("<%s?%s>; rel=\"%s\"", c.Request.URL.Path, query.Encode(), rel)
+}
+
+// maxAllRows caps ?all=true listings, so a table that has grown large can't
+// be dumped in one response by accident
+const maxAllRows = 10000
+
+// bindListParams reads the page, limit, sort, search and filters of a list
+// request as described by spec. When any of them is malformed it writes a
+// VALIDATION_ERROR detailing each one and returns ok=false.
+func bindListParams(c *gin.Context, spec utils.ListSpec) (params utils.ListParams, ok bool) {
+	params, fieldErrors := utils.ParseListParams(c.Request.URL.Query(), spec)
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid query parameters",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return params, false
+	}
+	return params, true
+}
+
+// allRowsFilter lets a list return every row as a single page with
+// ?all=true. Only endpoints over tables known to stay small accept it.
+var allRowsFilter = map[string]utils.Filter{"all": {Kind: utils.FilterBool}}
+
+// listWindow returns the page, limit and offset of a paginated list of total
+// rows. With ?all=true (see allRowsFilter) every row is returned as a single
+// page; when that would exceed maxAllRows it writes an error response and
+// returns ok=false.
+func listWindow(c *gin.Context, params utils.ListParams, total int) (page, limit, offset int, ok bool) {
+	if params.Bool("all") {
+		if total > maxAllRows {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Too many rows for all=true (limit " + strconv.Itoa(maxAllRows) + "), use pagination",
+				Code:      "LIST_TOO_LARGE",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return 0, 0, 0, false
+		}
+		return 1, maxAllRows, 0, true
+	}
+
+	return params.Page, params.Limit, params.Offset, true
+}
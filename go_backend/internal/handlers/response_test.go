@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindListParamsRejectsMalformedQuery(t *testing.T) {
+	spec := utils.ListSpec{
+		Sorts:       map[string]string{"newest": "created_at DESC"},
+		DefaultSort: "newest",
+		Filters:     map[string]utils.Filter{"active": {Kind: utils.FilterBool}},
+	}
+	list := func(c *gin.Context) {
+		params, ok := bindListParams(c, spec)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, models.APIResponse{Success: true, Data: gin.H{"page": params.Page, "limit": params.Limit}})
+	}
+
+	res := serve(t, list, http.MethodGet, "/items", "/items?page=2&limit=5&sort=newest&active=true", "", "", nil)
+	expectStatus(t, res, http.StatusOK, "")
+	if res.Data["page"] != 2.0 || res.Data["limit"] != 5.0 {
+		t.Fatalf("page %v, limit %v; want 2, 5", res.Data["page"], res.Data["limit"])
+	}
+
+	res = serve(t, list, http.MethodGet, "/items", "/items?page=0&limit=500&sort=oldest&active=maybe", "", "", nil)
+	expectStatus(t, res, http.StatusBadRequest, "VALIDATION_ERROR")
+	var details []utils.FieldError
+	if err := json.Unmarshal(res.Details, &details); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, detail := range details {
+		fields = append(fields, detail.Field)
+	}
+	if len(fields) != 4 || fields[0] != "page" || fields[1] != "limit" || fields[2] != "sort" || fields[3] != "active" {
+		t.Fatalf("field errors for %v, want page, limit, sort and active", fields)
+	}
+}
+
+func TestOtherUsersResourcesAreNotFound(t *testing.T) {
+	owner := createTestUser(t, "customer")
+	other := createTestUser(t, "customer")
+	createTestAddress(t, other)
+	addTestCartItem(t, other, createTestProduct(t, 1000, 5), nil, 1)
+
+	orderID := createTestOrder(t, owner, 1000)
+	itemID := addTestCartItem(t, owner, createTestProduct(t, 1000, 5), nil, 1)
+	addressID := createTestAddress(t, owner)
+
+	tests := []struct {
+		name    string
+		handler gin.HandlerFunc
+		method  string
+		route   string
+		path    string
+		body    interface{}
+	}{
+		{"get order", GetOrder, http.MethodGet, "/orders/:id", "/orders/" + orderID, nil},
+		{"cancel order", CancelOrder, http.MethodDelete, "/orders/:id", "/orders/" + orderID, nil},
+		{"pay order", PayOrder, http.MethodPost, "/orders/:id/pay", "/orders/" + orderID + "/pay",
+			map[string]string{"method": "credit_card", "idempotency_key": "not-found-test"}},
+		{"remove cart item", RemoveFromCart, http.MethodDelete, "/cart/items/:itemId", "/cart/items/" + itemID, nil},
+		{"update address", UpdateAddress, http.MethodPut, "/addresses/:id", "/addresses/" + addressID,
+			map[string]string{"city": "Shelbyville"}},
+		{"delete address", DeleteAddress, http.MethodDelete, "/addresses/:id", "/addresses/" + addressID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := serve(t, tt.handler, tt.method, tt.route, tt.path, other, "customer", tt.body)
+			expectStatus(t, res, http.StatusNotFound, "NOT_FOUND")
+		})
+	}
+
+	// Nothing of the owner's was touched
+	if n := queryInt(t, "SELECT COUNT(*) FROM orders WHERE id = ? AND status = 'pending'", orderID); n != 1 {
+		t.Error("order was changed")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM cart_items WHERE id = ?", itemID); n != 1 {
+		t.Error("cart item was removed")
+	}
+	if n := queryInt(t, "SELECT COUNT(*) FROM addresses WHERE id = ? AND city = 'Springfield'", addressID); n != 1 {
+		t.Error("address was changed")
+	}
+}
+
+func TestListWindowCapsAllRows(t *testing.T) {
+	tests := []struct {
+		query string
+		total int
+		want  []int
+		code  string
+	}{
+		{"page=2&limit=10", 50, []int{2, 10, 10}, ""},
+		{"page=2&limit=10&all=true", 50, []int{1, maxAllRows, 0}, ""},
+		{"all=true", maxAllRows, []int{1, maxAllRows, 0}, ""},
+		{"all=true", maxAllRows + 1, nil, "LIST_TOO_LARGE"},
+		{"all=false", maxAllRows + 1, []int{1, utils.DefaultPageLimit, 0}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s of %d", tt.query, tt.total), func(t *testing.T) {
+			list := func(c *gin.Context) {
+				params, ok := bindListParams(c, utils.ListSpec{Filters: allRowsFilter})
+				if !ok {
+					return
+				}
+				page, limit, offset, ok := listWindow(c, params, tt.total)
+				if !ok {
+					return
+				}
+				c.JSON(http.StatusOK, models.APIResponse{Success: true, Data: gin.H{"window": []int{page, limit, offset}}})
+			}
+
+			res := serve(t, list, http.MethodGet, "/items", "/items?"+tt.query, "", "", nil)
+			if tt.code != "" {
+				expectStatus(t, res, http.StatusBadRequest, tt.code)
+				return
+			}
+			expectStatus(t, res, http.StatusOK, "")
+			if got := fmt.Sprint(res.Data["window"]); got != fmt.Sprint(tt.want) {
+				t.Fatalf("page, limit, offset = %s, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListStoresReturnsAllRows(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	for i := 0; i < 3; i++ {
+		id := utils.GenerateID()
+		mustExec(t, "INSERT INTO stores (id, slug, name) VALUES (?, ?, ?)", id, "store-"+id, "Store "+id)
+	}
+	total := queryInt(t, "SELECT COUNT(*) FROM stores")
+
+	res := serve(t, ListStores, http.MethodGet, "/admin/stores", "/admin/stores?limit=1&all=true", admin, "admin", nil)
+	expectStatus(t, res, http.StatusOK, "")
+	if n := len(res.Data["data"].([]interface{})); n != total {
+		t.Fatalf("listed %d stores, want all %d", n, total)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"cmp"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// reviewKeywordsTTL is how long a product's review keywords are cached
+const reviewKeywordsTTL = 5 * time.Minute
+
+// maxReviewKeywords caps the keywords returned for a product
+const maxReviewKeywords = 20
+
+// minKeywordLength is the shortest word counted as a keyword
+const minKeywordLength = 3
+
+// reviewStopwords are common English words that say nothing about a product
+var reviewStopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`
+		about after again all also and any are because been before being but
+		can could did does doing don done for from get got had has have having
+		her here him his how into its just like more most much not now off once
+		only other our out over own same she should some such than that the
+		their them then there these they this those through too under until
+		very was way were what when where which while who why will with would
+		you your yours i'm it's i've don't didn't doesn't isn't wasn't can't
+		product item bought buy one really`) {
+		reviewStopwords[word] = true
+	}
+}
+
+// reviewKeyword is a word used in a product's reviews and how often
+type reviewKeyword struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type reviewKeywordsEntry struct {
+	reviewCount int
+	keywords    []reviewKeyword
+	expires     time.Time
+}
+
+var reviewKeywordsCache = struct {
+	sync.Mutex
+	entries map[string]reviewKeywordsEntry
+}{entries: map[string]reviewKeywordsEntry{}}
+
+// GetReviewKeywords returns the words used most in a product's approved
+// reviews, with how many times each is used, leaving out stopwords. Products
+// with few reviews get few keywords, or none; review_count tells how much
+// the keywords are based on.
+func GetReviewKeywords(c *gin.Context) {
+	productID := c.Param("id")
+	key := currentStoreID(c) + "|" + productID
+
+	reviewKeywordsCache.Lock()
+	entry, ok := reviewKeywordsCache.entries[key]
+	reviewKeywordsCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success:   true,
+			Data:      gin.H{"review_count": entry.reviewCount, "keywords": entry.keywords},
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM products WHERE id = ? AND store_id = ? AND status = 'active'",
+		productID, currentStoreID(c)).Scan(&exists)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query("SELECT description FROM reviews WHERE product_id = ? AND is_approved = 1", productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	reviewCount := 0
+	for rows.Next() {
+		var description string
+		if err := rows.Scan(&description); err != nil {
+			continue
+		}
+		reviewCount++
+		for _, word := range reviewWords(description) {
+			counts[word]++
+		}
+	}
+
+	keywords := make([]reviewKeyword, 0, len(counts))
+	for term, count := range counts {
+		keywords = append(keywords, reviewKeyword{Term: term, Count: count})
+	}
+	slices.SortFunc(keywords, func(a, b reviewKeyword) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Term, b.Term))
+	})
+	if len(keywords) > maxReviewKeywords {
+		keywords = keywords[:maxReviewKeywords]
+	}
+
+	reviewKeywordsCache.Lock()
+	now := time.Now()
+	for cachedKey, cached := range reviewKeywordsCache.entries {
+		if now.After(cached.expires) {
+			delete(reviewKeywordsCache.entries, cachedKey)
+		}
+	}
+	reviewKeywordsCache.entries[key] = reviewKeywordsEntry{reviewCount: reviewCount, keywords: keywords, expires: now.Add(reviewKeywordsTTL)}
+	reviewKeywordsCache.Unlock()
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"review_count": reviewCount, "keywords": keywords},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// reviewWords splits review text into lowercase words worth counting:
+// stopwords, numbers and words shorter than minKeywordLength are dropped
+func reviewWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+
+	var words []string
+	for _, word := range fields {
+		word = strings.Trim(word, "'")
+		if len([]rune(word)) < minKeywordLength || reviewStopwords[word] {
+			continue
+		}
+		if strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		words = append(words, word)
+	}
+	return words
+}
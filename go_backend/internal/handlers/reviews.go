@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// Review field limits
+const (
+	maxReviewTitleLength = 200
+	maxReviewBodyLength  = 5000
+)
+
+// anonymousReviewerEmail is the placeholder account imported reviews without
+// a reviewer email are attributed to
+const anonymousReviewerEmail = "anonymous-reviewer@reviews.invalid"
+
+// ImportReviews imports historical reviews from another platform. Products
+// are matched by SKU in the current store and reviewers by email. Unknown
+// reviewers are rejected unless create_placeholder_users is set, in which case
+// an inactive account that cannot log in is created for each of them. The
+// import is all or nothing: any invalid row rejects the whole batch, with an
+// error for every invalid row.
+func ImportReviews(c *gin.Context) {
+	var req struct {
+		Reviews []struct {
+			SKU           string  `json:"sku"`
+			Rating        int     `json:"rating"`
+			Title         string  `json:"title"`
+			Body          string  `json:"body"`
+			ReviewerEmail string  `json:"reviewer_email"`
+			ReviewerName  string  `json:"reviewer_name"`
+			CreatedAt     *string `json:"created_at"`
+		} `json:"reviews" binding:"required,min=1,max=1000"`
+		Approved               bool `json:"approved"`
+		CreatePlaceholderUsers bool `json:"create_placeholder_users"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var fieldErrors []utils.FieldError
+	reviewers := map[string]string{}
+	placeholders := 0
+	imported := make([]models.Review, 0, len(req.Reviews))
+	now := time.Now().UTC()
+
+	for i, row := range req.Reviews {
+		field := fmt.Sprintf("reviews[%d]", i)
+		rowErrors := len(fieldErrors)
+
+		if row.Rating < 1 || row.Rating > 5 {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".rating", Message: "must be between 1 and 5"})
+		}
+		title, body := strings.TrimSpace(row.Title), strings.TrimSpace(row.Body)
+		if utf8.RuneCountInString(title) > maxReviewTitleLength {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".title", Message: fmt.Sprintf("must be at most %d characters", maxReviewTitleLength)})
+		}
+		if body == "" {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".body", Message: "must not be empty"})
+		} else if utf8.RuneCountInString(body) > maxReviewBodyLength {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".body", Message: fmt.Sprintf("must be at most %d characters", maxReviewBodyLength)})
+		}
+
+		createdAt := now
+		if row.CreatedAt != nil {
+			createdAt, err = parseReviewDate(*row.CreatedAt)
+			if err != nil {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".created_at", Message: "must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			} else if createdAt.After(now) {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".created_at", Message: "must not be in the future"})
+			}
+		}
+
+		var productID string
+		err := tx.QueryRow("SELECT id FROM products WHERE sku = ? AND store_id = ?", utils.NormalizeSKU(row.SKU), storeID).Scan(&productID)
+		if err == sql.ErrNoRows {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".sku", Message: "product not found"})
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		email := strings.ToLower(strings.TrimSpace(row.ReviewerEmail))
+		if email != "" && !utils.IsValidEmail(email) {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".reviewer_email", Message: "is not a valid email"})
+			continue
+		}
+		if email == "" {
+			email = anonymousReviewerEmail
+		}
+
+		// Reviewers are only looked up, or created, for otherwise valid rows
+		if len(fieldErrors) > rowErrors {
+			continue
+		}
+
+		userID, ok := reviewers[email]
+		if !ok {
+			err := tx.QueryRow("SELECT id FROM users WHERE email = ? COLLATE NOCASE", email).Scan(&userID)
+			if err == sql.ErrNoRows && req.CreatePlaceholderUsers {
+				userID, err = createPlaceholderReviewer(tx, email, row.ReviewerName)
+				placeholders++
+			}
+			if err == sql.ErrNoRows {
+				fieldErrors = append(fieldErrors, utils.FieldError{Field: field + ".reviewer_email", Message: "user not found"})
+				continue
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Failed to import reviews",
+					Code:      "INTERNAL_ERROR",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			reviewers[email] = userID
+		}
+
+		imported = append(imported, models.Review{
+			ID:          utils.GenerateID(),
+			ProductID:   productID,
+			UserID:      userID,
+			Title:       title,
+			Description: body,
+			Rating:      row.Rating,
+			IsApproved:  req.Approved,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		})
+	}
+
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid reviews, nothing was imported",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	for _, review := range imported {
+		createdAt := review.CreatedAt.UTC().Format(time.RFC3339)
+		_, err := tx.Exec(`
+			INSERT INTO reviews (id, product_id, user_id, title, description, rating, is_approved, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, review.ID, review.ProductID, review.UserID, review.Title, review.Description, review.Rating, review.IsApproved,
+			createdAt, createdAt)
+		if err == nil {
+			err = recordAudit(tx, c, "review_import", "review", review.ID, gin.H{
+				"product_id":  review.ProductID,
+				"user_id":     review.UserID,
+				"rating":      review.Rating,
+				"is_approved": review.IsApproved,
+			})
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to import reviews",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"imported":          len(imported),
+			"placeholder_users": placeholders,
+			"reviews":           imported,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// parseReviewDate accepts either a full timestamp or a date
+func parseReviewDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// createPlaceholderReviewer creates an inactive customer account standing in
+// for a reviewer from another platform. Its random password is discarded, so
+// nobody can log in as it.
+func createPlaceholderReviewer(tx *sql.Tx, email, name string) (string, error) {
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(name), " ")
+	if firstName == "" {
+		firstName = "Imported"
+	}
+	if lastName == "" {
+		lastName = "Reviewer"
+	}
+
+	user := models.User{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  strings.TrimSpace(lastName),
+		Role:      "customer",
+	}
+	if err := createUser(tx, &user, utils.GenerateTemporaryPassword()); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec("UPDATE users SET is_active = 0 WHERE id = ?", user.ID); err != nil {
+		return "", err
+	}
+	return user.ID, nil
+}
+
+// ModerateReviews approves or rejects a batch of reviews of the current
+// store's products in one transaction, recording who moderated each one and
+// when. Rejected reviews stay unapproved but, unlike pending ones, have a
+// moderated_at. Reviews that don't exist are reported in the results without
+// failing the rest of the batch.
+func ModerateReviews(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Reviews []struct {
+			ReviewID string `json:"review_id" binding:"required"`
+			Action   string `json:"action" binding:"required,oneof=approve reject"`
+		} `json:"reviews" binding:"required,min=1,max=500,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var fieldErrors []utils.FieldError
+	seen := map[string]bool{}
+	for i, item := range req.Reviews {
+		if seen[item.ReviewID] {
+			fieldErrors = append(fieldErrors, utils.FieldError{Field: fmt.Sprintf("reviews[%d].review_id", i), Message: "review is moderated more than once"})
+		}
+		seen[item.ReviewID] = true
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid review moderation",
+			Code:      "VALIDATION_ERROR",
+			Details:   fieldErrors,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	moderatedAt := time.Now().UTC().Format(time.RFC3339)
+	results := make([]gin.H, 0, len(req.Reviews))
+	counts := map[string]int{"approved": 0, "rejected": 0, "not_found": 0}
+	for _, item := range req.Reviews {
+		approve := item.Action == "approve"
+		result, err := tx.Exec(`
+			UPDATE reviews SET is_approved = ?, moderated_by = ?, moderated_at = ?
+			WHERE id = ? AND product_id IN (SELECT id FROM products WHERE store_id = ?)
+		`, approve, userID, moderatedAt, item.ReviewID, storeID)
+		var updated int64
+		if err == nil {
+			updated, err = result.RowsAffected()
+		}
+		if err == nil && updated > 0 {
+			err = recordAudit(tx, c, "review_moderate", "review", item.ReviewID, gin.H{
+				"action":      item.Action,
+				"is_approved": approve,
+			})
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to moderate reviews",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		status := "rejected"
+		switch {
+		case updated == 0:
+			status = "not_found"
+		case approve:
+			status = "approved"
+		}
+		counts[status]++
+		results = append(results, gin.H{
+			"review_id": item.ReviewID,
+			"action":    item.Action,
+			"status":    status,
+		})
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"approved":     counts["approved"],
+			"rejected":     counts["rejected"],
+			"not_found":    counts["not_found"],
+			"moderated_by": userID,
+			"moderated_at": moderatedAt,
+			"results":      results,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// reviewStatusConditions selects reviews by moderation status: pending ones
+// haven't been moderated yet, rejected ones were moderated but not approved
+var reviewStatusConditions = map[string]string{
+	"pending":  "r.is_approved = 0 AND r.moderated_at IS NULL",
+	"approved": "r.is_approved = 1",
+	"rejected": "r.is_approved = 0 AND r.moderated_at IS NOT NULL",
+}
+
+// vendorReview is a review listed on the vendor dashboard, with the name of
+// the product it is about
+type vendorReview struct {
+	models.Review
+	ProductName string `json:"product_name"`
+}
+
+// ListVendorReviews lists, for the vendor dashboard, the reviews of the
+// vendor's products, newest first, optionally filtered by moderation status.
+// Admins see the reviews of every product.
+func ListVendorReviews(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	params, ok := bindListParams(c, utils.ListSpec{
+		Filters: map[string]utils.Filter{
+			"status": {Choices: []string{"pending", "approved", "rejected"}},
+		},
+	})
+	if !ok {
+		return
+	}
+
+	conditions := []string{"p.store_id = ?"}
+	args := []interface{}{currentStoreID(c)}
+	if role != "admin" {
+		conditions = append(conditions, "p.vendor_id IN (SELECT id FROM vendors WHERE user_id = ?)")
+		args = append(args, userID)
+	}
+	if status := params.String("status"); status != "" {
+		conditions = append(conditions, reviewStatusConditions[status])
+	}
+
+	where := strings.Join(conditions, " AND ")
+	db := database.GetDB()
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM reviews r JOIN products p ON r.product_id = p.id WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT r.id, r.product_id, r.user_id, r.title, r.description, r.rating, r.is_approved, r.helpful_count,
+		       r.moderated_by, r.moderated_at, r.created_at, r.updated_at, p.name
+		FROM reviews r
+		JOIN products p ON r.product_id = p.id
+		WHERE `+where+`
+		ORDER BY r.created_at DESC, r.id
+		LIMIT ? OFFSET ?
+	`, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	reviews := []vendorReview{}
+	for rows.Next() {
+		var r vendorReview
+		if err := rows.Scan(&r.ID, &r.ProductID, &r.UserID, &r.Title, &r.Description, &r.Rating, &r.IsApproved, &r.HelpfulCount,
+			&r.ModeratedBy, database.NullUTCTime(&r.ModeratedAt), database.UTCTime(&r.CreatedAt), database.UTCTime(&r.UpdatedAt), &r.ProductName); err != nil {
+			continue
+		}
+		reviews = append(reviews, r)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, reviews, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSavedSearches caps how many searches a user can save, which also bounds
+// the work of each alert run
+const maxSavedSearches = 25
+
+// CreateSavedSearch saves a product search for the current user in the
+// current store. New products matching it are announced as notifications.
+func CreateSavedSearch(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Name    string        `json:"name" binding:"required,max=100"`
+		Filters productSearch `json:"filters"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	filters := req.Filters
+	filters.Search = utils.SanitizeSearchQuery(filters.Search)
+	filters.Tags = utils.NormalizeTags(filters.Tags)
+	if filters.Search == "" && !filters.OnSale && len(filters.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "A saved search needs at least one filter",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM saved_searches WHERE user_id = ?", userID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if count >= maxSavedSearches {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Saved search limit reached",
+			Code:      "LIMIT_EXCEEDED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	encoded, _ := json.Marshal(filters)
+	searchID := utils.GenerateID()
+	_, err := db.Exec("INSERT INTO saved_searches (id, user_id, store_id, name, filters) VALUES (?, ?, ?, ?, ?)",
+		searchID, userID, currentStoreID(c), req.Name, string(encoded))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to save search",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"saved_search": gin.H{
+				"id":       searchID,
+				"user_id":  userID,
+				"store_id": currentStoreID(c),
+				"name":     req.Name,
+				"filters":  filters,
+			},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ListSavedSearches lists the current user's saved searches, newest first
+func ListSavedSearches(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM saved_searches WHERE user_id = ?", userID).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, user_id, store_id, name, filters, last_checked_at, created_at, updated_at
+		FROM saved_searches WHERE user_id = ?
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?
+	`, userID, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		var s models.SavedSearch
+		var filters string
+		err := rows.Scan(&s.ID, &s.UserID, &s.StoreID, &s.Name, &filters, database.UTCTime(&s.LastCheckedAt), database.UTCTime(&s.CreatedAt), database.UTCTime(&s.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		s.Filters = json.RawMessage(filters)
+		searches = append(searches, s)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, searches, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteSavedSearch deletes one of the current user's saved searches
+func DeleteSavedSearch(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+	result, err := db.Exec("DELETE FROM saved_searches WHERE id = ? AND user_id = ?", c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete saved search",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Saved search not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Saved search deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// StartSavedSearchAlerts checks saved searches for newly created matching
+// products every interval and notifies their owners
+func StartSavedSearchAlerts(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			if err := runSavedSearchAlerts(database.GetDB(), time.Now().UTC()); err != nil {
+				log.Println("Saved search alerts:", err)
+			}
+		}
+	}()
+}
+
+// runSavedSearchAlerts notifies each saved search's owner of the products
+// created since the search was last checked, up to now
+func runSavedSearchAlerts(db *sql.DB, now time.Time) error {
+	type savedSearch struct {
+		id, userID, storeID, name, filters, lastCheckedAt string
+	}
+
+	rows, err := db.Query("SELECT id, user_id, store_id, name, filters, last_checked_at FROM saved_searches")
+	if err != nil {
+		return err
+	}
+	var searches []savedSearch
+	for rows.Next() {
+		var s savedSearch
+		if err := rows.Scan(&s.id, &s.userID, &s.storeID, &s.name, &s.filters, &s.lastCheckedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		searches = append(searches, s)
+	}
+	rows.Close()
+
+	checkedAt := now.Format(time.RFC3339)
+	for _, s := range searches {
+		var filters productSearch
+		if err := json.Unmarshal([]byte(s.filters), &filters); err != nil {
+			log.Printf("Saved search %s: invalid filters: %v", s.id, err)
+			continue
+		}
+
+		where, args := filters.where(s.storeID)
+		rows, err := db.Query("SELECT name FROM products WHERE "+where+" AND created_at > ? AND created_at <= ? ORDER BY created_at",
+			append(args, s.lastCheckedAt, checkedAt)...)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err == nil {
+				names = append(names, name)
+			}
+		}
+		rows.Close()
+
+		if len(names) > 0 {
+			examples := names
+			if len(examples) > 3 {
+				examples = examples[:3]
+			}
+			tmpl := notificationTemplates["saved_search_alert"]
+			err := NotifyMany(db, []string{s.userID}, tmpl.Type, tmpl.Title, tmpl.Message,
+				map[string]interface{}{"Name": s.name, "Count": len(names), "Examples": strings.Join(examples, ", ")})
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := db.Exec("UPDATE saved_searches SET last_checked_at = ? WHERE id = ?", checkedAt, s.id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSessionUserAgentLength caps the user agent recorded for a session
+const maxSessionUserAgentLength = 500
+
+// issuedTokens are the tokens a sign-in hands out
+type issuedTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// issueToken starts a session for a user on the requesting device, so the
+// user can see and revoke it, and returns its access and refresh tokens
+func issueToken(c *gin.Context, db *sql.DB, userID, role string) (issuedTokens, error) {
+	token, err := utils.GenerateToken(userID, role)
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		return issuedTokens{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	defer tx.Rollback()
+
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxSessionUserAgentLength {
+		userAgent = userAgent[:maxSessionUserAgentLength]
+	}
+	// The session lasts as long as it can be refreshed
+	_, err = tx.Exec("INSERT INTO sessions (id, user_id, user_agent, ip_address, expires_at) VALUES (?, ?, ?, ?, ?)",
+		claims.SessionID, userID, userAgent, c.ClientIP(), time.Now().Add(utils.RefreshTokenLifetime).UTC().Format(time.RFC3339))
+	if err != nil {
+		return issuedTokens{}, err
+	}
+
+	refreshToken, err := issueRefreshToken(tx, userID, claims.SessionID)
+	if err != nil {
+		return issuedTokens{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return issuedTokens{}, err
+	}
+
+	return issuedTokens{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenLifetime.Seconds()),
+	}, nil
+}
+
+// issueRefreshToken generates a refresh token for a session and records it
+func issueRefreshToken(tx *sql.Tx, userID, sessionID string) (string, error) {
+	refreshToken, err := utils.GenerateRefreshToken(userID)
+	if err != nil {
+		return "", err
+	}
+	claims, err := utils.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec("INSERT INTO refresh_tokens (id, user_id, session_id, expires_at) VALUES (?, ?, ?, ?)",
+		claims.ID, userID, sessionID, claims.ExpiresAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token. Refresh
+// tokens are single use: each refresh revokes the one presented and returns
+// a new one, extending the session. Presenting a refresh token that was
+// already used means it was copied, so the whole session is revoked.
+func RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	invalid := func() {
+		c.JSON(http.StatusUnauthorized, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid or expired refresh token",
+			Code:      "UNAUTHORIZED",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	claims, err := utils.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		invalid()
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	var sessionID, role string
+	var tokenRevoked, sessionRevoked bool
+	var active bool
+	err = tx.QueryRow(`
+		SELECT r.session_id, r.revoked_at IS NOT NULL, s.revoked_at IS NOT NULL, u.role, u.is_active
+		FROM refresh_tokens r
+		JOIN sessions s ON s.id = r.session_id
+		JOIN users u ON u.id = r.user_id
+		WHERE r.id = ? AND r.user_id = ? AND r.expires_at > ?
+	`, claims.ID, claims.UserID, now).Scan(&sessionID, &tokenRevoked, &sessionRevoked, &role, &active)
+	if err == sql.ErrNoRows {
+		invalid()
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if tokenRevoked && !sessionRevoked {
+		if err := revokeSessionTokens(tx, sessionID); err == nil {
+			tx.Commit()
+		}
+	}
+	if tokenRevoked || sessionRevoked || !active {
+		invalid()
+		return
+	}
+
+	token, err := utils.GenerateSessionToken(sessionID, claims.UserID, role)
+	if err == nil {
+		_, err = tx.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE id = ?", now, claims.ID)
+	}
+	var refreshToken string
+	if err == nil {
+		refreshToken, err = issueRefreshToken(tx, claims.UserID, sessionID)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE sessions SET expires_at = ? WHERE id = ?",
+			time.Now().Add(utils.RefreshTokenLifetime).UTC().Format(time.RFC3339), sessionID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to refresh token",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: issuedTokens{
+			Token:        token,
+			RefreshToken: refreshToken,
+			ExpiresIn:    int(utils.AccessTokenLifetime.Seconds()),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// revokeSessionTokens revokes a session and its refresh tokens
+func revokeSessionTokens(ex execer, sessionID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	if _, err := ex.Exec("UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL", now, sessionID); err != nil {
+		return err
+	}
+	_, err := ex.Exec("UPDATE refresh_tokens SET revoked_at = ? WHERE session_id = ? AND revoked_at IS NULL", now, sessionID)
+	return err
+}
+
+// revokeToken rejects a single access token, by jti, until it expires
+func revokeToken(ex execer, tokenID, userID string, expiresAt time.Time) error {
+	_, err := ex.Exec(`
+		INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (jti) DO NOTHING
+	`, tokenID, userID, expiresAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// StartRevokedTokenPurge deletes revoked tokens that have expired every
+// interval. An expired token is rejected anyway, so its entry is no longer
+// needed.
+func StartRevokedTokenPurge(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		for range ticker.C {
+			_, err := database.GetDB().Exec("DELETE FROM revoked_tokens WHERE expires_at < ?",
+				time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				log.Println("Revoked token purge:", err)
+			}
+		}
+	}()
+}
+
+// ListSessions lists the current user's unexpired, unrevoked sessions, most
+// recently active first. The session of the calling token is marked current.
+func ListSessions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	sessionID, _ := c.Get("sessionID")
+
+	rows, err := database.GetDB().Query(`
+		SELECT id, user_agent, ip_address, issued_at, last_seen_at, expires_at FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC, id
+	`, userID, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserAgent, &s.IPAddress, database.UTCTime(&s.IssuedAt), database.UTCTime(&s.LastSeenAt),
+			database.UTCTime(&s.ExpiresAt)); err != nil {
+			continue
+		}
+		s.Current = s.ID == sessionID
+		sessions = append(sessions, s)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(sessions, len(sessions)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// RevokeSession signs one of the current user's sessions out: its access
+// and refresh tokens are rejected from then on, even before they expire
+func RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	db := database.GetDB()
+
+	result, err := db.Exec("UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339), c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to revoke session",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		notFound(c, "Session")
+		return
+	}
+
+	if err := revokeSessionTokens(db, c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to revoke session",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Session revoked"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// cartWeight returns the total billable weight of the items in a cart
+func cartWeight(db *sql.DB, cartID string) (float64, error) {
+	rows, err := db.Query(`
+		SELECT ci.quantity, p.weight, p.length, p.width, p.height
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		WHERE ci.cart_id = ?
+	`, cartID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var total float64
+	for rows.Next() {
+		var quantity int
+		var weight, length, width, height *float64
+		if err := rows.Scan(&quantity, &weight, &length, &width, &height); err != nil {
+			return 0, err
+		}
+		total += utils.BillableWeight(weight, length, width, height) * float64(quantity)
+	}
+
+	return total, rows.Err()
+}
+
+// QuoteShipping quotes the cost of each active shipping method for a set of
+// items, falling back to the current user's cart when no items are given
+func QuoteShipping(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		Items []struct {
+			ProductID string `json:"product_id" binding:"required"`
+			Quantity  int    `json:"quantity" binding:"required,gt=0"`
+		} `json:"items" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var totalWeight float64
+	if len(req.Items) > 0 {
+		for _, item := range req.Items {
+			var weight, length, width, height *float64
+			err := db.QueryRow("SELECT weight, length, width, height FROM products WHERE id = ? AND store_id = ?",
+				item.ProductID, currentStoreID(c)).
+				Scan(&weight, &length, &width, &height)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, models.APIResponse{
+					Success:   false,
+					Error:     "Product not found",
+					Code:      "NOT_FOUND",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Database error",
+					Code:      "INTERNAL_ERROR",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+			totalWeight += utils.BillableWeight(weight, length, width, height) * float64(item.Quantity)
+		}
+	} else {
+		var cartID string
+		err := db.QueryRow("SELECT id FROM carts WHERE user_id = ?", userID).Scan(&cartID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.APIResponse{
+				Success:   false,
+				Error:     "Cart not found",
+				Code:      "NOT_FOUND",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		totalWeight, err = cartWeight(db, cartID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, base_cost, cost_per_kg, estimated_days
+		FROM shipping_methods WHERE is_active = 1
+		ORDER BY base_cost ASC
+	`)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	quotes := []gin.H{}
+	for rows.Next() {
+		var m models.ShippingMethod
+		if err := rows.Scan(&m.ID, &m.Name, &m.BaseCost, &m.CostPerKg, &m.EstimatedDays); err != nil {
+			continue
+		}
+
+		quotes = append(quotes, gin.H{
+			"shipping_method_id": m.ID,
+			"name":               m.Name,
+			"cost":               utils.ShippingCost(m.BaseCost, m.CostPerKg, totalWeight),
+			"estimated_days":     m.EstimatedDays,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"total_weight": totalWeight,
+			"quotes":       quotes,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ListShippingMethods lists every shipping method, including inactive ones,
+// for admins. Paginated unless ?all=true.
+func ListShippingMethods(c *gin.Context) {
+	params, ok := bindListParams(c, utils.ListSpec{Filters: allRowsFilter})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM shipping_methods").Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	page, limit, offset, ok := listWindow(c, params, total)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, name, description, base_cost, cost_per_kg, estimated_days, is_active, created_at, updated_at
+		FROM shipping_methods
+		ORDER BY name
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	methods := []models.ShippingMethod{}
+	for rows.Next() {
+		var m models.ShippingMethod
+		err := rows.Scan(&m.ID, &m.Name, &m.Description, &m.BaseCost, &m.CostPerKg, &m.EstimatedDays, &m.IsActive, database.UTCTime(&m.CreatedAt), database.UTCTime(&m.UpdatedAt))
+		if err != nil {
+			continue
+		}
+		methods = append(methods, m)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, methods, page, limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// deliveryWindowDays is how many days after the earliest estimated date a
+// delivery may still arrive
+const deliveryWindowDays = 2
+
+// GetDeliveryEstimate estimates when a product would be delivered with each
+// active shipping method. Out-of-stock products ship from their restock date;
+// without one no estimate can be given. postal_code is accepted for
+// destination-specific estimates but every method currently uses its
+// estimated_days.
+func GetDeliveryEstimate(c *gin.Context) {
+	productID := c.Param("id")
+	db := database.GetDB()
+
+	var stock int
+	var restockDate *string
+	err := db.QueryRow("SELECT stock_quantity, restock_date FROM products WHERE id = ? AND store_id = ? AND status = 'active'",
+		productID, currentStoreID(c)).Scan(&stock, &restockDate)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Product not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	shipDate := today
+	availability := "in_stock"
+	if stock <= 0 {
+		availability = "out_of_stock"
+		if restockDate != nil {
+			restock, err := time.Parse("2006-01-02", *restockDate)
+			if err == nil && restock.After(today) {
+				availability = "backorder"
+				shipDate = restock
+			}
+		}
+	}
+
+	var shipsOn *string
+	estimates := []gin.H{}
+	if availability != "out_of_stock" {
+		date := shipDate.Format("2006-01-02")
+		shipsOn = &date
+
+		rows, err := db.Query(`
+			SELECT id, name, estimated_days
+			FROM shipping_methods WHERE is_active = 1
+			ORDER BY estimated_days ASC, base_cost ASC
+		`)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m models.ShippingMethod
+			if err := rows.Scan(&m.ID, &m.Name, &m.EstimatedDays); err != nil {
+				continue
+			}
+
+			earliest := shipDate.AddDate(0, 0, m.EstimatedDays)
+			estimates = append(estimates, gin.H{
+				"shipping_method_id": m.ID,
+				"name":               m.Name,
+				"estimated_days":     m.EstimatedDays,
+				"earliest_date":      earliest.Format("2006-01-02"),
+				"latest_date":        earliest.AddDate(0, 0, deliveryWindowDays).Format("2006-01-02"),
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"product_id":   productID,
+			"postal_code":  c.Query("postal_code"),
+			"availability": availability,
+			"ships_on":     shipsOn,
+			"estimates":    estimates,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SubscribeBackInStock asks to be notified when an out-of-stock product is
+// available again. The subscription ends with the notification.
+func SubscribeBackInStock(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	productID := c.Param("id")
+
+	db := database.GetDB()
+
+	var stock int
+	err := db.QueryRow("SELECT stock_quantity FROM products WHERE id = ? AND store_id = ? AND status = 'active'",
+		productID, currentStoreID(c)).Scan(&stock)
+	if err == sql.ErrNoRows {
+		notFound(c, "Product")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if stock > 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Product is in stock",
+			Code:      "IN_STOCK",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	id := utils.GenerateID()
+	result, err := db.Exec(`
+		INSERT INTO stock_subscriptions (id, user_id, product_id) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, product_id) DO NOTHING
+	`, id, userID, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to subscribe",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Already subscribed to this product",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"id":         id,
+			"product_id": productID,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// UnsubscribeBackInStock cancels the current user's back-in-stock
+// subscription for a product
+func UnsubscribeBackInStock(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	result, err := database.GetDB().Exec("DELETE FROM stock_subscriptions WHERE user_id = ? AND product_id = ?",
+		userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to unsubscribe",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		notFound(c, "Subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Unsubscribed"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// notifyBackInStock notifies the subscribers of a product that is in stock
+// and active again and ends their subscriptions. It runs in the transaction
+// that added the stock, so it is called after every stock increase: when the
+// product is still out of stock, or nobody subscribed, it does nothing.
+func notifyBackInStock(tx *sql.Tx, productID string) error {
+	rows, err := tx.Query(`
+		SELECT s.user_id, p.name FROM stock_subscriptions s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.product_id = ? AND p.stock_quantity > 0 AND p.status = 'active'
+	`, productID)
+	if err != nil {
+		return err
+	}
+
+	var userIDs []string
+	var productName string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID, &productName); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	tmpl := notificationTemplates["back_in_stock"]
+	err = NotifyMany(tx, userIDs, tmpl.Type, tmpl.Title, tmpl.Message,
+		map[string]interface{}{"ProductName": productName})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM stock_subscriptions WHERE product_id = ?", productID)
+	return err
+}
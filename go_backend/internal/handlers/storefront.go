@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Featured products per category limits
+const (
+	defaultFeaturedPerCategory = 4
+	maxFeaturedPerCategory     = 20
+)
+
+// ListFeaturedProducts lists the top active products of every category in
+// the current store for the home page, most viewed and then newest first.
+// per_category sets how many products each category gets; categories without
+// active products are listed with none.
+func ListFeaturedProducts(c *gin.Context) {
+	perCategory, err := strconv.Atoi(c.Query("per_category"))
+	if err != nil || perCategory < 1 {
+		perCategory = defaultFeaturedPerCategory
+	}
+	perCategory = min(perCategory, maxFeaturedPerCategory)
+
+	db := database.GetDB()
+	storeID := currentStoreID(c)
+
+	rows, err := db.Query(`
+		SELECT id, name, description, parent_id, image_url FROM categories
+		WHERE store_id = ? ORDER BY name
+	`, storeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	type featuredCategory struct {
+		Category gin.H            `json:"category"`
+		Products []models.Product `json:"products"`
+	}
+	categories := []*featuredCategory{}
+	byID := map[string]*featuredCategory{}
+	for rows.Next() {
+		var cat models.Category
+		if err := rows.Scan(&cat.ID, &cat.Name, &cat.Description, &cat.ParentID, &cat.ImageURL); err != nil {
+			continue
+		}
+		featured := &featuredCategory{
+			Category: gin.H{
+				"id":          cat.ID,
+				"name":        cat.Name,
+				"description": cat.Description,
+				"parent_id":   cat.ParentID,
+				"image_url":   cat.ImageURL,
+			},
+			Products: []models.Product{},
+		}
+		categories = append(categories, featured)
+		byID[cat.ID] = featured
+	}
+	rows.Close()
+
+	// One query for every category: rank each category's products and keep
+	// the first perCategory of each
+	rows, err = db.Query(`
+		SELECT `+productColumns+` FROM products
+		JOIN (
+			SELECT p.id AS ranked_id, ROW_NUMBER() OVER (
+				PARTITION BY p.category_id ORDER BY COALESCE(v.views, 0) DESC, p.created_at DESC, p.id
+			) AS position
+			FROM products p
+			LEFT JOIN product_view_counts v ON v.product_id = p.id
+			WHERE p.store_id = ? AND p.status = 'active'
+		) ranked ON ranked.ranked_id = products.id
+		WHERE ranked.position <= ?
+		ORDER BY products.category_id, ranked.position
+	`, storeID, perCategory)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Product
+		if err := scanProduct(rows, &p); err != nil {
+			continue
+		}
+		if featured, ok := byID[p.CategoryID]; ok {
+			featured.Products = append(featured.Products, p)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"per_category": perCategory,
+			"categories":   categories,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// storeSlugRegex limits slugs to values usable as a subdomain label
+var storeSlugRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ListStores lists stores by name, paginated unless ?all=true
+func ListStores(c *gin.Context) {
+	params, ok := bindListParams(c, utils.ListSpec{Filters: allRowsFilter})
+	if !ok {
+		return
+	}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM stores").Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	page, limit, offset, ok := listWindow(c, params, total)
+	if !ok {
+		return
+	}
+
+	rows, err := db.Query("SELECT id, slug, name, created_at, updated_at FROM stores ORDER BY name LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	stores := []models.Store{}
+	for rows.Next() {
+		var s models.Store
+		if err := rows.Scan(&s.ID, &s.Slug, &s.Name, database.UTCTime(&s.CreatedAt), database.UTCTime(&s.UpdatedAt)); err != nil {
+			continue
+		}
+		stores = append(stores, s)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, stores, page, limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// CreateStore creates a new store addressable by its slug
+func CreateStore(c *gin.Context) {
+	var req struct {
+		Slug string `json:"slug" binding:"required"`
+		Name string `json:"name" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !storeSlugRegex.MatchString(slug) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Slug must contain only lowercase letters, numbers and dashes",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	var existing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM stores WHERE slug = ?", slug).Scan(&existing); err == nil && existing > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Store slug already exists",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	store := models.Store{
+		ID:   utils.GenerateID(),
+		Slug: slug,
+		Name: req.Name,
+	}
+
+	_, err := db.Exec("INSERT INTO stores (id, slug, name) VALUES (?, ?, ?)", store.ID, store.Slug, store.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create store",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"store": store},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
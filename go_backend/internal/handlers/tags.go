@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// productTags returns the names of a product's tags in alphabetical order
+func productTags(q queryExecer, productID string) ([]string, error) {
+	rows, err := q.Query(`
+		SELECT t.name FROM product_tags pt
+		JOIN tags t ON pt.tag_id = t.id
+		WHERE pt.product_id = ?
+		ORDER BY t.name
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, name)
+	}
+	return tags, rows.Err()
+}
+
+// AddProductTags attaches tags to a product, creating tags that don't exist
+// yet. Tag names are normalized, and tags already attached are left as is.
+func AddProductTags(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	var req struct {
+		Tags []string `json:"tags" binding:"required,min=1,dive,max=50"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tags := utils.NormalizeTags(req.Tags)
+	if len(tags) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "At least one non-empty tag is required",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	storeID := currentStoreID(c)
+	for _, tag := range tags {
+		_, err := tx.Exec("INSERT OR IGNORE INTO tags (id, store_id, name) VALUES (?, ?, ?)", utils.GenerateID(), storeID, tag)
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT OR IGNORE INTO product_tags (product_id, tag_id)
+				SELECT ?, id FROM tags WHERE store_id = ? AND name = ?
+			`, productID, storeID, tag)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Failed to add tags",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to add tags",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	current, err := productTags(db, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(current, len(current)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// RemoveProductTag detaches a tag from a product
+func RemoveProductTag(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	role, _ := c.Get("role")
+	productID := c.Param("id")
+
+	tags := utils.NormalizeTags([]string{c.Param("tag")})
+	if len(tags) == 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid tag",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	if !productAccess(c, db, userID, role, productID) {
+		return
+	}
+
+	result, err := db.Exec(`
+		DELETE FROM product_tags
+		WHERE product_id = ? AND tag_id IN (SELECT id FROM tags WHERE store_id = ? AND name = ?)
+	`, productID, currentStoreID(c), tags[0])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to remove tag",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Tag not found on product",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Tag removed"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// productAccess checks that the product exists in the current store and that the
+// user may manage it, writing the error response when not
+func productAccess(c *gin.Context, db *sql.DB, userID, role interface{}, productID string) bool {
+	var exists int
+	err := db.QueryRow("SELECT COUNT(*) FROM products WHERE id = ? AND store_id = ?", productID, currentStoreID(c)).Scan(&exists)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+	if exists == 0 {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Product not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+
+	allowed, err := canManageProduct(db, userID, role, productID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success:   false,
+			Error:     "Access denied",
+			Code:      "FORBIDDEN",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return false
+	}
+
+	return true
+}
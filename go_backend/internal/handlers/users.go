@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateUser creates an account on someone else's behalf with the given role
+// and a generated temporary password. The password is only ever returned in
+// this response; the admin passes it on and the user should change it.
+func CreateUser(c *gin.Context) {
+	var req struct {
+		Email     string  `json:"email" binding:"required,email"`
+		FirstName string  `json:"first_name" binding:"required"`
+		LastName  string  `json:"last_name" binding:"required"`
+		Phone     *string `json:"phone"`
+		Role      string  `json:"role" binding:"required,oneof=admin customer vendor"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if !utils.IsValidEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid email format",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	taken, err := emailRegistered(db, req.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Email already registered",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to start transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	password := utils.GenerateTemporaryPassword()
+	user := models.User{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Phone:     req.Phone,
+		Role:      req.Role,
+	}
+	if err := createUser(tx, &user, password); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create user",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err := recordAudit(tx, c, "user_create", "user", user.ID, gin.H{
+		"email": user.Email,
+		"role":  user.Role,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to create user",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to commit transaction",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"user":               user,
+			"temporary_password": password,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// defaultInactiveAge is how long ago users must have last logged in to be
+// reported as inactive when no since date is given
+const defaultInactiveAge = 90 * 24 * time.Hour
+
+// ListInactiveUsers reports accounts nobody has logged in to since a date,
+// given as YYYY-MM-DD or an RFC 3339 timestamp (default 90 days ago). Users
+// who never logged in are included when their account is older than that
+// date, and listed first; the rest follow from the longest inactive.
+func ListInactiveUsers(c *gin.Context) {
+	since := time.Now().UTC().Add(-defaultInactiveAge)
+	if value := c.Query("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			t, err = time.Parse("2006-01-02", value)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Error:     "Invalid since, expected YYYY-MM-DD or an RFC 3339 timestamp",
+				Code:      "VALIDATION_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		since = t.UTC()
+	}
+	cutoff := since.Format(time.RFC3339)
+
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+
+	where := "(last_login_at < ? OR (last_login_at IS NULL AND created_at < ?))"
+	args := []interface{}{cutoff, cutoff}
+
+	db := database.GetDB()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE "+where, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT id, email, first_name, last_name, role, is_active, last_login_at, created_at
+		FROM users WHERE `+where+`
+		ORDER BY last_login_at, created_at, id
+		LIMIT ? OFFSET ?
+	`, append(args, params.Limit, params.Offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	users := []gin.H{}
+	for rows.Next() {
+		var id, email, firstName, lastName, role, createdAt string
+		var isActive bool
+		var lastLoginAt *string
+		err := rows.Scan(&id, &email, &firstName, &lastName, &role, &isActive, &lastLoginAt, &createdAt)
+		if err != nil {
+			continue
+		}
+		users = append(users, gin.H{
+			"id":            id,
+			"email":         email,
+			"first_name":    firstName,
+			"last_name":     lastName,
+			"role":          role,
+			"is_active":     isActive,
+			"last_login_at": lastLoginAt,
+			"created_at":    createdAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, users, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
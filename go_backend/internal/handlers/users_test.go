@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestListInactiveUsers(t *testing.T) {
+	admin := createTestUser(t, "admin")
+	old := time.Now().UTC().AddDate(-1, 0, 0).Format(time.RFC3339)
+	recent := time.Now().UTC().Format(time.RFC3339)
+
+	stale := createTestUser(t, "customer")
+	mustExec(t, "UPDATE users SET last_login_at = ? WHERE id = ?", old, stale)
+	neverLoggedIn := createTestUser(t, "customer")
+	mustExec(t, "UPDATE users SET created_at = ? WHERE id = ?", old, neverLoggedIn)
+	active := createTestUser(t, "customer")
+	mustExec(t, "UPDATE users SET last_login_at = ?, created_at = ? WHERE id = ?", recent, old, active)
+	newUser := createTestUser(t, "customer")
+
+	since := time.Now().UTC().AddDate(0, -1, 0).Format("2006-01-02")
+	res := serve(t, ListInactiveUsers, http.MethodGet, "/admin/users/inactive", "/admin/users/inactive?limit=100&since="+since, admin, "admin", nil)
+	expectStatus(t, res, http.StatusOK, "")
+
+	ids := listedIDs(res)
+	if !ids[stale] || !ids[neverLoggedIn] {
+		t.Errorf("stale accounts missing from %v", ids)
+	}
+	if ids[active] || ids[newUser] {
+		t.Errorf("active accounts listed in %v", ids)
+	}
+
+	expectStatus(t, serve(t, ListInactiveUsers, http.MethodGet, "/admin/users/inactive", "/admin/users/inactive?since=last-year", admin, "admin", nil),
+		http.StatusBadRequest, "VALIDATION_ERROR")
+}
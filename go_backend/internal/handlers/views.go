@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// productViewWindow is how long repeat views of a product by the same viewer
+// count as a single view
+const productViewWindow = 30 * time.Minute
+
+// recentViews remembers when each viewer last had a view of each product
+// counted, keyed by viewer and product id
+var recentViews = struct {
+	sync.Mutex
+	seen       map[string]time.Time
+	lastPruned time.Time
+}{seen: map[string]time.Time{}}
+
+// recordProductView counts a view of a product in the background, unless the
+// same viewer's view of it was counted within productViewWindow. Viewers are
+// identified by user id when signed in and by IP address otherwise.
+func recordProductView(c *gin.Context, productID string) {
+	viewer := "ip:" + c.ClientIP()
+	if userID, ok := c.Get("userID"); ok {
+		viewer = "user:" + userID.(string)
+	}
+	key := viewer + "|" + productID
+	now := time.Now()
+
+	recentViews.Lock()
+	if now.Sub(recentViews.lastPruned) > productViewWindow {
+		for k, seenAt := range recentViews.seen {
+			if now.Sub(seenAt) > productViewWindow {
+				delete(recentViews.seen, k)
+			}
+		}
+		recentViews.lastPruned = now
+	}
+	if seenAt, ok := recentViews.seen[key]; ok && now.Sub(seenAt) <= productViewWindow {
+		recentViews.Unlock()
+		return
+	}
+	recentViews.seen[key] = now
+	recentViews.Unlock()
+
+	viewedAt := now.UTC().Format(time.RFC3339)
+	go func() {
+		_, err := database.GetDB().Exec(`
+			INSERT INTO product_view_counts (product_id, views, last_viewed_at) VALUES (?, 1, ?)
+			ON CONFLICT(product_id) DO UPDATE SET views = views + 1, last_viewed_at = excluded.last_viewed_at
+		`, productID, viewedAt)
+		if err != nil {
+			log.Printf("Failed to record view of product %s: %v", productID, err)
+		}
+	}()
+}
+
+// ListPopularProducts lists the current store's active products that have
+// been viewed, most viewed first
+func ListPopularProducts(c *gin.Context) {
+	params, ok := bindListParams(c, utils.ListSpec{})
+	if !ok {
+		return
+	}
+	storeID := currentStoreID(c)
+
+	db := database.GetDB()
+
+	var total int
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM products p
+		JOIN product_view_counts v ON v.product_id = p.id
+		WHERE p.store_id = ? AND p.status = 'active'
+	`, storeID).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	rows, err := db.Query(`
+		SELECT `+productColumns+`, v.views FROM products
+		JOIN product_view_counts v ON v.product_id = products.id
+		WHERE products.store_id = ? AND products.status = 'active'
+		ORDER BY v.views DESC, v.last_viewed_at DESC, products.id
+		LIMIT ? OFFSET ?
+	`, storeID, params.Limit, params.Offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		var p models.Product
+		var views int
+		if err := scanProduct(rows, &p, &views); err != nil {
+			continue
+		}
+		p.Views = &views
+		products = append(products, p)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      paginated(c, products, params.Page, params.Limit, total),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
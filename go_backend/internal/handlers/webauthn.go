@@ -0,0 +1,628 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// The relying party passkeys are registered with. Browsers only use a
+// passkey on the RP ID's domain, from one of the allowed origins.
+var (
+	webAuthnRPID    = "localhost"
+	webAuthnRPName  = "E-commerce API"
+	webAuthnOrigins = []string{"http://localhost:3000"}
+)
+
+// SetWebAuthnRelyingParty sets the domain, display name and allowed origins
+// used for passkey registration and login
+func SetWebAuthnRelyingParty(rpID, rpName string, origins []string) {
+	webAuthnRPID = rpID
+	webAuthnRPName = rpName
+	webAuthnOrigins = origins
+}
+
+// webAuthnChallengeTTL is how long a registration or login ceremony may take
+const webAuthnChallengeTTL = 5 * time.Minute
+
+// Ceremonies a challenge is issued for
+const (
+	webAuthnRegistration = "webauthn.create"
+	webAuthnLogin        = "webauthn.get"
+)
+
+type webAuthnChallenge struct {
+	ceremony string
+	// userID is the user the ceremony is for; empty for a login that lets
+	// the authenticator pick any of its passkeys
+	userID  string
+	expires time.Time
+}
+
+// webAuthnChallenges holds outstanding challenges; each can be used once
+var webAuthnChallenges = struct {
+	sync.Mutex
+	entries map[string]webAuthnChallenge
+}{entries: map[string]webAuthnChallenge{}}
+
+// issueWebAuthnChallenge creates a challenge for a ceremony, returning it
+// base64url encoded as it appears in the client data
+func issueWebAuthnChallenge(ceremony, userID string) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+
+	webAuthnChallenges.Lock()
+	defer webAuthnChallenges.Unlock()
+
+	now := time.Now()
+	for key, entry := range webAuthnChallenges.entries {
+		if now.After(entry.expires) {
+			delete(webAuthnChallenges.entries, key)
+		}
+	}
+	webAuthnChallenges.entries[challenge] = webAuthnChallenge{ceremony: ceremony, userID: userID, expires: now.Add(webAuthnChallengeTTL)}
+
+	return challenge
+}
+
+// consumeWebAuthnChallenge removes a challenge, returning it if it was issued
+// for the ceremony and has not expired
+func consumeWebAuthnChallenge(challenge, ceremony string) (webAuthnChallenge, bool) {
+	webAuthnChallenges.Lock()
+	defer webAuthnChallenges.Unlock()
+
+	entry, ok := webAuthnChallenges.entries[challenge]
+	delete(webAuthnChallenges.entries, challenge)
+	if !ok || entry.ceremony != ceremony || time.Now().After(entry.expires) {
+		return webAuthnChallenge{}, false
+	}
+	return entry, true
+}
+
+// decodeWebAuthnBytes decodes a base64url value as sent by WebAuthn clients,
+// with or without padding
+func decodeWebAuthnBytes(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// verifyClientData checks the client data of a ceremony and consumes its
+// challenge
+func verifyClientData(clientDataJSON []byte, ceremony string) (webAuthnChallenge, error) {
+	var clientData struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return webAuthnChallenge{}, errors.New("invalid client data")
+	}
+	if clientData.Type != ceremony {
+		return webAuthnChallenge{}, errors.New("unexpected ceremony type")
+	}
+
+	originAllowed := false
+	for _, origin := range webAuthnOrigins {
+		if clientData.Origin == origin {
+			originAllowed = true
+		}
+	}
+	if !originAllowed {
+		return webAuthnChallenge{}, errors.New("origin not allowed")
+	}
+
+	challenge, ok := consumeWebAuthnChallenge(strings.TrimRight(clientData.Challenge, "="), ceremony)
+	if !ok {
+		return webAuthnChallenge{}, errors.New("unknown or expired challenge")
+	}
+	return challenge, nil
+}
+
+func respondWebAuthnFailure(c *gin.Context, err error) {
+	c.JSON(http.StatusUnauthorized, models.APIResponse{
+		Success:   false,
+		Error:     "Passkey verification failed: " + err.Error(),
+		Code:      "WEBAUTHN_VERIFICATION_FAILED",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// userCredentialDescriptors lists a user's passkeys as credential descriptors
+func userCredentialDescriptors(db *sql.DB, userID string) ([]gin.H, error) {
+	rows, err := db.Query("SELECT credential_id FROM webauthn_credentials WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	descriptors := []gin.H{}
+	for rows.Next() {
+		var credentialID string
+		if err := rows.Scan(&credentialID); err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, gin.H{"type": "public-key", "id": credentialID})
+	}
+	return descriptors, rows.Err()
+}
+
+// BeginWebAuthnRegistration returns the options for navigator.credentials.create
+// to register a passkey for the current user
+func BeginWebAuthnRegistration(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	db := database.GetDB()
+
+	var email, firstName, lastName string
+	err := db.QueryRow("SELECT email, first_name, last_name FROM users WHERE id = ?", userID).Scan(&email, &firstName, &lastName)
+	if err == sql.ErrNoRows {
+		notFound(c, "User")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	existing, err := userCredentialDescriptors(db, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"publicKey": gin.H{
+				"challenge": issueWebAuthnChallenge(webAuthnRegistration, userID.(string)),
+				"rp":        gin.H{"id": webAuthnRPID, "name": webAuthnRPName},
+				"user": gin.H{
+					"id":          base64.RawURLEncoding.EncodeToString([]byte(userID.(string))),
+					"name":        email,
+					"displayName": firstName + " " + lastName,
+				},
+				"pubKeyCredParams": []gin.H{
+					{"type": "public-key", "alg": utils.COSEAlgES256},
+					{"type": "public-key", "alg": utils.COSEAlgEdDSA},
+					{"type": "public-key", "alg": utils.COSEAlgRS256},
+				},
+				"timeout":            webAuthnChallengeTTL.Milliseconds(),
+				"excludeCredentials": existing,
+				"attestation":        "none",
+				"authenticatorSelection": gin.H{
+					"residentKey":      "preferred",
+					"userVerification": "preferred",
+				},
+			},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's response and stores
+// the new passkey's public key
+func FinishWebAuthnRegistration(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var req struct {
+		ID       string `json:"id" binding:"required"`
+		Name     string `json:"name" binding:"max=100"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+			AttestationObject string `json:"attestationObject" binding:"required"`
+		} `json:"response" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	clientDataJSON, err1 := decodeWebAuthnBytes(req.Response.ClientDataJSON)
+	attestationObject, err2 := decodeWebAuthnBytes(req.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Response fields must be base64url encoded",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	challenge, err := verifyClientData(clientDataJSON, webAuthnRegistration)
+	if err == nil && challenge.userID != userID.(string) {
+		err = errors.New("challenge was issued to another user")
+	}
+	if err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+
+	rawAuthData, err := utils.ParseAttestationObject(attestationObject)
+	if err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+	authData, err := utils.ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+	if !utils.RPIDHashMatches(authData, webAuthnRPID) {
+		respondWebAuthnFailure(c, errors.New("relying party mismatch"))
+		return
+	}
+	if authData.Flags&utils.AuthenticatorUserPresent == 0 {
+		respondWebAuthnFailure(c, errors.New("user not present"))
+		return
+	}
+	if authData.CredentialID == nil {
+		respondWebAuthnFailure(c, errors.New("no credential attested"))
+		return
+	}
+	if _, _, err := utils.ParseCOSEKey(authData.PublicKey); err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+
+	credential := models.WebAuthnCredential{
+		ID:           utils.GenerateID(),
+		UserID:       userID.(string),
+		CredentialID: base64.RawURLEncoding.EncodeToString(authData.CredentialID),
+		Name:         req.Name,
+		SignCount:    int64(authData.SignCount),
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	}
+	if credential.Name == "" {
+		credential.Name = "Passkey"
+	}
+
+	db := database.GetDB()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM webauthn_credentials WHERE credential_id = ?", credential.CredentialID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Error:     "Passkey already registered",
+			Code:      "CONFLICT",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, name, sign_count)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, credential.ID, credential.UserID, credential.CredentialID, authData.PublicKey, credential.Name, credential.SignCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to save passkey",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"credential": credential},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// BeginWebAuthnLogin returns the options for navigator.credentials.get. With
+// an email only that user's passkeys are allowed; without one the
+// authenticator offers any passkey it holds for this site.
+func BeginWebAuthnLogin(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	db := database.GetDB()
+
+	userID := ""
+	allowCredentials := []gin.H{}
+	if req.Email != "" {
+		// An unknown email gets an empty allow list rather than an error,
+		// so this endpoint cannot be used to discover accounts
+		err := db.QueryRow("SELECT id FROM users WHERE email = ?", req.Email).Scan(&userID)
+		if err != nil && err != sql.ErrNoRows {
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Error:     "Database error",
+				Code:      "INTERNAL_ERROR",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+		if userID != "" {
+			allowCredentials, err = userCredentialDescriptors(db, userID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Database error",
+					Code:      "INTERNAL_ERROR",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"publicKey": gin.H{
+				"challenge":        issueWebAuthnChallenge(webAuthnLogin, userID),
+				"rpId":             webAuthnRPID,
+				"timeout":          webAuthnChallengeTTL.Milliseconds(),
+				"allowCredentials": allowCredentials,
+				"userVerification": "preferred",
+			},
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// FinishWebAuthnLogin verifies a passkey assertion and issues the same token
+// as a password login
+func FinishWebAuthnLogin(c *gin.Context) {
+	var req struct {
+		ID       string `json:"id" binding:"required"`
+		Response struct {
+			ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+			AuthenticatorData string `json:"authenticatorData" binding:"required"`
+			Signature         string `json:"signature" binding:"required"`
+		} `json:"response" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Invalid request body",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	clientDataJSON, err1 := decodeWebAuthnBytes(req.Response.ClientDataJSON)
+	rawAuthData, err2 := decodeWebAuthnBytes(req.Response.AuthenticatorData)
+	signature, err3 := decodeWebAuthnBytes(req.Response.Signature)
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Error:     "Response fields must be base64url encoded",
+			Code:      "VALIDATION_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	challenge, err := verifyClientData(clientDataJSON, webAuthnLogin)
+	if err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+
+	db := database.GetDB()
+
+	var credentialID, userID string
+	var publicKey []byte
+	var signCount int64
+	err = db.QueryRow("SELECT id, user_id, public_key, sign_count FROM webauthn_credentials WHERE credential_id = ?",
+		strings.TrimRight(req.ID, "=")).Scan(&credentialID, &userID, &publicKey, &signCount)
+	if err == sql.ErrNoRows || (err == nil && challenge.userID != "" && challenge.userID != userID) {
+		respondWebAuthnFailure(c, errors.New("unknown passkey"))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	authData, err := utils.ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+	if !utils.RPIDHashMatches(authData, webAuthnRPID) {
+		respondWebAuthnFailure(c, errors.New("relying party mismatch"))
+		return
+	}
+	if authData.Flags&utils.AuthenticatorUserPresent == 0 {
+		respondWebAuthnFailure(c, errors.New("user not present"))
+		return
+	}
+	if err := utils.VerifyWebAuthnSignature(publicKey, rawAuthData, clientDataJSON, signature); err != nil {
+		respondWebAuthnFailure(c, err)
+		return
+	}
+	// A counter that fails to increase suggests a cloned authenticator;
+	// authenticators that don't count always report 0
+	if (authData.SignCount != 0 || signCount != 0) && int64(authData.SignCount) <= signCount {
+		respondWebAuthnFailure(c, errors.New("signature counter did not increase"))
+		return
+	}
+
+	_, err = db.Exec("UPDATE webauthn_credentials SET sign_count = ?, last_used_at = ? WHERE id = ?",
+		authData.SignCount, time.Now().UTC().Format(time.RFC3339), credentialID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	var user models.User
+	err = db.QueryRow(`
+		SELECT id, email, first_name, last_name, phone, role, is_active, email_verified, created_at, updated_at
+		FROM users WHERE id = ?
+	`, userID).Scan(
+		&user.ID, &user.Email, &user.FirstName, &user.LastName,
+		&user.Phone, &user.Role, &user.IsActive, &user.EmailVerified,
+		database.UTCTime(&user.CreatedAt), database.UTCTime(&user.UpdatedAt),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success:   false,
+			Error:     "Account is inactive",
+			Code:      "FORBIDDEN",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	tokens, err := issueToken(c, db, user.ID, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to generate token",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	recordLogin(db, user.ID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Data: gin.H{
+			"token":         tokens.Token,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+			"user":          user,
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// ListWebAuthnCredentials lists the current user's passkeys
+func ListWebAuthnCredentials(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	rows, err := database.GetDB().Query(`
+		SELECT id, user_id, credential_id, name, sign_count, last_used_at, created_at, updated_at
+		FROM webauthn_credentials WHERE user_id = ?
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Database error",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+	defer rows.Close()
+
+	credentials := []models.WebAuthnCredential{}
+	for rows.Next() {
+		var cred models.WebAuthnCredential
+		if err := rows.Scan(&cred.ID, &cred.UserID, &cred.CredentialID, &cred.Name, &cred.SignCount,
+			database.NullUTCTime(&cred.LastUsedAt), database.UTCTime(&cred.CreatedAt), database.UTCTime(&cred.UpdatedAt)); err != nil {
+			continue
+		}
+		credentials = append(credentials, cred)
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      singlePage(credentials, len(credentials)),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// DeleteWebAuthnCredential removes one of the current user's passkeys
+func DeleteWebAuthnCredential(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	result, err := database.GetDB().Exec("DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Error:     "Failed to delete passkey",
+			Code:      "INTERNAL_ERROR",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		notFound(c, "Passkey")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Data:      gin.H{"message": "Passkey deleted"},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
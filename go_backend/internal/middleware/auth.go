@@ -5,20 +5,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
 	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
+// authenticatedKey marks a request whose token was already validated, so the
+// route's AuthMiddleware doesn't repeat the revocation lookups
+// OptionalAuthMiddleware made
+const authenticatedKey = "authenticated"
+
 // AuthMiddleware validates JWT tokens
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetBool(authenticatedKey) {
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success":   false,
 				"error":     "Authorization header required",
 				"code":      "UNAUTHORIZED",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
@@ -31,32 +42,81 @@ func AuthMiddleware() gin.HandlerFunc {
 				"success":   false,
 				"error":     "Invalid authorization header format",
 				"code":      "UNAUTHORIZED",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
 		}
 
 		token := parts[1]
-		userID, role, err := utils.ValidateToken(token)
+		claims, err := utils.ParseToken(token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success":   false,
 				"error":     "Invalid or expired token",
 				"code":      "UNAUTHORIZED",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+
+		revoked, err := database.TokenRevoked(claims.ID, claims.SessionID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success":   false,
+				"error":     "Database error",
+				"code":      "INTERNAL_ERROR",
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success":   false,
+				"error":     "Token has been revoked",
+				"code":      "UNAUTHORIZED",
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
 		}
 
-		// Store user info in context
-		c.Set("userID", userID)
-		c.Set("role", role)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
 
+// OptionalAuthMiddleware stores the user of a valid bearer token in the
+// context and lets every request through, so middleware that runs before
+// the route's AuthMiddleware (e.g. the rate limiter) can tell who is calling.
+// AuthMiddleware then reuses the validated claims.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			claims, err := utils.ParseToken(parts[1])
+			if err == nil {
+				if revoked, err := database.TokenRevoked(claims.ID, claims.SessionID, true); err == nil && !revoked {
+					setClaims(c, claims)
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// setClaims stores the user of a validated token in the context
+func setClaims(c *gin.Context, claims *utils.TokenClaims) {
+	c.Set("userID", claims.UserID)
+	c.Set("role", claims.Role)
+	c.Set("tokenID", claims.ID)
+	c.Set("tokenExpiresAt", claims.ExpiresAt)
+	c.Set("sessionID", claims.SessionID)
+	c.Set(authenticatedKey, true)
+}
+
 // RequireRole checks if user has required role
 func RequireRole(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -66,7 +126,7 @@ func RequireRole(requiredRole string) gin.HandlerFunc {
 				"success":   false,
 				"error":     "Access denied",
 				"code":      "FORBIDDEN",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
@@ -77,7 +137,7 @@ func RequireRole(requiredRole string) gin.HandlerFunc {
 				"success":   false,
 				"error":     "Access denied",
 				"code":      "FORBIDDEN",
-				"timestamp": time.Now().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareReusesOptionalAuthClaims(t *testing.T) {
+	userID := utils.GenerateID()
+	if _, err := database.GetDB().Exec("INSERT INTO users (id, email, password_hash, first_name, last_name, role) VALUES (?, ?, 'x', 'Test', 'User', 'customer')",
+		userID, userID+"@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	token, err := utils.GenerateToken(userID, "customer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoking the token after OptionalAuthMiddleware checked it shows
+	// whether AuthMiddleware looks it up again
+	revoke := func(c *gin.Context) {
+		database.GetDB().Exec("INSERT OR IGNORE INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, '2099-01-01T00:00:00Z')",
+			claims.ID, userID)
+		c.Next()
+	}
+	header := http.Header{"Authorization": {"Bearer " + token}}
+
+	r := gin.New()
+	r.GET("/reused", OptionalAuthMiddleware(), revoke, AuthMiddleware(), func(c *gin.Context) {
+		if c.GetString("userID") != userID || c.GetString("role") != "customer" {
+			t.Errorf("userID = %q, role = %q", c.GetString("userID"), c.GetString("role"))
+		}
+		ok(c)
+	})
+	r.GET("/checked", AuthMiddleware(), ok)
+
+	if w := serve(r, http.MethodGet, "/reused", header); w.Code != http.StatusOK {
+		t.Fatalf("with OptionalAuthMiddleware: got %d, want 200", w.Code)
+	}
+	if w := serve(r, http.MethodGet, "/checked", header); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked token: got %d, want 401", w.Code)
+	}
+}
+
+func TestAuthMiddlewareRejectsMissingToken(t *testing.T) {
+	r := gin.New()
+	r.GET("/", OptionalAuthMiddleware(), AuthMiddleware(), ok)
+
+	for _, header := range []http.Header{
+		{},
+		{"Authorization": {"Bearer not-a-token"}},
+		{"Authorization": {"Basic dXNlcjpwYXNz"}},
+	} {
+		if w := serve(r, http.MethodGet, "/", header); w.Code != http.StatusUnauthorized {
+			t.Errorf("%v: got %d, want 401", header, w.Code)
+		}
+	}
+}
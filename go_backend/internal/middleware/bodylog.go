@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLogConfig controls the debug request/response body logger
+type BodyLogConfig struct {
+	// RedactFields are JSON keys (case-insensitive, at any depth) whose values are masked
+	RedactFields []string
+	// RedactHeaders are request headers whose values are masked
+	RedactHeaders []string
+	// MaxBytes caps how much of each body is logged
+	MaxBytes int
+}
+
+// DefaultBodyLogConfig returns the default redaction rules
+func DefaultBodyLogConfig() BodyLogConfig {
+	return BodyLogConfig{
+		RedactFields:  []string{"password", "password_confirm", "token", "refresh_token", "access_token"},
+		RedactHeaders: []string{"Authorization"},
+		MaxBytes:      4096,
+	}
+}
+
+const redacted = "[REDACTED]"
+
+// bodyLogWriter tees everything written to the response into a buffer
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// BodyLogMiddleware logs request and response bodies with sensitive fields
+// redacted. It is meant for debugging only and buffers the request body so
+// handlers can still read it.
+func BodyLogMiddleware(cfg BodyLogConfig) gin.HandlerFunc {
+	fields := map[string]bool{}
+	for _, f := range cfg.RedactFields {
+		fields[strings.ToLower(strings.TrimSpace(f))] = true
+	}
+
+	return func(c *gin.Context) {
+		var requestBody []byte
+		if c.Request.Body != nil {
+			requestBody, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(requestBody))
+		}
+
+		writer := &bodyLogWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+
+		c.Next()
+
+		headers := map[string]string{}
+		for name := range c.Request.Header {
+			headers[name] = c.Request.Header.Get(name)
+		}
+		headerJSON, _ := json.Marshal(redactHeaders(headers, cfg.RedactHeaders))
+
+		log.Printf("[body] request_id=%s %s %s status=%d headers=%s request=%s response=%s",
+			c.GetString("requestID"), c.Request.Method, c.Request.URL.RequestURI(), c.Writer.Status(),
+			headerJSON,
+			truncateBody(RedactBody(requestBody, fields), cfg.MaxBytes),
+			truncateBody(RedactBody(writer.body.Bytes(), fields), cfg.MaxBytes))
+	}
+}
+
+// redactHeaders masks the named headers, matching names case-insensitively
+func redactHeaders(headers map[string]string, names []string) map[string]string {
+	for key := range headers {
+		for _, name := range names {
+			if strings.EqualFold(key, name) {
+				headers[key] = redacted
+			}
+		}
+	}
+	return headers
+}
+
+// RedactBody masks the values of the given (lowercased) keys anywhere in a
+// JSON body. Bodies that are not JSON are returned unchanged.
+func RedactBody(body []byte, fields map[string]bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+
+	out, err := json.Marshal(redactValue(value, fields))
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
+
+func redactValue(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if fields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(inner, fields)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner, fields)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func truncateBody(body string, maxBytes int) string {
+	if maxBytes > 0 && len(body) > maxBytes {
+		return body[:maxBytes] + "...(truncated)"
+	}
+	return body
+}
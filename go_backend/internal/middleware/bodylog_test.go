@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactBody(t *testing.T) {
+	fields := map[string]bool{"password": true, "token": true}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"empty", ``, ``},
+		{"not JSON", `password=hunter2`, `password=hunter2`},
+		{"top level", `{"email":"a@b.c","password":"hunter2"}`, `{"email":"a@b.c","password":"[REDACTED]"}`},
+		{"any case", `{"Password":"hunter2"}`, `{"Password":"[REDACTED]"}`},
+		{"nested", `{"user":{"token":"abc","name":"x"}}`, `{"user":{"name":"x","token":"[REDACTED]"}}`},
+		{"in arrays", `[{"token":"abc"},{"token":{"value":"abc"}}]`, `[{"token":"[REDACTED]"},{"token":"[REDACTED]"}]`},
+		{"other values", `{"count":3,"ok":true,"items":null}`, `{"count":3,"items":null,"ok":true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RedactBody([]byte(tt.body), fields)
+			if !sameJSON(got, tt.want) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// sameJSON compares two JSON documents, or two strings that aren't JSON
+func sameJSON(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func TestBodyLogMiddlewareRedactsLog(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(io.Discard)
+
+	r := gin.New()
+	r.Use(BodyLogMiddleware(DefaultBodyLogConfig()))
+	r.POST("/login", func(c *gin.Context) {
+		var req struct {
+			Password string `json:"password"`
+		}
+		c.ShouldBindJSON(&req)
+		c.JSON(http.StatusOK, gin.H{"access_token": "secret-token", "echo": req.Password == "hunter2"})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"a@b.c","password":"hunter2"}`))
+	req.Header.Set("Authorization", "Bearer secret-bearer")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The handler still read the body
+	if !strings.Contains(w.Body.String(), `"echo":true`) {
+		t.Fatalf("handler didn't get the request body: %s", w.Body.String())
+	}
+	for _, secret := range []string{"hunter2", "secret-token", "secret-bearer"} {
+		if strings.Contains(logged.String(), secret) {
+			t.Errorf("log contains %q: %s", secret, logged.String())
+		}
+	}
+	if !strings.Contains(logged.String(), "a@b.c") {
+		t.Errorf("log is missing the rest of the body: %s", logged.String())
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// busyRetryAfter is the Retry-After, in seconds, sent with 503 BUSY
+const busyRetryAfter = "5"
+
+// ConcurrencyLimitMiddleware lets at most limit requests run the routes it
+// guards at once. Requests beyond that are turned away with 503 BUSY instead
+// of queueing, so expensive handlers can't pile up queries on the database.
+// Routes sharing one returned middleware share its limit; a limit below 1
+// disables it.
+func ConcurrencyLimitMiddleware(limit int) gin.HandlerFunc {
+	if limit < 1 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	slots := make(chan struct{}, limit)
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			c.Next()
+		default:
+			c.Header("Retry-After", busyRetryAfter)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.APIResponse{
+				Success:   false,
+				Error:     "Server is busy, try again shortly",
+				Code:      "BUSY",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestConcurrencyLimitMiddlewareRespondsBusy(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	limit := ConcurrencyLimitMiddleware(2)
+	r := gin.New()
+	r.GET("/slow", limit, func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		ok(c)
+	})
+	r.GET("/fast", limit, ok)
+
+	// Fill both slots
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- serve(r, http.MethodGet, "/slow", nil).Code }()
+		<-entered
+	}
+
+	// Routes sharing the middleware share its slots
+	w := serve(r, http.MethodGet, "/fast", nil)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != busyRetryAfter {
+		t.Fatalf("got %d with Retry-After %q, want 503 with %s", w.Code, w.Header().Get("Retry-After"), busyRetryAfter)
+	}
+	var res models.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil || res.Code != "BUSY" {
+		t.Fatalf("got %s, want a BUSY envelope", w.Body.String())
+	}
+
+	// Slots are given back once requests finish
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != http.StatusOK {
+			t.Fatalf("slow request: got %d, want 200", code)
+		}
+	}
+	if w := serve(r, http.MethodGet, "/fast", nil); w.Code != http.StatusOK {
+		t.Fatalf("after release: got %d, want 200", w.Code)
+	}
+}
+
+func TestConcurrencyLimitMiddlewareDisabled(t *testing.T) {
+	r := gin.New()
+	r.GET("/", ConcurrencyLimitMiddleware(0), ok)
+	if w := serve(r, http.MethodGet, "/", nil); w.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", w.Code)
+	}
+}
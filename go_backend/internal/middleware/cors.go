@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig is a cross-origin policy. AllowedOrigins lists the origins
+// (e.g. "https://shop.example.com") allowed to call the API from a browser;
+// "*" allows any origin.
+type CORSConfig struct {
+	AllowedOrigins []string
+	AllowMethods   string
+	AllowHeaders   string
+	ExposeHeaders  string
+}
+
+// DefaultCORSConfig returns the policy for public endpoints: any origin
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowMethods:   "GET, POST, PUT, DELETE, OPTIONS",
+		AllowHeaders:   "Content-Type, Authorization, X-Store-ID",
+		ExposeHeaders:  "Link",
+	}
+}
+
+// ParseCORSOrigins splits a comma-separated origin list, dropping blanks and
+// trailing slashes
+func ParseCORSOrigins(list string) []string {
+	var origins []string
+	for _, origin := range strings.Split(list, ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// CORSMiddleware applies the CORS policy of the route group a request is
+// for. groups maps a group's path prefix (e.g. "/api/v1/admin") to its
+// policy; the longest matching prefix wins and defaultConfig covers every
+// other path. It runs on the engine rather than on the groups themselves so
+// that preflight OPTIONS requests, which match no route, get their group's
+// policy too. Requests from an origin the policy doesn't allow are rejected
+// with 403; requests without an Origin header, i.e. not from a browser, are
+// let through.
+func CORSMiddleware(defaultConfig CORSConfig, groups map[string]CORSConfig) gin.HandlerFunc {
+	prefixes := make([]string, 0, len(groups))
+	for prefix := range groups {
+		prefixes = append(prefixes, prefix)
+	}
+	// Longest first, so the most specific group matches
+	slices.SortFunc(prefixes, func(a, b string) int { return len(b) - len(a) })
+
+	return func(c *gin.Context) {
+		cfg := defaultConfig
+		for _, prefix := range prefixes {
+			if c.Request.URL.Path == prefix || strings.HasPrefix(c.Request.URL.Path, prefix+"/") {
+				cfg = groups[prefix]
+				break
+			}
+		}
+
+		origin := c.GetHeader("Origin")
+		switch {
+		case slices.Contains(cfg.AllowedOrigins, "*"):
+			c.Header("Access-Control-Allow-Origin", "*")
+		case origin == "":
+		case slices.Contains(cfg.AllowedOrigins, origin):
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		default:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success":   false,
+				"error":     "Origin not allowed",
+				"code":      "CORS_ORIGIN_DENIED",
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			})
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", cfg.AllowMethods)
+		c.Header("Access-Control-Allow-Headers", cfg.AllowHeaders)
+		c.Header("Access-Control-Expose-Headers", cfg.ExposeHeaders)
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORSMiddlewareAppliesGroupPolicy(t *testing.T) {
+	admin := DefaultCORSConfig()
+	admin.AllowedOrigins = []string{"https://admin.example.com"}
+	adminReports := DefaultCORSConfig()
+	adminReports.AllowedOrigins = []string{"https://reports.example.com"}
+
+	r := gin.New()
+	r.Use(CORSMiddleware(DefaultCORSConfig(), map[string]CORSConfig{
+		"/api/v1/admin":         admin,
+		"/api/v1/admin/reports": adminReports,
+	}))
+	r.GET("/api/v1/products", ok)
+	r.GET("/api/v1/admin/orders", ok)
+	r.GET("/api/v1/admin/reports/sales", ok)
+	r.GET("/api/v1/administrators", ok)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"public, any origin", http.MethodGet, "/api/v1/products", "https://evil.example.com", http.StatusOK, "*"},
+		{"admin, allowed origin", http.MethodGet, "/api/v1/admin/orders", "https://admin.example.com", http.StatusOK, "https://admin.example.com"},
+		{"admin, other origin", http.MethodGet, "/api/v1/admin/orders", "https://evil.example.com", http.StatusForbidden, ""},
+		{"admin, no origin", http.MethodGet, "/api/v1/admin/orders", "", http.StatusOK, ""},
+		{"admin preflight", http.MethodOptions, "/api/v1/admin/orders", "https://admin.example.com", http.StatusNoContent, "https://admin.example.com"},
+		{"admin preflight, other origin", http.MethodOptions, "/api/v1/admin/orders", "https://evil.example.com", http.StatusForbidden, ""},
+		{"longest prefix wins", http.MethodGet, "/api/v1/admin/reports/sales", "https://reports.example.com", http.StatusOK, "https://reports.example.com"},
+		{"longest prefix only", http.MethodGet, "/api/v1/admin/reports/sales", "https://admin.example.com", http.StatusForbidden, ""},
+		{"prefix matches whole segments", http.MethodGet, "/api/v1/administrators", "https://evil.example.com", http.StatusOK, "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			w := serve(r, tt.method, tt.path, header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestParseCORSOrigins(t *testing.T) {
+	got := ParseCORSOrigins(" https://a.example.com/, ,https://b.example.com ")
+	want := []string{"https://a.example.com", "https://b.example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
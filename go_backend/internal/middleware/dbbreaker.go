@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseBreakerMiddleware fails requests fast with 503 while the database
+// circuit breaker is open. exemptPaths (e.g. health checks) are always let
+// through so they can report the outage.
+func DatabaseBreakerMiddleware(exemptPaths ...string) gin.HandlerFunc {
+	exempt := map[string]bool{}
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if exempt[c.Request.URL.Path] || database.Available() {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", "5")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.APIResponse{
+			Success:   false,
+			Error:     "Service temporarily unavailable",
+			Code:      "SERVICE_UNAVAILABLE",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/features"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature hides a route behind a feature flag. While the flag is off
+// for the caller the route responds 404, as if it did not exist.
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("userID")
+		id, _ := userID.(string)
+		if features.Enabled(name, id) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Error:     "Not found",
+			Code:      "NOT_FOUND",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// LimiterStore counts requests per key so the rate limiter can share its
+// state between instances
+type LimiterStore interface {
+	// Allow records a request for key and reports how many requests the key
+	// has made in the current window, including this one, and whether that is
+	// within limit
+	Allow(key string, limit int, window time.Duration) (count int, allowed bool, err error)
+}
+
+// MemoryLimiterStore keeps a sliding log of request times per key in memory.
+// Limits apply per instance and reset on restart.
+type MemoryLimiterStore struct {
+	requests  map[string][]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewMemoryLimiterStore creates an empty in-memory limiter store
+func NewMemoryLimiterStore() *MemoryLimiterStore {
+	return &MemoryLimiterStore{
+		requests:  make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow implements LimiterStore
+func (s *MemoryLimiterStore) Allow(key string, limit int, window time.Duration) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	// Drop idle keys once per window so memory doesn't grow with every client
+	if now.Sub(s.lastSweep) >= window {
+		for k, times := range s.requests {
+			if remaining := recentRequests(times, now, window); len(remaining) == 0 {
+				delete(s.requests, k)
+			} else {
+				s.requests[k] = remaining
+			}
+		}
+		s.lastSweep = now
+	}
+
+	// Rejected requests aren't recorded, so they don't extend the wait
+	filtered := recentRequests(s.requests[key], now, window)
+	if len(filtered) >= limit {
+		s.requests[key] = filtered
+		return len(filtered) + 1, false, nil
+	}
+
+	s.requests[key] = append(filtered, now)
+	return len(filtered) + 1, true, nil
+}
+
+// recentRequests returns the request times that fall within the window
+func recentRequests(times []time.Time, now time.Time, window time.Duration) []time.Time {
+	filtered := []time.Time{}
+	for _, t := range times {
+		if now.Sub(t) < window {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
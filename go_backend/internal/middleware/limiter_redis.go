@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisPoolSize is how many connections a RedisLimiterStore opens at most,
+// and keeps open between requests
+const redisPoolSize = 8
+
+// RedisLimiterStore counts requests in fixed windows in Redis with INCR and
+// PEXPIRE, so every instance pointed at the same Redis shares one limit
+type RedisLimiterStore struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	// slots limits the open connections to redisPoolSize; idle holds the
+	// connections not in use
+	slots chan struct{}
+	idle  chan *redisConn
+}
+
+// redisConn is a connection to Redis with its reply reader
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedisLimiterStore connects to the Redis server at a URL of the form
+// redis://[:password@]host:port[/db]
+func NewRedisLimiterStore(rawURL string) (*RedisLimiterStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q", rawURL)
+	}
+
+	s := &RedisLimiterStore{
+		addr:    u.Host,
+		timeout: 2 * time.Second,
+		slots:   make(chan struct{}, redisPoolSize),
+		idle:    make(chan *redisConn, redisPoolSize),
+	}
+	if !strings.Contains(s.addr, ":") {
+		s.addr += ":6379"
+	}
+	if password, ok := u.User.Password(); ok {
+		s.password = password
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if s.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("invalid Redis database %q", path)
+		}
+	}
+
+	conn, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.do(conn, "PING")
+	s.put(conn, err)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Allow implements LimiterStore
+func (s *RedisLimiterStore) Allow(key string, limit int, window time.Duration) (int, bool, error) {
+	windowStart := time.Now().UnixNano() / int64(window)
+	redisKey := "ratelimit:" + key + ":" + strconv.FormatInt(windowStart, 10)
+
+	conn, err := s.get()
+	if err != nil {
+		return 0, false, err
+	}
+
+	count, err := s.do(conn, "INCR", redisKey)
+	if err == nil && count == 1 {
+		_, err = s.do(conn, "PEXPIRE", redisKey, strconv.FormatInt(window.Milliseconds(), 10))
+	}
+	s.put(conn, err)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return int(count), count <= int64(limit), nil
+}
+
+// get takes an idle connection from the pool, or dials a new one, waiting
+// while redisPoolSize connections are in use
+func (s *RedisLimiterStore) get() (*redisConn, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-time.After(s.timeout):
+		return nil, errors.New("redis: no connection available")
+	}
+
+	select {
+	case conn := <-s.idle:
+		return conn, nil
+	default:
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		<-s.slots
+		return nil, err
+	}
+	return conn, nil
+}
+
+// put returns a connection to the pool after a command that returned err.
+// The connection state is unknown after a failed round trip, so unless the
+// server replied with an error the connection is closed instead.
+func (s *RedisLimiterStore) put(conn *redisConn, err error) {
+	var redisErr redisError
+	if err == nil || errors.As(err, &redisErr) {
+		s.idle <- conn
+	} else {
+		conn.Close()
+	}
+	<-s.slots
+}
+
+// do sends a command on conn and returns its integer reply (0 for status
+// replies)
+func (s *RedisLimiterStore) do(conn *redisConn, args ...string) (int64, error) {
+	return s.roundTrip(conn, args)
+}
+
+func (s *RedisLimiterStore) dial() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to Redis: %w", err)
+	}
+	conn := &redisConn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if _, err := s.roundTrip(conn, []string{"AUTH", s.password}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.db != 0 {
+		if _, err := s.roundTrip(conn, []string{"SELECT", strconv.Itoa(s.db)}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// redisError is an error reply from the server
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// roundTrip writes a RESP command and reads a status, error or integer reply
+func (s *RedisLimiterStore) roundTrip(conn *redisConn, args []string) (int64, error) {
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	if _, err := conn.Write([]byte(cmd.String())); err != nil {
+		return 0, err
+	}
+
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return 0, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return 0, nil
+	case '-':
+		return 0, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	default:
+		return 0, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
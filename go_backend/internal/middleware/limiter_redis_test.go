@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a Redis server understanding just the commands the limiter
+// store sends
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	counts   map[string]int64
+	conns    atomic.Int32
+	open     atomic.Int32
+	maxOpen  atomic.Int32
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on loopback:", err)
+	}
+	r := &fakeRedis{listener: listener, counts: make(map[string]int64)}
+	t.Cleanup(func() { listener.Close() })
+	go r.serve()
+	return r
+}
+
+func (r *fakeRedis) url() string {
+	return "redis://" + r.listener.Addr().String()
+}
+
+func (r *fakeRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		r.conns.Add(1)
+		open := r.open.Add(1)
+		for {
+			max := r.maxOpen.Load()
+			if open <= max || r.maxOpen.CompareAndSwap(max, open) {
+				break
+			}
+		}
+		go r.handle(conn)
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer r.open.Add(-1)
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			reply = "+PONG"
+		case "INCR":
+			r.mu.Lock()
+			r.counts[args[1]]++
+			reply = ":" + strconv.FormatInt(r.counts[args[1]], 10)
+			r.mu.Unlock()
+		case "PEXPIRE":
+			reply = ":1"
+		default:
+			reply = "-ERR unknown command"
+		}
+		if _, err := conn.Write([]byte(reply + "\r\n")); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisLimiterStoreCountsWithinLimit(t *testing.T) {
+	store, err := NewRedisLimiterStore(newFakeRedis(t).url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 4; i++ {
+		count, allowed, err := store.Allow("client", 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != i || allowed != (i <= 3) {
+			t.Fatalf("request %d: count = %d, allowed = %v", i, count, allowed)
+		}
+	}
+}
+
+func TestRedisLimiterStorePoolsConnections(t *testing.T) {
+	server := newFakeRedis(t)
+	store, err := NewRedisLimiterStore(server.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 200
+	var wg sync.WaitGroup
+	seen := make([]atomic.Bool, requests+1)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			count, _, err := store.Allow("client", requests, time.Minute)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if count < 1 || count > requests || seen[count].Swap(true) {
+				t.Errorf("count %d out of range or repeated", count)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max := server.maxOpen.Load(); max > redisPoolSize {
+		t.Errorf("%d connections open at once, want at most %d", max, redisPoolSize)
+	}
+	if conns := server.conns.Load(); conns > redisPoolSize {
+		t.Errorf("dialed %d connections, want idle ones reused", conns)
+	}
+}
+
+func TestRedisLimiterStoreRedialsAfterFailure(t *testing.T) {
+	server := newFakeRedis(t)
+	store, err := NewRedisLimiterStore(server.url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Break the idle connection under the store
+	conn := <-store.idle
+	conn.Close()
+	store.idle <- conn
+
+	if _, _, err := store.Allow("client", 1, time.Minute); err == nil {
+		t.Fatal("expected an error on the closed connection")
+	}
+	if _, allowed, err := store.Allow("client", 1, time.Minute); err != nil || !allowed {
+		t.Fatalf("after redialing: allowed = %v, err = %v", allowed, err)
+	}
+}
+
+func TestNewRedisLimiterStoreErrors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen on loopback:", err)
+	}
+	closedAddr := listener.Addr().String()
+	listener.Close()
+
+	for _, rawURL := range []string{
+		"http://localhost:6379",
+		"redis://",
+		"redis://localhost:6379/db",
+		fmt.Sprintf("redis://%s", closedAddr),
+	} {
+		if _, err := NewRedisLimiterStore(rawURL); err == nil {
+			t.Errorf("%s: expected an error", rawURL)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLimiterStores checks every LimiterStore honours the interface: requests
+// are counted up to the limit, refused past it and allowed again once the
+// window has passed
+func TestLimiterStores(t *testing.T) {
+	redisStore, err := NewRedisLimiterStore(newFakeRedis(t).url())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		limit  = 3
+		window = 200 * time.Millisecond
+	)
+
+	for _, tc := range []struct {
+		name  string
+		store LimiterStore
+	}{
+		{"memory", NewMemoryLimiterStore()},
+		{"redis", redisStore},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Start at the beginning of a window, as the Redis store counts
+			// in fixed windows and a burst must not straddle two of them
+			next := (time.Now().UnixNano()/int64(window) + 1) * int64(window)
+			time.Sleep(time.Until(time.Unix(0, next)))
+
+			for i := 1; i <= limit; i++ {
+				count, allowed, err := tc.store.Allow("client", limit, window)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != i || !allowed {
+					t.Fatalf("request %d: count = %d, allowed = %v", i, count, allowed)
+				}
+			}
+
+			count, allowed, err := tc.store.Allow("client", limit, window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count <= limit || allowed {
+				t.Fatalf("request past the limit: count = %d, allowed = %v", count, allowed)
+			}
+
+			time.Sleep(window)
+
+			count, allowed, err = tc.store.Allow("client", limit, window)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 || !allowed {
+				t.Fatalf("request after the window: count = %d, allowed = %v", count, allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain runs the middleware tests against a fresh database in a
+// temporary directory, as InitDB opens ./ecommerce.db
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(io.Discard)
+
+	dir, err := os.MkdirTemp("", "middleware-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if _, err := database.InitDB(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// serve sends a request through r and returns the recorded response
+func serve(r *gin.Engine, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// ok is a final handler for requests the middleware under test lets through
+func ok(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
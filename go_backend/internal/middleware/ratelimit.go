@@ -1,47 +1,72 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-type rateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.RWMutex
+// limiterStore holds the rate limiter's request counts
+var limiterStore LimiterStore = NewMemoryLimiterStore()
+
+// SetLimiterStore replaces the in-memory request counts, e.g. with a
+// RedisLimiterStore so that several instances share one limit
+func SetLimiterStore(store LimiterStore) {
+	limiterStore = store
+}
+
+// adminRateLimitMultiplier scales the limit for authenticated admins; 0
+// exempts them entirely
+var adminRateLimitMultiplier = 10
+
+// SetAdminRateLimitMultiplier sets how many times the normal limit admins
+// get. 0 exempts admins from rate limiting. The role is only known when
+// OptionalAuthMiddleware runs before the limiter.
+func SetAdminRateLimitMultiplier(multiplier int) {
+	if multiplier < 0 {
+		multiplier = 0
+	}
+	adminRateLimitMultiplier = multiplier
 }
 
-var limiter = &rateLimiter{
-	requests: make(map[string][]time.Time),
+// Rate limiter modes
+const (
+	// RateLimitEnforce rejects requests over the limit with 429
+	RateLimitEnforce = "enforce"
+	// RateLimitMonitor only logs requests that would have been rejected, so
+	// limits can be tuned against real traffic before they are enforced
+	RateLimitMonitor = "monitor"
+)
+
+var rateLimitMode = RateLimitEnforce
+
+// monitoredRequests counts requests let through in monitor mode that would
+// have been rejected
+var monitoredRequests atomic.Int64
+
+// SetRateLimitMode switches the rate limiter between RateLimitEnforce and
+// RateLimitMonitor. Unknown modes enforce.
+func SetRateLimitMode(mode string) {
+	if mode != RateLimitMonitor {
+		mode = RateLimitEnforce
+	}
+	rateLimitMode = mode
+}
+
+// RateLimitStats reports the rate limiter mode and, in monitor mode, how
+// many requests would have been rejected so far
+func RateLimitStats() gin.H {
+	return gin.H{
+		"mode":               rateLimitMode,
+		"would_have_limited": monitoredRequests.Load(),
+	}
 }
 
 // RateLimitMiddleware limits requests per IP
 func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc {
-	// Cleanup old entries periodically
-	go func() {
-		ticker := time.NewTicker(window)
-		for range ticker.C {
-			limiter.mu.Lock()
-			now := time.Now()
-			for key, times := range limiter.requests {
-				filtered := []time.Time{}
-				for _, t := range times {
-					if now.Sub(t) < window {
-						filtered = append(filtered, t)
-					}
-				}
-				if len(filtered) == 0 {
-					delete(limiter.requests, key)
-				} else {
-					limiter.requests[key] = filtered
-				}
-			}
-			limiter.mu.Unlock()
-		}
-	}()
-
 	return func(c *gin.Context) {
 		// Skip rate limiting for GET requests
 		if c.Request.Method == "GET" {
@@ -49,38 +74,43 @@ func RateLimitMiddleware(maxRequests int, window time.Duration) gin.HandlerFunc
 			return
 		}
 
+		limit := maxRequests
+		if role, _ := c.Get("role"); role == "admin" {
+			if adminRateLimitMultiplier == 0 {
+				c.Next()
+				return
+			}
+			limit = maxRequests * adminRateLimitMultiplier
+		}
+
 		clientIP := c.ClientIP()
 		key := clientIP + "-" + c.Request.URL.Path
 
-		limiter.mu.Lock()
-		defer limiter.mu.Unlock()
-
-		now := time.Now()
-		requests := limiter.requests[key]
+		count, allowed, err := limiterStore.Allow(key, limit, window)
+		if err != nil {
+			// Fail open: an unavailable store shouldn't take the API down
+			log.Println("Rate limiter store error:", err)
+			allowed = true
+		}
 
-		// Filter out old requests
-		filtered := []time.Time{}
-		for _, t := range requests {
-			if now.Sub(t) < window {
-				filtered = append(filtered, t)
-			}
+		if !allowed && rateLimitMode == RateLimitMonitor {
+			monitoredRequests.Add(1)
+			log.Printf("[ratelimit] mode=monitor request_id=%s key=%q count=%d limit=%d window=%s would_limit=true",
+				c.GetString("requestID"), key, count, limit, window)
+			allowed = true
 		}
 
-		if len(filtered) >= maxRequests {
+		if !allowed {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"success":   false,
 				"error":     "Rate limit exceeded",
 				"code":      "RATE_LIMIT_EXCEEDED",
-				"timestamp": now.Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			})
 			c.Abort()
 			return
 		}
 
-		// Add current request
-		filtered = append(filtered, now)
-		limiter.requests[key] = filtered
-
 		c.Next()
 	}
 }
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedEngine serves POST / behind the rate limiter as a user with role
+func rateLimitedEngine(role string, limit int) *gin.Engine {
+	r := gin.New()
+	r.POST("/", func(c *gin.Context) {
+		if role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	}, RateLimitMiddleware(limit, time.Minute), ok)
+	return r
+}
+
+// allowedRequests counts the requests of n the engine lets through
+func allowedRequests(r *gin.Engine, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if serve(r, http.MethodPost, "/", nil).Code == http.StatusOK {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestRateLimitAdminMultiplier(t *testing.T) {
+	defer SetAdminRateLimitMultiplier(adminRateLimitMultiplier)
+	defer SetLimiterStore(limiterStore)
+
+	tests := []struct {
+		name       string
+		role       string
+		multiplier int
+		want       int
+	}{
+		{"customer", "customer", 3, 2},
+		{"admin", "admin", 3, 6},
+		{"exempt admin", "admin", 0, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetLimiterStore(NewMemoryLimiterStore())
+			SetAdminRateLimitMultiplier(tt.multiplier)
+			if got := allowedRequests(rateLimitedEngine(tt.role, 2), 20); got != tt.want {
+				t.Errorf("allowed %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// failingLimiterStore is a LimiterStore whose backend is unavailable
+type failingLimiterStore struct {
+	calls atomic.Int32
+}
+
+func (s *failingLimiterStore) Allow(key string, limit int, window time.Duration) (int, bool, error) {
+	s.calls.Add(1)
+	return 0, false, errors.New("store unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	defer SetLimiterStore(limiterStore)
+
+	store := &failingLimiterStore{}
+	SetLimiterStore(store)
+	if got := allowedRequests(rateLimitedEngine("customer", 1), 5); got != 5 {
+		t.Errorf("allowed %d requests, want all 5", got)
+	}
+	if store.calls.Load() != 5 {
+		t.Errorf("store called %d times, want 5", store.calls.Load())
+	}
+}
+
+func TestRateLimitMonitorMode(t *testing.T) {
+	defer SetLimiterStore(limiterStore)
+	defer SetRateLimitMode(rateLimitMode)
+
+	SetLimiterStore(NewMemoryLimiterStore())
+	SetRateLimitMode(RateLimitMonitor)
+	before := monitoredRequests.Load()
+	if got := allowedRequests(rateLimitedEngine("customer", 2), 5); got != 5 {
+		t.Errorf("allowed %d requests, want all 5", got)
+	}
+	if got := monitoredRequests.Load() - before; got != 3 {
+		t.Errorf("would have limited %d requests, want 3", got)
+	}
+}
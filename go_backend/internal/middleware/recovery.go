@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryMiddleware recovers from panics in later handlers, logs the panic
+// with its stack trace and request id, and responds with the standard error
+// envelope instead of gin's plain 500
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				requestID := c.GetString("requestID")
+				log.Printf("panic recovered [request_id=%s] %s %s: %v\n%s",
+					requestID, c.Request.Method, c.Request.URL.Path, err, debug.Stack())
+
+				if c.Writer.Written() {
+					c.Abort()
+					return
+				}
+
+				c.AbortWithStatusJSON(http.StatusInternalServerError, models.APIResponse{
+					Success:   false,
+					Error:     "Internal server error",
+					Code:      "INTERNAL_ERROR",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryMiddlewareRespondsWithEnvelope(t *testing.T) {
+	r := gin.New()
+	r.Use(RecoveryMiddleware())
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	r.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusAccepted, "partial")
+		panic("boom")
+	})
+
+	w := serve(r, http.MethodGet, "/panic", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", w.Code)
+	}
+	var res models.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("invalid envelope %q: %v", w.Body.String(), err)
+	}
+	if res.Success || res.Code != "INTERNAL_ERROR" || res.Timestamp == "" {
+		t.Fatalf("got %+v, want an INTERNAL_ERROR envelope", res)
+	}
+
+	// A response already started is left as it is
+	w = serve(r, http.MethodGet, "/written", nil)
+	if w.Code != http.StatusAccepted || w.Body.String() != "partial" {
+		t.Fatalf("got %d %q, want the partial response", w.Code, w.Body.String())
+	}
+}
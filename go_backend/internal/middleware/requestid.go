@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/Seyamalam/bun_backend/go_backend/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware tags each request with an id, reusing a client-supplied
+// X-Request-ID when present, and echoes it in the response headers
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = utils.GenerateID()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"database/sql"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/database"
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// StoreMiddleware resolves the store a request is for and stores its id in
+// the context as "storeID". An explicit X-Store-ID header (store id or slug)
+// must name an existing store; otherwise the first label of a subdomain is
+// matched against store slugs, falling back to the default store.
+func StoreMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := database.GetDB()
+
+		if header := strings.TrimSpace(c.GetHeader("X-Store-ID")); header != "" {
+			var storeID string
+			err := db.QueryRow("SELECT id FROM stores WHERE id = ? OR slug = ?", header, strings.ToLower(header)).Scan(&storeID)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success":   false,
+					"error":     "Store not found",
+					"code":      "STORE_NOT_FOUND",
+					"timestamp": time.Now().UTC().Format(time.RFC3339),
+				})
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success":   false,
+					"error":     "Database error",
+					"code":      "INTERNAL_ERROR",
+					"timestamp": time.Now().UTC().Format(time.RFC3339),
+				})
+				c.Abort()
+				return
+			}
+
+			c.Set("storeID", storeID)
+			c.Next()
+			return
+		}
+
+		storeID := models.DefaultStoreID
+		if slug := subdomain(c.Request.Host); slug != "" {
+			var id string
+			if err := db.QueryRow("SELECT id FROM stores WHERE slug = ?", slug).Scan(&id); err == nil {
+				storeID = id
+			}
+		}
+
+		c.Set("storeID", storeID)
+		c.Next()
+	}
+}
+
+// subdomain returns the lowercased first label of a host with at least three
+// labels, e.g. "acme" for "acme.shop.example.com"
+func subdomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return strings.ToLower(labels[0])
+}
@@ -1,6 +1,22 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// DefaultStoreID is the store that requests without a store selector, and
+// all data created before multi-store support, belong to
+const DefaultStoreID = "default"
+
+// Store represents a storefront hosted by the backend
+type Store struct {
+	ID        string    `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
 
 // User represents a user in the system
 type User struct {
@@ -17,6 +33,29 @@ type User struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// WebAuthnCredential is a passkey a user can log in with instead of a password
+type WebAuthnCredential struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"user_id"`
+	CredentialID string     `json:"credential_id"`
+	Name         string     `json:"name"`
+	SignCount    int64      `json:"sign_count"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Session is a signed-in device: one issued access token, identified by its jti
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	Current    bool      `json:"current"`
+	IssuedAt   time.Time `json:"issued_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 // Address represents a user address
 type Address struct {
 	ID            string    `json:"id"`
@@ -38,23 +77,63 @@ type Category struct {
 	Description *string   `json:"description,omitempty"`
 	ParentID    *string   `json:"parent_id,omitempty"`
 	ImageURL    *string   `json:"image_url,omitempty"`
+	StoreID     string    `json:"store_id"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // Product represents a product
 type Product struct {
-	ID            string    `json:"id"`
-	Name          string    `json:"name"`
-	Description   string    `json:"description"`
-	Price         float64   `json:"price"`
-	CategoryID    string    `json:"category_id"`
-	VendorID      *string   `json:"vendor_id,omitempty"`
-	Status        string    `json:"status"`
-	StockQuantity int       `json:"stock_quantity"`
-	SKU           string    `json:"sku"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	Description    string    `json:"description"`
+	Price          Money     `json:"price"`
+	BasePrice      Money     `json:"base_price"`
+	CompareAtPrice *Money    `json:"compare_at_price,omitempty"`
+	CategoryID     string    `json:"category_id"`
+	VendorID       *string   `json:"vendor_id,omitempty"`
+	StoreID        string    `json:"store_id"`
+	Status         string    `json:"status"`
+	StockQuantity  int       `json:"stock_quantity"`
+	ReserveStock   int       `json:"reserve_stock"`
+	SKU            string    `json:"sku"`
+	Weight         *float64  `json:"weight,omitempty"`
+	Length         *float64  `json:"length,omitempty"`
+	Width          *float64  `json:"width,omitempty"`
+	Height         *float64  `json:"height,omitempty"`
+	RestockDate    *string   `json:"restock_date,omitempty"`
+	Views          *int      `json:"views,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// PriceRule schedules a product price for a period of time
+type PriceRule struct {
+	ID          string     `json:"id"`
+	ProductID   string     `json:"product_id"`
+	Price       Money      `json:"price"`
+	StartsAt    time.Time  `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// PriceChange is one change of a product's base price
+type PriceChange struct {
+	ID        string    `json:"id"`
+	OldPrice  Money     `json:"old_price"`
+	NewPrice  Money     `json:"new_price"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// InventoryChange is one change of a product's or variant's stock
+type InventoryChange struct {
+	ID              string    `json:"id"`
+	VariantID       *string   `json:"variant_id"`
+	QuantityChanged int       `json:"quantity_changed"`
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // ProductVariant represents a product variant
@@ -63,13 +142,32 @@ type ProductVariant struct {
 	ProductID     string    `json:"product_id"`
 	Name          string    `json:"name"`
 	Value         string    `json:"value"`
-	PriceModifier float64   `json:"price_modifier"`
+	PriceModifier Money     `json:"price_modifier"`
 	StockQuantity int       `json:"stock_quantity"`
 	SKU           string    `json:"sku"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// ProductAttribute is a free-form name/value detail of a product
+type ProductAttribute struct {
+	ID        string    `json:"id"`
+	ProductID string    `json:"product_id"`
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InventoryHistory records a single stock movement for a product or variant
+type InventoryHistory struct {
+	ID              string    `json:"id"`
+	ProductID       string    `json:"product_id"`
+	VariantID       *string   `json:"variant_id,omitempty"`
+	QuantityChanged int       `json:"quantity_changed"`
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
 // Cart represents a shopping cart
 type Cart struct {
 	ID        string    `json:"id"`
@@ -91,25 +189,41 @@ type CartItem struct {
 
 // Order represents an order
 type Order struct {
-	ID                string    `json:"id"`
-	UserID            string    `json:"user_id"`
-	Status            string    `json:"status"`
-	TotalAmount       float64   `json:"total_amount"`
-	ShippingAddressID string    `json:"shipping_address_id"`
-	CreatedAt         time.Time `json:"created_at"`
-	UpdatedAt         time.Time `json:"updated_at"`
+	ID                string     `json:"id"`
+	UserID            string     `json:"user_id"`
+	Status            string     `json:"status"`
+	TotalAmount       Money      `json:"total_amount"`
+	ShippingAddressID string     `json:"shipping_address_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
-	ID         string    `json:"id"`
-	OrderID    string    `json:"order_id"`
-	ProductID  string    `json:"product_id"`
-	VariantID  *string   `json:"variant_id,omitempty"`
-	Quantity   int       `json:"quantity"`
-	UnitPrice  float64   `json:"unit_price"`
-	TotalPrice float64   `json:"total_price"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID                string    `json:"id"`
+	OrderID           string    `json:"order_id"`
+	ProductID         string    `json:"product_id"`
+	VariantID         *string   `json:"variant_id,omitempty"`
+	Quantity          int       `json:"quantity"`
+	UnitPrice         Money     `json:"unit_price"`
+	TotalPrice        Money     `json:"total_price"`
+	FulfilledQuantity int       `json:"fulfilled_quantity"`
+	ShippingAddressID *string   `json:"shipping_address_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// OrderShipping represents a shipment of some or all of an order's items
+type OrderShipping struct {
+	ID                string    `json:"id"`
+	OrderID           string    `json:"order_id"`
+	ShippingAddressID *string   `json:"shipping_address_id,omitempty"`
+	ShippingMethodID  *string   `json:"shipping_method_id,omitempty"`
+	TrackingNumber    *string   `json:"tracking_number,omitempty"`
+	Status            string    `json:"status"`
+	EstimatedDelivery *string   `json:"estimated_delivery,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // Payment represents a payment
@@ -117,7 +231,7 @@ type Payment struct {
 	ID            string    `json:"id"`
 	OrderID       string    `json:"order_id"`
 	UserID        string    `json:"user_id"`
-	Amount        float64   `json:"amount"`
+	Amount        Money     `json:"amount"`
 	Status        string    `json:"status"`
 	Method        string    `json:"method"`
 	TransactionID *string   `json:"transaction_id,omitempty"`
@@ -130,8 +244,8 @@ type Coupon struct {
 	ID                string    `json:"id"`
 	Code              string    `json:"code"`
 	DiscountType      string    `json:"discount_type"`
-	DiscountValue     float64   `json:"discount_value"`
-	MinPurchaseAmount float64   `json:"min_purchase_amount"`
+	DiscountValue     float64   `json:"discount_value"` // percent, or cents for fixed_amount coupons
+	MinPurchaseAmount Money     `json:"min_purchase_amount"`
 	MaxUses           int       `json:"max_uses"`
 	UsesCount         int       `json:"uses_count"`
 	ExpiryDate        time.Time `json:"expiry_date"`
@@ -140,18 +254,104 @@ type Coupon struct {
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+// ShippingMethod represents an available shipping method
+type ShippingMethod struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Description   *string   `json:"description,omitempty"`
+	BaseCost      Money     `json:"base_cost"`
+	CostPerKg     Money     `json:"cost_per_kg"`
+	EstimatedDays int       `json:"estimated_days"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 // Review represents a product review
 type Review struct {
-	ID           string    `json:"id"`
-	ProductID    string    `json:"product_id"`
-	UserID       string    `json:"user_id"`
-	Title        string    `json:"title"`
-	Description  string    `json:"description"`
-	Rating       int       `json:"rating"`
-	IsApproved   bool      `json:"is_approved"`
-	HelpfulCount int       `json:"helpful_count"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string     `json:"id"`
+	ProductID    string     `json:"product_id"`
+	UserID       string     `json:"user_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Rating       int        `json:"rating"`
+	IsApproved   bool       `json:"is_approved"`
+	HelpfulCount int        `json:"helpful_count"`
+	ModeratedBy  *string    `json:"moderated_by,omitempty"`
+	ModeratedAt  *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ProductQuestion is a shopper's question about a product
+type ProductQuestion struct {
+	ID        string          `json:"id"`
+	ProductID string          `json:"product_id"`
+	UserID    string          `json:"user_id"`
+	Question  string          `json:"question"`
+	IsHidden  bool            `json:"is_hidden"`
+	Answers   []ProductAnswer `json:"answers"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ProductAnswer is a vendor's or admin's answer to a product question
+type ProductAnswer struct {
+	ID         string    `json:"id"`
+	QuestionID string    `json:"question_id"`
+	UserID     string    `json:"user_id"`
+	AuthorName string    `json:"author_name"`
+	AuthorRole string    `json:"author_role"`
+	Answer     string    `json:"answer"`
+	IsHidden   bool      `json:"is_hidden"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SavedSearch is a product search a user is alerted about when new products
+// match it
+type SavedSearch struct {
+	ID            string          `json:"id"`
+	UserID        string          `json:"user_id"`
+	StoreID       string          `json:"store_id"`
+	Name          string          `json:"name"`
+	Filters       json.RawMessage `json:"filters"`
+	LastCheckedAt time.Time       `json:"last_checked_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Notification types
+const (
+	NotificationOrderStatus       = "order_status"
+	NotificationOrderConfirmation = "order_confirmation"
+	NotificationSavedSearch       = "saved_search"
+	NotificationAnnouncement      = "announcement"
+	NotificationCartUpdate        = "cart_update"
+	NotificationBackInStock       = "back_in_stock"
+)
+
+// Notification is an in-app message for a user
+type Notification struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	IsRead    bool      `json:"is_read"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureFlag turns a feature on or off, optionally for only a percentage of
+// users
+type FeatureFlag struct {
+	Name              string    `json:"name"`
+	Description       string    `json:"description"`
+	Enabled           bool      `json:"enabled"`
+	RolloutPercentage int       `json:"rollout_percentage"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // Request/Response types
@@ -181,6 +381,7 @@ type APIResponse struct {
 	Data      interface{} `json:"data,omitempty"`
 	Error     string      `json:"error,omitempty"`
 	Code      string      `json:"code,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
 	Timestamp string      `json:"timestamp"`
 }
 
@@ -194,4 +395,5 @@ type PaginationResponse struct {
 type ListResponse struct {
 	Data       interface{}        `json:"data"`
 	Pagination PaginationResponse `json:"pagination"`
+	Facets     interface{}        `json:"facets,omitempty"`
 }
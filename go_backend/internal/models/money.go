@@ -0,0 +1,152 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// MoneyPrecision is the number of decimals every amount is rounded to and
+// serialized with
+const MoneyPrecision = 2
+
+// currency is the ISO 4217 code all amounts are in
+var currency = "USD"
+
+// SetCurrency sets the ISO 4217 currency code reported with amounts
+func SetCurrency(code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return fmt.Errorf("invalid currency code %q", code)
+	}
+	currency = code
+	return nil
+}
+
+// Currency returns the ISO 4217 code all amounts are in
+func Currency() string {
+	return currency
+}
+
+// Money is an amount in integer cents. All money is stored and summed in
+// cents; it is only converted to a decimal amount (e.g. 12.34) at the JSON
+// boundary, so totals always equal the sum of their line items to the cent.
+type Money int64
+
+// MoneyFromFloat converts a decimal amount to cents, rounding half away from zero
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// ParseMoney parses a decimal amount such as "12.34" without going through
+// float64. Digits beyond the cents are rounded half away from zero.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	for _, r := range fraction {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+
+	fraction += "000"
+	cents, _ := strconv.ParseInt(fraction[:2], 10, 64)
+	if fraction[2] >= '5' {
+		cents++
+	}
+
+	m := Money(units*100 + cents)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// Float64 returns the amount in currency units
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals, e.g. "12.34"
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MarshalJSON encodes the amount as a decimal number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a decimal number or a numeric string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %q", s)
+		}
+		*m = MoneyFromFloat(f)
+		return nil
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads cents from the database. Money columns declared REAL hold whole
+// numbers, which SQLite may hand back as float64.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("cannot scan %q into Money", v)
+		}
+		*m = Money(math.Round(f))
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+// Value stores the amount as integer cents
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
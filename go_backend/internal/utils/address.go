@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+)
+
+// FieldError describes a validation failure on a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AddressValidator validates and normalizes an address before it is stored.
+// Implementations may rewrite fields in place and return any field errors.
+type AddressValidator interface {
+	Validate(addr *models.Address) []FieldError
+}
+
+// PassThroughAddressValidator accepts every address unchanged
+type PassThroughAddressValidator struct{}
+
+// Validate implements AddressValidator
+func (PassThroughAddressValidator) Validate(addr *models.Address) []FieldError {
+	return nil
+}
+
+// postalCodePatterns holds the postal code format for each supported country
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] \d[A-Z]\d$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} [A-Z]{2}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"BD": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"JP": regexp.MustCompile(`^\d{3}-\d{4}$`),
+}
+
+// postalCodeSuffixLengths is the length of the part after the space for
+// formats that contain one
+var postalCodeSuffixLengths = map[string]int{
+	"CA": 3,
+	"GB": 3,
+	"NL": 2,
+}
+
+var countryAliases = map[string]string{
+	"USA":            "US",
+	"UNITED STATES":  "US",
+	"CANADA":         "CA",
+	"UK":             "GB",
+	"UNITED KINGDOM": "GB",
+	"GERMANY":        "DE",
+	"FRANCE":         "FR",
+	"NETHERLANDS":    "NL",
+	"INDIA":          "IN",
+	"BANGLADESH":     "BD",
+	"AUSTRALIA":      "AU",
+	"JAPAN":          "JP",
+}
+
+// PostalCodeValidator trims address fields, normalizes the country to its
+// ISO code and checks the postal code format for known countries. Countries
+// without a rule are accepted as-is.
+type PostalCodeValidator struct{}
+
+// Validate implements AddressValidator
+func (PostalCodeValidator) Validate(addr *models.Address) []FieldError {
+	addr.StreetAddress = strings.TrimSpace(addr.StreetAddress)
+	addr.City = strings.TrimSpace(addr.City)
+	addr.State = strings.TrimSpace(addr.State)
+	addr.Country = strings.ToUpper(strings.TrimSpace(addr.Country))
+	addr.PostalCode = strings.ToUpper(strings.Join(strings.Fields(addr.PostalCode), " "))
+
+	if code, ok := countryAliases[addr.Country]; ok {
+		addr.Country = code
+	}
+
+	errors := []FieldError{}
+	if addr.StreetAddress == "" {
+		errors = append(errors, FieldError{Field: "street_address", Message: "Street address is required"})
+	}
+	if addr.City == "" {
+		errors = append(errors, FieldError{Field: "city", Message: "City is required"})
+	}
+	if addr.Country == "" {
+		errors = append(errors, FieldError{Field: "country", Message: "Country is required"})
+	}
+	if addr.PostalCode == "" {
+		errors = append(errors, FieldError{Field: "postal_code", Message: "Postal code is required"})
+	} else if pattern, ok := postalCodePatterns[addr.Country]; ok {
+		// Some formats are commonly written without their separating space
+		if suffix, ok := postalCodeSuffixLengths[addr.Country]; ok && !strings.Contains(addr.PostalCode, " ") && len(addr.PostalCode) > suffix {
+			split := len(addr.PostalCode) - suffix
+			addr.PostalCode = addr.PostalCode[:split] + " " + addr.PostalCode[split:]
+		}
+		if !pattern.MatchString(addr.PostalCode) {
+			errors = append(errors, FieldError{Field: "postal_code", Message: "Invalid postal code format for " + addr.Country})
+		}
+	}
+
+	if len(errors) == 0 {
+		return nil
+	}
+	return errors
+}
@@ -10,7 +10,21 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("your-secret-key-change-in-production")
+// jwtSecret signs and verifies tokens. It starts out random, so tokens only
+// stay valid across restarts once SetJWTSecret sets a fixed one.
+var jwtSecret = randomJWTSecret()
+
+// randomJWTSecret generates a random 256-bit token signing secret
+func randomJWTSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
+
+// SetJWTSecret sets the secret tokens are signed and verified with
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
 
 // GenerateID generates a unique ID
 func GenerateID() string {
@@ -31,19 +45,110 @@ func VerifyPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateToken generates a JWT token
+// Token lifetimes. Access tokens are short-lived and renewed with a refresh
+// token, so a leaked access token is only useful for a few minutes.
+const (
+	AccessTokenLifetime  = 15 * time.Minute
+	RefreshTokenLifetime = 7 * 24 * time.Hour
+)
+
+// refreshTokenType is the typ claim that tells refresh tokens apart from
+// access tokens
+const refreshTokenType = "refresh"
+
+// GenerateToken generates an access token starting a new session, whose ID
+// is the token's jti
 func GenerateToken(userID string, role string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	return GenerateSessionToken("", userID, role)
+}
+
+// GenerateSessionToken generates an access token for a session. An empty
+// sessionID starts a new session.
+func GenerateSessionToken(sessionID, userID, role string) (string, error) {
+	claims := jwt.MapClaims{
+		"jti":     GenerateID(),
 		"user_id": userID,
 		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // 24 hours
+		"exp":     time.Now().Add(AccessTokenLifetime).Unix(),
+	}
+	if sessionID != "" {
+		claims["sid"] = sessionID
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+// GenerateRefreshToken generates a refresh token for a user, valid for
+// RefreshTokenLifetime. Refresh tokens are only good for getting new access
+// tokens, and only while their jti is recorded as unrevoked.
+func GenerateRefreshToken(userID string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"jti":     GenerateID(),
+		"user_id": userID,
+		"typ":     refreshTokenType,
+		"exp":     time.Now().Add(RefreshTokenLifetime).Unix(),
 	})
 
 	return token.SignedString(jwtSecret)
 }
 
-// ValidateToken validates a JWT token and returns the user ID
-func ValidateToken(tokenString string) (string, string, error) {
+// TokenClaims are the claims of a valid token. ID is the token's jti, empty
+// for tokens issued before tokens had one. SessionID is the session the token
+// belongs to: the jti of the token that started it. Role is empty for refresh
+// tokens.
+type TokenClaims struct {
+	ID        string
+	SessionID string
+	UserID    string
+	Role      string
+	ExpiresAt time.Time
+}
+
+// ParseToken validates an access token and returns its claims
+func ParseToken(tokenString string) (*TokenClaims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	if sessionID == "" {
+		sessionID = tokenID
+	}
+	userID, _ := claims["user_id"].(string)
+	role, _ := claims["role"].(string)
+	tokenType, _ := claims["typ"].(string)
+	exp, err := claims.GetExpirationTime()
+	if userID == "" || role == "" || tokenType != "" || err != nil || exp == nil {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+
+	return &TokenClaims{ID: tokenID, SessionID: sessionID, UserID: userID, Role: role, ExpiresAt: exp.Time}, nil
+}
+
+// ParseRefreshToken validates a refresh token and returns its claims. It only
+// checks the signature and expiry; whether the token was revoked is up to
+// the caller.
+func ParseRefreshToken(tokenString string) (*TokenClaims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	userID, _ := claims["user_id"].(string)
+	tokenType, _ := claims["typ"].(string)
+	exp, err := claims.GetExpirationTime()
+	if tokenID == "" || userID == "" || tokenType != refreshTokenType || err != nil || exp == nil {
+		return nil, fmt.Errorf("invalid refresh token claims")
+	}
+
+	return &TokenClaims{ID: tokenID, UserID: userID, ExpiresAt: exp.Time}, nil
+}
+
+// parseClaims checks a token's signature and expiry and returns its claims
+func parseClaims(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -52,16 +157,42 @@ func ValidateToken(tokenString string) (string, string, error) {
 	})
 
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		userID := claims["user_id"].(string)
-		role := claims["role"].(string)
-		return userID, role, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
+	return claims, nil
+}
+
+// ValidateToken validates a JWT token and returns the user ID
+func ValidateToken(tokenString string) (string, string, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return "", "", err
+	}
+	return claims.UserID, claims.Role, nil
+}
+
+// temporaryPasswordChars are the characters temporary passwords are drawn
+// from, leaving out ones that are easily confused such as 0/O and 1/l
+const temporaryPasswordChars = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
 
-	return "", "", fmt.Errorf("invalid token")
+// GenerateTemporaryPassword generates a random password that satisfies
+// IsValidPassword, for accounts created on someone else's behalf
+func GenerateTemporaryPassword() string {
+	for {
+		b := make([]byte, 16)
+		rand.Read(b)
+		for i := range b {
+			b[i] = temporaryPasswordChars[int(b[i])%len(temporaryPasswordChars)]
+		}
+		if password := string(b); IsValidPassword(password) {
+			return password
+		}
+	}
 }
 
 // GenerateVerificationToken generates a verification token
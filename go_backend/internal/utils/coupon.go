@@ -0,0 +1,19 @@
+package utils
+
+import "crypto/rand"
+
+// couponCodeChars are the characters random coupon codes are drawn from:
+// uppercase letters and digits without easily confused ones such as 0/O and
+// 1/I. There are exactly 32, so every random byte maps to one without bias.
+const couponCodeChars = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// GenerateCouponCode generates a coupon code of prefix followed by length
+// random characters
+func GenerateCouponCode(prefix string, length int) string {
+	b := make([]byte, length)
+	rand.Read(b)
+	for i := range b {
+		b[i] = couponCodeChars[int(b[i])%len(couponCodeChars)]
+	}
+	return prefix + string(b)
+}
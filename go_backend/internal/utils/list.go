@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Pagination defaults and bounds shared by every list endpoint
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// FilterKind is the type of value a list filter takes
+type FilterKind int
+
+const (
+	// FilterString takes any text
+	FilterString FilterKind = iota
+	// FilterBool takes "true" or "false"
+	FilterBool
+	// FilterInt takes a whole number
+	FilterInt
+)
+
+// Filter describes one filter query parameter of a list endpoint. Choices,
+// when set, lists the only values a string filter accepts.
+type Filter struct {
+	Kind    FilterKind
+	Choices []string
+}
+
+// ListSpec describes the query parameters a list endpoint accepts on top of
+// page and limit. Sorts maps each accepted sort value to its ORDER BY clause,
+// so only whitelisted SQL ever reaches a query; DefaultSort is used when no
+// sort is given. Search enables the search parameter. Query parameters that
+// aren't described are ignored.
+type ListSpec struct {
+	Sorts       map[string]string
+	DefaultSort string
+	Search      bool
+	Filters     map[string]Filter
+}
+
+// ListParams are the parsed parameters of a list request. Filters holds only
+// the filters that were given, as a string, bool or int depending on their
+// kind.
+type ListParams struct {
+	Page    int
+	Limit   int
+	Offset  int
+	Sort    string
+	OrderBy string
+	Search  string
+	Filters map[string]interface{}
+}
+
+// String returns a string filter, or "" when it wasn't given
+func (p ListParams) String(name string) string {
+	value, _ := p.Filters[name].(string)
+	return value
+}
+
+// Bool returns a bool filter, or false when it wasn't given
+func (p ListParams) Bool(name string) bool {
+	value, _ := p.Filters[name].(bool)
+	return value
+}
+
+// Int returns an int filter and whether it was given
+func (p ListParams) Int(name string) (int, bool) {
+	value, ok := p.Filters[name].(int)
+	return value, ok
+}
+
+// ParseListParams reads a list request's query parameters as described by
+// spec. Missing parameters get their defaults: page 1, DefaultPageLimit items
+// and DefaultSort. Malformed ones are reported as field errors rather than
+// silently replaced, so clients learn about a typo instead of getting a
+// different page than they asked for.
+func ParseListParams(query url.Values, spec ListSpec) (ListParams, []FieldError) {
+	params := ListParams{
+		Page:    1,
+		Limit:   DefaultPageLimit,
+		Filters: map[string]interface{}{},
+	}
+	var errs []FieldError
+
+	if value := query.Get("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			errs = append(errs, FieldError{Field: "page", Message: "must be a positive integer"})
+		} else {
+			params.Page = page
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			errs = append(errs, FieldError{Field: "limit", Message: "must be an integer from 1 to " + strconv.Itoa(MaxPageLimit)})
+		} else {
+			params.Limit = limit
+		}
+	}
+	params.Offset = (params.Page - 1) * params.Limit
+
+	if len(spec.Sorts) > 0 {
+		params.Sort = spec.DefaultSort
+		if value := query.Get("sort"); value != "" {
+			if _, ok := spec.Sorts[value]; ok {
+				params.Sort = value
+			} else {
+				errs = append(errs, FieldError{Field: "sort", Message: "must be one of " + strings.Join(slices.Sorted(maps.Keys(spec.Sorts)), ", ")})
+			}
+		}
+		params.OrderBy = spec.Sorts[params.Sort]
+	}
+
+	if spec.Search {
+		params.Search = SanitizeSearchQuery(query.Get("search"))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(spec.Filters)) {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+
+		filter := spec.Filters[name]
+		switch filter.Kind {
+		case FilterBool:
+			if value != "true" && value != "false" {
+				errs = append(errs, FieldError{Field: name, Message: "must be true or false"})
+				continue
+			}
+			params.Filters[name] = value == "true"
+		case FilterInt:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, FieldError{Field: name, Message: "must be an integer"})
+				continue
+			}
+			params.Filters[name] = n
+		default:
+			if len(filter.Choices) > 0 && !slices.Contains(filter.Choices, value) {
+				errs = append(errs, FieldError{Field: name, Message: "must be one of " + strings.Join(filter.Choices, ", ")})
+				continue
+			}
+			params.Filters[name] = value
+		}
+	}
+
+	return params, errs
+}
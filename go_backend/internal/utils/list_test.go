@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var testListSpec = ListSpec{
+	Sorts:       map[string]string{"newest": "created_at DESC", "price": "price ASC"},
+	DefaultSort: "newest",
+	Search:      true,
+	Filters: map[string]Filter{
+		"featured":   {Kind: FilterBool},
+		"min_rating": {Kind: FilterInt},
+		"status":     {Kind: FilterString, Choices: []string{"active", "inactive"}},
+		"brand":      {Kind: FilterString},
+	},
+}
+
+func TestParseListParamsDefaults(t *testing.T) {
+	params, errs := ParseListParams(url.Values{}, testListSpec)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if params.Page != 1 || params.Limit != DefaultPageLimit || params.Offset != 0 {
+		t.Errorf("page %d, limit %d, offset %d; want 1, %d, 0", params.Page, params.Limit, params.Offset, DefaultPageLimit)
+	}
+	if params.Sort != "newest" || params.OrderBy != "created_at DESC" {
+		t.Errorf("sort %q (%q), want the default", params.Sort, params.OrderBy)
+	}
+	if len(params.Filters) != 0 {
+		t.Errorf("filters = %v, want none", params.Filters)
+	}
+}
+
+func TestParseListParamsValid(t *testing.T) {
+	query, _ := url.ParseQuery("page=3&limit=10&sort=price&featured=true&min_rating=4&status=inactive&brand=acme&unknown=x")
+	params, errs := ParseListParams(query, testListSpec)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if params.Page != 3 || params.Limit != 10 || params.Offset != 20 {
+		t.Errorf("page %d, limit %d, offset %d; want 3, 10, 20", params.Page, params.Limit, params.Offset)
+	}
+	if params.OrderBy != "price ASC" {
+		t.Errorf("order by %q, want price ASC", params.OrderBy)
+	}
+	want := map[string]interface{}{"featured": true, "min_rating": 4, "status": "inactive", "brand": "acme"}
+	if !reflect.DeepEqual(params.Filters, want) {
+		t.Errorf("filters = %v, want %v", params.Filters, want)
+	}
+}
+
+func TestParseListParamsMalformed(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+	}{
+		{"page=0", "page"},
+		{"page=-2", "page"},
+		{"page=two", "page"},
+		{"page=1.5", "page"},
+		{"limit=0", "limit"},
+		{"limit=101", "limit"},
+		{"limit=ten", "limit"},
+		{"sort=name", "sort"},
+		{"sort=created_at%20DESC", "sort"},
+		{"featured=yes", "featured"},
+		{"featured=1", "featured"},
+		{"min_rating=high", "min_rating"},
+		{"min_rating=4.5", "min_rating"},
+		{"status=deleted", "status"},
+		{"status=ACTIVE", "status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, errs := ParseListParams(query, testListSpec)
+			if len(errs) != 1 || errs[0].Field != tt.field {
+				t.Fatalf("errors = %v, want one for %s", errs, tt.field)
+			}
+			if _, ok := params.Filters[tt.field]; ok {
+				t.Errorf("malformed %s was kept as a filter", tt.field)
+			}
+		})
+	}
+}
+
+func TestParseListParamsReportsEveryError(t *testing.T) {
+	query, _ := url.ParseQuery("page=x&limit=x&sort=x&featured=x")
+	_, errs := ParseListParams(query, testListSpec)
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	if want := []string{"page", "limit", "sort", "featured"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("errors for %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Product field limits
+const (
+	MaxProductNameLength        = 200
+	MaxProductDescriptionLength = 5000
+)
+
+// skuPattern is the format every product SKU must match
+var skuPattern = regexp.MustCompile(`^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$`)
+
+// SetSKUPattern sets the regular expression product SKUs must match. The
+// pattern is anchored, so it has to match the whole SKU.
+func SetSKUPattern(pattern string) error {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return err
+	}
+	skuPattern = re
+	return nil
+}
+
+// NormalizeSKU puts a product or variant SKU in its stored form: trimmed,
+// uppercase, with runs of whitespace collapsed to one space, so "abc-1 " and
+// "ABC-1" are the same SKU
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(sku), " "))
+}
+
+// ProductFields holds the product fields shared by product create and update
+// requests. Nil fields were not supplied and are not checked, so partial
+// updates validate only what they change. Price is the raw JSON number, as
+// its precision is lost once it is parsed into cents.
+type ProductFields struct {
+	Name        *string
+	Description *string
+	SKU         *string
+	Price       *string
+}
+
+// ValidateProductFields returns an error for every invalid product field
+func ValidateProductFields(f ProductFields) []FieldError {
+	var errs []FieldError
+
+	if f.Name != nil {
+		name := strings.TrimSpace(*f.Name)
+		if name == "" {
+			errs = append(errs, FieldError{"name", "must not be empty"})
+		} else if utf8.RuneCountInString(name) > MaxProductNameLength {
+			errs = append(errs, FieldError{"name", fmt.Sprintf("must be at most %d characters", MaxProductNameLength)})
+		}
+	}
+
+	if f.Description != nil && utf8.RuneCountInString(*f.Description) > MaxProductDescriptionLength {
+		errs = append(errs, FieldError{"description", fmt.Sprintf("must be at most %d characters", MaxProductDescriptionLength)})
+	}
+
+	if f.SKU != nil && !skuPattern.MatchString(*f.SKU) {
+		errs = append(errs, FieldError{"sku", "must be letters and digits separated by single dashes"})
+	}
+
+	if f.Price != nil && decimalPlaces(*f.Price) > 2 {
+		errs = append(errs, FieldError{"price", "must have at most 2 decimal places"})
+	}
+
+	return errs
+}
+
+// decimalPlaces counts the significant digits after the decimal point of a
+// JSON number, which may be quoted or use an exponent
+func decimalPlaces(raw string) int {
+	s := strings.Trim(strings.TrimSpace(raw), `"`)
+
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0
+		}
+		for places := 0; places <= 15; places++ {
+			scaled := f * math.Pow10(places)
+			if math.Abs(scaled-math.Round(scaled)) < 1e-9*math.Max(1, math.Abs(scaled)) {
+				return places
+			}
+		}
+		return 16
+	}
+
+	_, fraction, found := strings.Cut(s, ".")
+	if !found {
+		return 0
+	}
+	return len(strings.TrimRight(fraction, "0"))
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeSKU(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"abc-1", "ABC-1"},
+		{"ABC-1", "ABC-1"},
+		{"  abc-1\t", "ABC-1"},
+		{"abc  1", "ABC 1"},
+		{"\tabc \n 1 ", "ABC 1"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSKU(tt.in); got != tt.want {
+			t.Errorf("NormalizeSKU(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateProductFields(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name   string
+		fields ProductFields
+		want   []string
+	}{
+		{"nothing supplied", ProductFields{}, nil},
+		{"valid", ProductFields{Name: str("Widget"), Description: str("A widget"), SKU: str("WID-1"), Price: str("9.99")}, nil},
+		{"empty name", ProductFields{Name: str("")}, []string{"name"}},
+		{"blank name", ProductFields{Name: str(" \t ")}, []string{"name"}},
+		{"long name", ProductFields{Name: str(strings.Repeat("é", MaxProductNameLength+1))}, []string{"name"}},
+		{"name at the limit", ProductFields{Name: str(strings.Repeat("é", MaxProductNameLength))}, nil},
+		{"long description", ProductFields{Description: str(strings.Repeat("x", MaxProductDescriptionLength+1))}, []string{"description"}},
+		{"empty sku", ProductFields{SKU: str("")}, []string{"sku"}},
+		{"sku with a space", ProductFields{SKU: str("WID 1")}, []string{"sku"}},
+		{"sku with a double dash", ProductFields{SKU: str("WID--1")}, []string{"sku"}},
+		{"sku with a trailing dash", ProductFields{SKU: str("WID-")}, []string{"sku"}},
+		{"sku with symbols", ProductFields{SKU: str("WID_1!")}, []string{"sku"}},
+		{"price with three decimals", ProductFields{Price: str("9.999")}, []string{"price"}},
+		{"quoted price with three decimals", ProductFields{Price: str(`"1.005"`)}, []string{"price"}},
+		{"price with an exponent", ProductFields{Price: str("1.2345e1")}, []string{"price"}},
+		{"price with trailing zeros", ProductFields{Price: str("9.9900")}, nil},
+		{"price with an integral exponent", ProductFields{Price: str("1e2")}, nil},
+		{"several invalid", ProductFields{Name: str(""), SKU: str("-"), Price: str("0.001")}, []string{"name", "sku", "price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range ValidateProductFields(tt.fields) {
+				got = append(got, err.Field)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("errors for %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
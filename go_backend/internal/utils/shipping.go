@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/Seyamalam/bun_backend/go_backend/internal/models"
+)
+
+// volumetricDivisor converts a volume in cubic centimetres into kilograms of
+// dimensional weight
+const volumetricDivisor = 5000.0
+
+// BillableWeight returns the weight charged for a single unit: the greater of
+// its actual weight and its dimensional weight. Missing measurements count as zero.
+func BillableWeight(weight, length, width, height *float64) float64 {
+	actual := valueOrZero(weight)
+	volumetric := valueOrZero(length) * valueOrZero(width) * valueOrZero(height) / volumetricDivisor
+	return math.Max(actual, volumetric)
+}
+
+// ShippingCost returns the cost of shipping a parcel of the given weight,
+// rounded to the cent
+func ShippingCost(baseCost, costPerKg models.Money, weight float64) models.Money {
+	return baseCost + models.Money(math.Round(float64(costPerKg)*weight))
+}
+
+func valueOrZero(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MaxSlugLength caps generated slugs, before any numeric suffix
+const MaxSlugLength = 80
+
+// slugFolds spells accented Latin letters without their accents
+var slugFolds = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// Slugify turns a name into a URL slug: lowercase ASCII letters and digits,
+// with every other run of characters collapsed into a single dash. Accented
+// Latin letters lose their accents. Names without any usable characters
+// become "product".
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range slugFolds.Replace(strings.ToLower(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MaxSlugLength {
+		slug = strings.TrimRight(slug[:MaxSlugLength], "-")
+	}
+	if slug == "" {
+		return "product"
+	}
+	return slug
+}
+
+// NextSlug returns base if it is not taken, otherwise base with the lowest
+// numeric suffix from 2 up that is free, so the same set of existing slugs
+// always yields the same result
+func NextSlug(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for n := 2; ; n++ {
+		if candidate := base + "-" + strconv.Itoa(n); !taken[candidate] {
+			return candidate
+		}
+	}
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"regexp"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -36,27 +35,6 @@ func IsValidPassword(password string) bool {
 	return hasUpper && hasLower && hasNumber
 }
 
-// ValidatePagination validates and returns pagination parameters
-func ValidatePagination(pageStr, limitStr string) (page, limit, offset int) {
-	page = 1
-	limit = 20
-
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
-	}
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	offset = (page - 1) * limit
-	return
-}
-
 // SanitizeSearchQuery sanitizes a search query
 func SanitizeSearchQuery(query string) string {
 	query = strings.TrimSpace(query)
@@ -65,3 +43,19 @@ func SanitizeSearchQuery(query string) string {
 	}
 	return query
 }
+
+// NormalizeTags lowercases and trims tag names, collapses inner whitespace,
+// and drops empty and duplicate tags while keeping the original order
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
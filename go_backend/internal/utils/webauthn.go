@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Authenticator data flags
+const (
+	AuthenticatorUserPresent        byte = 0x01
+	AuthenticatorUserVerified       byte = 0x04
+	AuthenticatorAttestedCredential byte = 0x40
+)
+
+// COSE algorithms accepted for passkeys
+const (
+	COSEAlgES256 int64 = -7
+	COSEAlgEdDSA int64 = -8
+	COSEAlgRS256 int64 = -257
+)
+
+// AuthenticatorData is the parsed authenticator data of a WebAuthn
+// registration or assertion
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// CredentialID and PublicKey (a COSE key) are only present when the
+	// authenticator attested a new credential during registration
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// ParseAuthenticatorData parses the binary authenticator data structure
+func ParseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+
+	a := &AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if a.Flags&AuthenticatorAttestedCredential != 0 {
+		rest := data[37:]
+		// 16-byte AAGUID followed by the 2-byte credential ID length
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return nil, errors.New("credential ID truncated")
+		}
+		a.CredentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		a.PublicKey = rest[:len(rest)-len(remaining)]
+	}
+
+	return a, nil
+}
+
+// ParseAttestationObject extracts the authenticator data from a WebAuthn
+// attestation object. The attestation statement is not verified, which is
+// equivalent to requesting "none" attestation.
+func ParseAttestationObject(data []byte) ([]byte, error) {
+	value, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	object, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("attestation object is not a map")
+	}
+	authData, ok := object["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object has no authData")
+	}
+	return authData, nil
+}
+
+// ParseCOSEKey converts a COSE_Key into a public key, returning its algorithm.
+// EC2 P-256 (ES256), OKP Ed25519 (EdDSA) and RSA (RS256) keys are supported.
+func ParseCOSEKey(key []byte) (crypto.PublicKey, int64, error) {
+	value, _, err := decodeCBOR(key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid COSE key: %w", err)
+	}
+	params, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("COSE key is not a map")
+	}
+
+	kty, _ := params[int64(1)].(int64)
+	alg, _ := params[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == COSEAlgES256:
+		crv, _ := params[int64(-1)].(int64)
+		x, _ := params[int64(-2)].([]byte)
+		y, _ := params[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("unsupported EC2 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, errors.New("EC2 key is not on the curve")
+		}
+		return pub, alg, nil
+
+	case kty == 1 && alg == COSEAlgEdDSA:
+		crv, _ := params[int64(-1)].(int64)
+		x, _ := params[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("unsupported OKP key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+
+	case kty == 3 && alg == COSEAlgRS256:
+		n, _ := params[int64(-1)].([]byte)
+		e, _ := params[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("unsupported RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, alg, nil
+	}
+
+	return nil, 0, fmt.Errorf("unsupported COSE key type %d with algorithm %d", kty, alg)
+}
+
+// VerifyWebAuthnSignature checks an assertion signature, which covers the
+// authenticator data followed by the SHA-256 of the client data JSON
+func VerifyWebAuthnSignature(coseKey, authData, clientDataJSON, signature []byte) error {
+	pub, alg, err := ParseCOSEKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	digest := sha256.Sum256(signed)
+
+	valid := false
+	switch alg {
+	case COSEAlgES256:
+		valid = ecdsa.VerifyASN1(pub.(*ecdsa.PublicKey), digest[:], signature)
+	case COSEAlgEdDSA:
+		valid = ed25519.Verify(pub.(ed25519.PublicKey), signed, signature)
+	case COSEAlgRS256:
+		valid = rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// RPIDHashMatches reports whether authenticator data was produced for the
+// relying party ID
+func RPIDHashMatches(a *AuthenticatorData, rpID string) bool {
+	expected := sha256.Sum256([]byte(rpID))
+	return bytes.Equal(a.RPIDHash, expected[:])
+}
+
+// maxCBORDepth bounds nesting so malicious input cannot exhaust the stack
+const maxCBORDepth = 16
+
+// decodeCBOR decodes one CBOR data item, returning it and the bytes after it.
+// Only the definite-length subset used by WebAuthn is supported: integers
+// (as int64), byte and text strings, arrays, maps and simple values.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nesting too deep")
+	}
+	if len(data) == 0 {
+		return nil, nil, errors.New("cbor: unexpected end of data")
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	data = data[1:]
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < size {
+			return nil, nil, errors.New("cbor: unexpected end of data")
+		}
+		for _, b := range data[:size] {
+			arg = arg<<8 | uint64(b)
+		}
+		data = data[size:]
+	default:
+		return nil, nil, errors.New("cbor: indefinite lengths are not supported")
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), data, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), data, nil
+	case 2, 3:
+		if uint64(len(data)) < arg {
+			return nil, nil, errors.New("cbor: string truncated")
+		}
+		if major == 2 {
+			return data[:arg], data[arg:], nil
+		}
+		return string(data[:arg]), data[arg:], nil
+	case 4:
+		if arg > uint64(len(data)) {
+			return nil, nil, errors.New("cbor: array truncated")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, rest, err := decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+			data = rest
+		}
+		return items, data, nil
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, nil, errors.New("cbor: map truncated")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, rest, err := decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor: unsupported map key")
+			}
+			value, rest, err := decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+			data = rest
+		}
+		return m, data, nil
+	case 6:
+		// Tags are skipped, keeping the tagged item
+		return decodeCBORItem(data, depth+1)
+	case 7:
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22, 23:
+			return nil, data, nil
+		}
+		return nil, nil, errors.New("cbor: unsupported simple value")
+	}
+
+	return nil, nil, errors.New("cbor: unknown major type")
+}